@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// ListBackups 获取备份列表，可通过 ?fileId= 过滤
+// GET /api/backups
+func (h *ConfigHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	h.writeBackups(w, r.URL.Query().Get("fileId"))
+}
+
+// ListFileBackups 获取指定配置文件的备份列表
+// GET /api/files/{id}/backups
+func (h *ConfigHandler) ListFileBackups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	h.writeBackups(w, mux.Vars(r)["id"])
+}
+
+// writeBackups 输出备份列表响应
+func (h *ConfigHandler) writeBackups(w http.ResponseWriter, fileID string) {
+	backups, err := h.configService.ListBackups(fileID)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(backups)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RestoreBackup 将备份内容恢复到对应的配置文件
+// POST /api/backups/{backupId}/restore
+func (h *ConfigHandler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	backupID := mux.Vars(r)["backupId"]
+
+	backup, err := h.configService.RestoreBackup(backupID, requestAuthor(r))
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("备份已恢复", backup)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteBackup 删除备份
+// DELETE /api/backups/{backupId}
+func (h *ConfigHandler) DeleteBackup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	backupID := mux.Vars(r)["backupId"]
+
+	if err := h.configService.DeleteBackup(backupID); err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("备份已删除", nil)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetBackupPolicy 获取备份保留策略
+// GET /api/backups/policy
+func (h *ConfigHandler) GetBackupPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	policy, err := h.configService.GetBackupPolicy()
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(policy)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateBackupPolicy 修改备份保留策略
+// PUT /api/backups/policy
+func (h *ConfigHandler) UpdateBackupPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var policy models.BackupPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	updated, err := h.configService.SetBackupPolicy(policy)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("备份保留策略已更新", updated)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/services"
+)
+
+// ListBlocks 列出配置文件中的受管块，以及标记不成对等问题
+// GET /api/files/{id}/blocks
+func (h *ConfigHandler) ListBlocks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fileID := mux.Vars(r)["id"]
+
+	blocks, err := h.configService.ListBlocks(fileID)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(blocks)
+	json.NewEncoder(w).Encode(response)
+}
+
+// SetBlock 插入或更新受管块，可选 If-Match 请求头
+// PUT /api/files/{id}/blocks/{name}，请求体 {"content": "...", "insertAfter": "", "insertBefore": "", "force": false}
+func (h *ConfigHandler) SetBlock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	var req models.SetBlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	result, err := h.configService.SetBlock(vars["id"], vars["name"], req, services.UpdateOptions{
+		Author:  requestAuthor(r),
+		IfMatch: r.Header.Get("If-Match"),
+	})
+	h.writeBlockResult(w, result, err, "受管块已更新")
+}
+
+// RemoveBlock 删除受管块及其标记行，可选 If-Match 请求头
+// DELETE /api/files/{id}/blocks/{name}，?force=true 时文件未通过格式校验也删除
+func (h *ConfigHandler) RemoveBlock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	result, err := h.configService.RemoveBlock(vars["id"], vars["name"], services.UpdateOptions{
+		Author:  requestAuthor(r),
+		IfMatch: r.Header.Get("If-Match"),
+		Force:   r.URL.Query().Get("force") == "true",
+	})
+	h.writeBlockResult(w, result, err, "受管块已删除")
+}
+
+// writeBlockResult 输出受管块修改的响应，冲突和校验失败时与 UpdateFile 一样附带详情
+func (h *ConfigHandler) writeBlockResult(w http.ResponseWriter, result *models.WriteResult, err error, message string) {
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		var preconditionErr *services.PreconditionError
+		if errors.As(err, &preconditionErr) {
+			w.Header().Set("ETag", preconditionErr.Conflict.CurrentETag)
+			response.Data = preconditionErr.Conflict
+		}
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			response.Data = validationErr.Result
+		}
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.Header().Set("ETag", result.ETag)
+	response := models.NewSuccessMessageResponse(message, result)
+	json.NewEncoder(w).Encode(response)
+}
@@ -1,14 +1,10 @@
 package handlers
 
 import (
-	"archive/zip"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
-	"mime/multipart"
 	"net/http"
-	"strings"
-	"time"
 
 	"github.com/gorilla/mux"
 
@@ -33,9 +29,86 @@ func NewConfigHandler(configService *services.ConfigService) *ConfigHandler {
 func (h *ConfigHandler) GetCategories(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	categories := h.configService.GetCategories()
+	categories, err := h.configService.GetCategories()
+	if err != nil {
+		response := models.NewErrorResponse("获取分类失败: " + err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
 	response := models.NewSuccessResponse(categories)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateCategory 新建配置分类
+// POST /api/categories
+func (h *ConfigHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
+	var category models.ConfigCategory
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	created, err := h.configService.CreateCategory(category)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("分类创建成功", created)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateCategory 修改配置分类
+// PUT /api/categories/{id}
+func (h *ConfigHandler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	categoryID := mux.Vars(r)["id"]
+
+	var category models.ConfigCategory
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	updated, err := h.configService.UpdateCategory(categoryID, category)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("分类已更新", updated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteCategory 删除配置分类
+// 仍有文件使用该分类时需通过 ?reassign=<分类ID> 指定迁移目标，否则返回 409
+// DELETE /api/categories/{id}
+func (h *ConfigHandler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	categoryID := mux.Vars(r)["id"]
+	reassignTo := r.URL.Query().Get("reassign")
+
+	if err := h.configService.DeleteCategory(categoryID, reassignTo); err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("分类已删除", nil)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -78,11 +151,13 @@ func (h *ConfigHandler) GetFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", file.ETag)
 	response := models.NewSuccessResponse(file)
 	json.NewEncoder(w).Encode(response)
 }
 
 // UpdateFile 更新配置文件内容
+// 必须携带 If-Match 请求头（来自 GetFile 返回的 ETag），文件已被修改时返回 412 及当前内容和差异
 // PUT /api/files/{id}
 func (h *ConfigHandler) UpdateFile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -104,246 +179,169 @@ func (h *ConfigHandler) UpdateFile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.configService.UpdateFile(fileID, updateRequest.Content)
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		response := models.NewErrorResponse("缺少 If-Match 请求头，请先获取文件以取得 ETag")
+		w.WriteHeader(http.StatusPreconditionRequired)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	result, err := h.configService.UpdateFile(fileID, updateRequest.Content, services.UpdateOptions{
+		Author:        requestAuthor(r),
+		SymlinkPolicy: updateRequest.SymlinkPolicy,
+		IfMatch:       ifMatch,
+		Force:         updateRequest.Force,
+	})
 	if err != nil {
 		response := models.NewErrorResponse(err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
+		var preconditionErr *services.PreconditionError
+		if errors.As(err, &preconditionErr) {
+			w.Header().Set("ETag", preconditionErr.Conflict.CurrentETag)
+			response.Data = preconditionErr.Conflict
+		}
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			response.Data = validationErr.Result
+		}
+		w.WriteHeader(errorStatus(err))
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	response := models.NewSuccessMessageResponse("文件保存成功", nil)
+	w.Header().Set("ETag", result.ETag)
+	response := models.NewSuccessMessageResponse("文件保存成功", result)
 	json.NewEncoder(w).Encode(response)
 }
 
-// BackupFile 创建配置文件备份
-// POST /api/files/{id}/backup
-func (h *ConfigHandler) BackupFile(w http.ResponseWriter, r *http.Request) {
+// ValidateFile 按文件格式校验内容但不写入，请求体省略 content 时校验磁盘上的当前内容
+// POST /api/files/{id}/validate
+func (h *ConfigHandler) ValidateFile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
-	vars := mux.Vars(r)
-	fileID := vars["id"]
+	fileID := mux.Vars(r)["id"]
 
-	if fileID == "" {
-		response := models.NewErrorResponse("文件ID不能为空")
+	// 请求体可选，允许为空
+	var validateRequest models.ValidateFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&validateRequest); err != nil && err != io.EOF {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	backupResponse, err := h.configService.BackupFile(fileID)
+	result, err := h.configService.ValidateFile(fileID, validateRequest.Content)
 	if err != nil {
 		response := models.NewErrorResponse(err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(errorStatus(err))
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	response := models.NewSuccessResponse(backupResponse)
-	w.WriteHeader(http.StatusCreated)
+	response := models.NewSuccessResponse(result)
 	json.NewEncoder(w).Encode(response)
 }
 
-// ExportConfigs 导出所有配置文件为压缩包
-// GET /api/export
-func (h *ConfigHandler) ExportConfigs(w http.ResponseWriter, r *http.Request) {
-	// 获取所有配置文件
-	files, err := h.configService.GetFiles()
-	if err != nil {
-		http.Error(w, "获取配置文件失败: "+err.Error(), http.StatusInternalServerError)
+// BackupFile 创建配置文件备份
+// POST /api/files/{id}/backup
+func (h *ConfigHandler) BackupFile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+	fileID := vars["id"]
+
+	if fileID == "" {
+		response := models.NewErrorResponse("文件ID不能为空")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	// 获取所有分类信息
-	categories := h.configService.GetCategories()
-
-	// 设置响应头
-	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", "attachment; filename=linux-configs.zip")
-
-	// 创建ZIP写入器
-	zipWriter := zip.NewWriter(w)
-	defer zipWriter.Close()
-
-	// 创建分类映射
-	categoryMap := make(map[string]models.ConfigCategory)
-	for _, cat := range categories {
-		categoryMap[cat.ID] = cat
+	// 请求体可选，允许为空
+	var backupRequest models.BackupFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&backupRequest); err != nil && err != io.EOF {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
 	}
 
-	// 按分类组织文件
-	for _, file := range files {
-		// 获取文件内容
-		fileWithContent, err := h.configService.GetFileByID(file.ID)
-		if err != nil {
-			continue // 跳过无法读取的文件
-		}
-
-		// 构建文件路径：分类名/文件名
-		categoryName := "其他"
-		if cat, exists := categoryMap[file.Category]; exists {
-			categoryName = cat.Name
-		}
-		
-		// 清理文件名，移除路径分隔符
-		fileName := strings.ReplaceAll(file.Name, "/", "_")
-		filePath := fmt.Sprintf("%s/%s", categoryName, fileName)
-
-		// 创建ZIP文件条目
-		fileWriter, err := zipWriter.Create(filePath)
-		if err != nil {
-			continue
-		}
-
-		// 写入文件内容
-		if fileWithContent.Content != "" {
-			fileWriter.Write([]byte(fileWithContent.Content))
-		}
+	backupResponse, err := h.configService.BackupFile(fileID, backupRequest.Reason)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
 	}
 
-	// 创建配置清单文件
-	manifestWriter, err := zipWriter.Create("配置清单.json")
-	if err == nil {
-		manifest := map[string]interface{}{
-			"exportTime": fmt.Sprintf("%s", time.Now().Format("2006-01-02 15:04:05")),
-			"totalFiles": len(files),
-			"categories": categories,
-			"files":      files,
-		}
-		manifestData, _ := json.MarshalIndent(manifest, "", "  ")
-		manifestWriter.Write(manifestData)
-	}
+	response := models.NewSuccessResponse(backupResponse)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
 }
 
-// ImportConfigs 导入配置文件
-// POST /api/import
-func (h *ConfigHandler) ImportConfigs(w http.ResponseWriter, r *http.Request) {
+// RegisterFile 登记新的配置文件
+// POST /api/files
+func (h *ConfigHandler) RegisterFile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// 解析multipart表单
-	err := r.ParseMultipartForm(32 << 20) // 32MB
-	if err != nil {
-		response := models.NewErrorResponse("解析上传文件失败: " + err.Error())
+	var registerRequest models.RegisterFileRequest
+	if err := json.NewDecoder(r.Body).Decode(&registerRequest); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	file, header, err := r.FormFile("configFile")
+	file, err := h.configService.RegisterFile(registerRequest)
 	if err != nil {
-		response := models.NewErrorResponse("获取上传文件失败: " + err.Error())
-		w.WriteHeader(http.StatusBadRequest)
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
 		json.NewEncoder(w).Encode(response)
 		return
 	}
-	defer file.Close()
 
-	// 检查文件类型
-	if !strings.HasSuffix(strings.ToLower(header.Filename), ".zip") {
-		response := models.NewErrorResponse("只支持ZIP格式的配置文件")
+	response := models.NewSuccessMessageResponse("文件登记成功", file)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateFileMeta 修改配置文件的元数据（名称、路径、分类、描述）
+// PATCH /api/files/{id}
+func (h *ConfigHandler) UpdateFileMeta(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fileID := mux.Vars(r)["id"]
+
+	var metaRequest models.UpdateFileMetaRequest
+	if err := json.NewDecoder(r.Body).Decode(&metaRequest); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	// 处理ZIP文件
-	result, err := h.processImportedZip(file, header.Size)
+	file, err := h.configService.UpdateFileMeta(fileID, metaRequest)
 	if err != nil {
-		response := models.NewErrorResponse("处理导入文件失败: " + err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	response := models.NewSuccessResponse(result)
+	response := models.NewSuccessMessageResponse("文件信息已更新", file)
 	json.NewEncoder(w).Encode(response)
 }
 
-// processImportedZip 处理导入的ZIP文件
-func (h *ConfigHandler) processImportedZip(file multipart.File, size int64) (map[string]interface{}, error) {
-	// 创建ZIP读取器
-	zipReader, err := zip.NewReader(file, size)
-	if err != nil {
-		return nil, fmt.Errorf("无法读取ZIP文件: %v", err)
-	}
-
-	importedFiles := 0
-	skippedFiles := 0
-	errors := []string{}
-
-	// 遍历ZIP文件中的所有文件
-	for _, zipFile := range zipReader.File {
-		// 跳过目录和配置清单文件
-		if zipFile.FileInfo().IsDir() || strings.HasSuffix(zipFile.Name, "配置清单.json") {
-			continue
-		}
-
-		// 打开ZIP文件中的文件
-		rc, err := zipFile.Open()
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("无法打开文件 %s: %v", zipFile.Name, err))
-			skippedFiles++
-			continue
-		}
-
-		// 读取文件内容
-		content, err := io.ReadAll(rc)
-		rc.Close()
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("无法读取文件 %s: %v", zipFile.Name, err))
-			skippedFiles++
-			continue
-		}
-
-		// 解析文件路径，获取分类和文件名
-		pathParts := strings.Split(zipFile.Name, "/")
-		if len(pathParts) < 2 {
-			errors = append(errors, fmt.Sprintf("文件路径格式不正确: %s", zipFile.Name))
-			skippedFiles++
-			continue
-		}
-
-		fileName := pathParts[len(pathParts)-1]
-		
-		// 尝试根据文件名找到对应的配置文件
-		files, err := h.configService.GetFiles()
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("获取配置文件列表失败: %v", err))
-			skippedFiles++
-			continue
-		}
-
-		// 查找匹配的配置文件
-		var targetFile *models.ConfigFile
-		for _, f := range files {
-			if strings.Contains(f.Name, fileName) || strings.Contains(fileName, f.Name) {
-				targetFile = &f
-				break
-			}
-		}
-
-		if targetFile == nil {
-			errors = append(errors, fmt.Sprintf("未找到匹配的配置文件: %s", fileName))
-			skippedFiles++
-			continue
-		}
-
-		// 更新配置文件内容
-		err = h.configService.UpdateFile(targetFile.ID, string(content))
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("更新文件 %s 失败: %v", targetFile.Name, err))
-			skippedFiles++
-			continue
-		}
-
-		importedFiles++
-	}
+// UnregisterFile 取消登记配置文件，磁盘上的文件不会被删除
+// DELETE /api/files/{id}
+func (h *ConfigHandler) UnregisterFile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fileID := mux.Vars(r)["id"]
 
-	result := map[string]interface{}{
-		"importedFiles": importedFiles,
-		"skippedFiles":  skippedFiles,
-		"errors":        errors,
-		"message":       fmt.Sprintf("导入完成：成功 %d 个文件，跳过 %d 个文件", importedFiles, skippedFiles),
+	if err := h.configService.UnregisterFile(fileID); err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
 	}
 
-	return result, nil
-}
\ No newline at end of file
+	response := models.NewSuccessMessageResponse("文件已取消登记", nil)
+	json.NewEncoder(w).Encode(response)
+}
@@ -4,10 +4,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/gorilla/mux"
+
 	"linux-config-manager-backend/internal/models"
 	"linux-config-manager-backend/internal/services"
+	"linux-config-manager-backend/internal/testutil"
 )
 
 func TestGetCategories(t *testing.T) {
@@ -79,4 +85,64 @@ func TestGetFiles(t *testing.T) {
 	if !response.Success {
 		t.Errorf("API 响应显示失败: %v", response.Error)
 	}
-}
\ No newline at end of file
+}
+
+func TestUpdateFileRequiresMatchingETag(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	vimrc := filepath.Join(home, ".vimrc")
+	if err := os.WriteFile(vimrc, []byte("set number\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewConfigHandler(services.NewConfigService())
+
+	// 读取文件获得 ETag
+	req := mux.SetURLVars(httptest.NewRequest("GET", "/api/files/vimrc", nil), map[string]string{"id": "vimrc"})
+	rr := httptest.NewRecorder()
+	handler.GetFile(rr, req)
+	etag := rr.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GetFile 未返回 ETag")
+	}
+
+	update := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/api/files/vimrc", strings.NewReader(`{"content":"set nonumber\n"}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "vimrc"})
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		handler.UpdateFile(rr, req)
+		return rr
+	}
+
+	if rr := update(""); rr.Code != http.StatusPreconditionRequired {
+		t.Errorf("缺少 If-Match 应返回 428, got %d", rr.Code)
+	}
+
+	// 模拟在编辑器之外修改了文件
+	if err := os.WriteFile(vimrc, []byte("set number\nsyntax on\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rr = update(etag)
+	if rr.Code != http.StatusPreconditionFailed {
+		t.Fatalf("ETag 不匹配应返回 412, got %d", rr.Code)
+	}
+	var conflict struct {
+		Data models.ConflictInfo `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &conflict); err != nil {
+		t.Fatal(err)
+	}
+	if conflict.Data.Content != "set number\nsyntax on\n" || !strings.Contains(conflict.Data.Diff, "-syntax on") {
+		t.Errorf("412 响应应包含当前内容和差异: %+v", conflict.Data)
+	}
+
+	if rr := update(conflict.Data.CurrentETag); rr.Code != http.StatusOK {
+		t.Errorf("使用最新 ETag 应保存成功, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if content, _ := os.ReadFile(vimrc); string(content) != "set nonumber\n" {
+		t.Errorf("文件内容错误: %q", content)
+	}
+}
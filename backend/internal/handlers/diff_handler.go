@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// DiffProposed 比较配置文件的当前内容与提议的内容（例如即将 PUT 的内容）
+// POST /api/files/{id}/diff
+func (h *ConfigHandler) DiffProposed(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fileID := mux.Vars(r)["id"]
+
+	var diffRequest models.DiffRequest
+	if err := json.NewDecoder(r.Body).Decode(&diffRequest); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	result, err := h.configService.DiffProposed(fileID, diffRequest.Content)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(result)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DiffAgainst 比较备份或历史版本与配置文件的当前内容
+// GET /api/files/{id}/diff?against=backup:<备份ID>|rev:<版本号>
+func (h *ConfigHandler) DiffAgainst(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fileID := mux.Vars(r)["id"]
+
+	against := r.URL.Query().Get("against")
+	if against == "" {
+		response := models.NewErrorResponse("缺少 against 参数（backup:<备份ID> 或 rev:<版本号>）")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	result, err := h.configService.DiffAgainst(fileID, against)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(result)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/services"
+)
+
+// DiscoveryHandler 处理配置文件自动发现相关的HTTP请求
+type DiscoveryHandler struct {
+	discoveryService *services.DiscoveryService
+}
+
+// NewDiscoveryHandler 创建新的发现处理器实例
+func NewDiscoveryHandler(discoveryService *services.DiscoveryService) *DiscoveryHandler {
+	return &DiscoveryHandler{
+		discoveryService: discoveryService,
+	}
+}
+
+// Discover 扫描尚未登记的配置文件
+// GET /api/discover
+func (h *DiscoveryHandler) Discover(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	candidates, err := h.discoveryService.Discover()
+	if err != nil {
+		response := models.NewErrorResponse("扫描配置文件失败: " + err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(candidates)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Adopt 将扫描发现的候选文件纳入管理
+// POST /api/discover/adopt
+func (h *DiscoveryHandler) Adopt(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var adoptRequest models.AdoptRequest
+	if err := json.NewDecoder(r.Body).Decode(&adoptRequest); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if len(adoptRequest.IDs) == 0 {
+		response := models.NewErrorResponse("请至少选择一个候选文件")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	result, err := h.discoveryService.Adopt(adoptRequest)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(result)
+	json.NewEncoder(w).Encode(response)
+}
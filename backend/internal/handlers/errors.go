@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"linux-config-manager-backend/internal/services"
+)
+
+// authorHeader 客户端用于标识修改者的请求头，会记录到历史版本中
+const authorHeader = "X-Config-Author"
+
+// requestAuthor 返回请求中声明的修改者，为空时由服务使用系统用户
+func requestAuthor(r *http.Request) string {
+	return r.Header.Get(authorHeader)
+}
+
+// errorStatus 根据业务错误类别返回对应的 HTTP 状态码
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrAlreadyExists), errors.Is(err, services.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidInput):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrPrecondition):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, services.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, services.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/services"
+)
+
+// ExportHandler 处理配置文件导出相关的HTTP请求
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler 创建新的导出处理器实例
+func NewExportHandler(exportService *services.ExportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+// ExportConfigs 导出配置文件为压缩包
+// GET /api/export?format=zip|tar.gz|script|nix|ansible&category=&ids=&tag=&exclude=&profile=，未指定 format 时按 Accept 头选择，默认为 zip
+// 筛选参数可重复或用逗号分隔多个值，都未指定时导出所有文件
+// 请求头 X-Bundle-Passphrase 非空时导出用该口令加密的导出包
+func (h *ExportHandler) ExportConfigs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	h.export(w, services.ExportOptions{
+		Format:     exportFormat(r),
+		Passphrase: r.Header.Get(passphraseHeader),
+		Selection: models.ExportSelection{
+			Categories: splitQueryValues(query["category"]),
+			IDs:        splitQueryValues(query["ids"]),
+			Tags:       splitQueryValues(query["tag"]),
+			Exclude:    splitQueryValues(query["exclude"]),
+			Profile:    query.Get("profile"),
+		},
+	})
+}
+
+// ExportSelected 按请求体中的筛选条件导出配置文件
+// POST /api/export，请求体 {"categories": [...], "ids": [...], "tags": [...], "exclude": [...], "profile": "", "format": "zip"}
+func (h *ExportHandler) ExportSelected(w http.ResponseWriter, r *http.Request) {
+	var req models.ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "无效的请求数据: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	format := req.Format
+	if format == "" {
+		format = exportFormat(r)
+	} else if format == "tgz" {
+		format = services.ExportFormatTarGz
+	}
+	h.export(w, services.ExportOptions{
+		Format:     format,
+		Passphrase: r.Header.Get(passphraseHeader),
+		Selection:  req.ExportSelection,
+	})
+}
+
+// export 生成导出包并作为附件返回
+func (h *ExportHandler) export(w http.ResponseWriter, opts services.ExportOptions) {
+	// 先在内存中打包，出错时仍可返回错误状态码
+	var buf bytes.Buffer
+	if err := h.exportService.Export(&buf, opts); err != nil {
+		http.Error(w, "导出失败: "+err.Error(), errorStatus(err))
+		return
+	}
+
+	// 设置响应头
+	fileName := "linux-configs.zip"
+	contentType := "application/zip"
+	switch opts.Format {
+	case services.ExportFormatTarGz:
+		fileName = "linux-configs.tar.gz"
+		contentType = "application/gzip"
+	case services.ExportFormatScript:
+		fileName = "linux-configs-install.sh"
+		contentType = "text/x-shellscript; charset=utf-8"
+	case services.ExportFormatNix:
+		fileName = "linux-configs-home-manager.tar.gz"
+		contentType = "application/gzip"
+	case services.ExportFormatAnsible:
+		fileName = "linux-configs-ansible-role.tar.gz"
+		contentType = "application/gzip"
+	}
+	if opts.Passphrase != "" {
+		fileName += ".enc"
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+	w.Write(buf.Bytes())
+}
+
+// passphraseHeader 传递导出包加密口令的请求头，避免口令出现在 URL 和访问日志中
+const passphraseHeader = "X-Bundle-Passphrase"
+
+// splitQueryValues 展开重复的和逗号分隔的查询参数值，忽略空值
+func splitQueryValues(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
+
+// exportFormat 从查询参数或 Accept 头确定导出格式
+func exportFormat(r *http.Request) string {
+	switch format := r.URL.Query().Get("format"); format {
+	case "":
+	case "tgz":
+		return services.ExportFormatTarGz
+	default:
+		return format
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "application/gzip") || strings.Contains(accept, "application/x-gzip") ||
+		strings.Contains(accept, "application/x-gtar") {
+		return services.ExportFormatTarGz
+	}
+	return services.ExportFormatZip
+}
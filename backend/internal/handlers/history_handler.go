@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// GetFileHistory 获取配置文件的历史版本列表
+// GET /api/files/{id}/history
+func (h *ConfigHandler) GetFileHistory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fileID := mux.Vars(r)["id"]
+
+	revisions, err := h.configService.GetFileHistory(fileID)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(revisions)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetFileRevision 获取配置文件在指定版本的内容
+// GET /api/files/{id}/history/{rev}
+func (h *ConfigHandler) GetFileRevision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	revision, err := h.configService.GetFileRevision(vars["id"], vars["rev"])
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(revision)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RestoreFileRevision 将配置文件恢复为指定版本
+// POST /api/files/{id}/restore/{rev}
+func (h *ConfigHandler) RestoreFileRevision(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	vars := mux.Vars(r)
+
+	revision, err := h.configService.RestoreFileRevision(vars["id"], vars["rev"], requestAuthor(r))
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("文件已恢复到版本 "+revision.ShortRev, revision.Revision)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,339 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/services"
+)
+
+// ImportHandler 处理配置压缩包导入相关的HTTP请求
+type ImportHandler struct {
+	importService *services.ImportService
+	jobService    *services.ImportJobService
+}
+
+// NewImportHandler 创建新的导入处理器实例
+func NewImportHandler(importService *services.ImportService, jobService *services.ImportJobService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+		jobService:    jobService,
+	}
+}
+
+// maxFormFieldSize 导入表单中普通字段的最大长度
+const maxFormFieldSize = 4096
+
+// ImportConfigs 导入配置文件
+// POST /api/import 立即导入；POST /api/import?mode=plan 只生成导入计划，不修改任何文件
+// async=true 时上传完成后立即返回导入任务，通过 GET /api/import/jobs/{jobId} 查询进度和结果
+func (h *ImportHandler) ImportConfigs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != "plan" {
+		response := models.NewErrorResponse("无效的导入模式: " + mode)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// 逐个读取multipart表单的各部分，上传的文件直接写入临时文件，不在内存中缓存
+	reader, err := r.MultipartReader()
+	if err != nil {
+		response := models.NewErrorResponse("解析上传文件失败: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	var job *models.ImportJob
+	fields := make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if job != nil {
+				h.jobService.Delete(job.ID)
+			}
+			response := models.NewErrorResponse("解析上传文件失败: " + err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		if part.FormName() == "configFile" && job == nil {
+			job, err = h.jobService.Upload(part, part.FileName())
+			if err != nil {
+				response := models.NewErrorResponse("接收上传文件失败: " + err.Error())
+				w.WriteHeader(errorStatus(err))
+				json.NewEncoder(w).Encode(response)
+				return
+			}
+			continue
+		}
+		value, _ := io.ReadAll(io.LimitReader(part, maxFormFieldSize))
+		fields[part.FormName()] = string(value)
+	}
+
+	if job == nil {
+		response := models.NewErrorResponse("获取上传文件失败: 缺少表单字段 configFile")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// createMissing=true 时新建本地不存在的文件，并按配置清单登记未登记的文件；
+	// force=true 时允许覆盖导出之后在本地修改过的文件；skipValidation=true 时未通过格式校验的内容也写入；
+	// 加密的导出包通过表单字段 passphrase 或请求头 X-Bundle-Passphrase 提供口令
+	startRequest := models.StartImportJobRequest{
+		Mode:           models.ImportJobModeImport,
+		Force:          fields["force"] == "true",
+		SkipValidation: fields["skipValidation"] == "true",
+		CreateMissing:  fields["createMissing"] == "true",
+		Passphrase:     fields["passphrase"],
+	}
+	if mode == "plan" {
+		startRequest.Mode = models.ImportJobModePlan
+	}
+	if startRequest.Passphrase == "" {
+		startRequest.Passphrase = r.Header.Get(passphraseHeader)
+	}
+
+	job, err = h.jobService.Start(job.ID, startRequest, requestAuthor(r))
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if r.URL.Query().Get("async") == "true" {
+		w.WriteHeader(http.StatusAccepted)
+		response := models.NewSuccessMessageResponse("导入任务已开始处理", job)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// 同步导入时等待处理结束，结果直接返回，不再保留任务
+	jobID := job.ID
+	job, err = h.jobService.Wait(jobID)
+	h.jobService.Delete(jobID)
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	var response *models.APIResponse
+	if mode == "plan" {
+		response = models.NewSuccessResponse(job.Plan)
+	} else {
+		response = models.NewSuccessResponse(job.Result)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeImportError 写入导入失败的响应，压缩包因安全限制被拒绝时附带错误码
+func writeImportError(w http.ResponseWriter, err error) {
+	response := models.NewErrorResponse("处理导入文件失败: " + err.Error())
+	var archiveErr *services.ArchiveError
+	if errors.As(err, &archiveErr) {
+		response.Data = archiveErr.Detail
+	}
+	w.WriteHeader(errorStatus(err))
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetImportPlan 获取尚未执行的导入计划
+// GET /api/import/plans/{planId}
+func (h *ImportHandler) GetImportPlan(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	plan, err := h.importService.GetPlan(mux.Vars(r)["planId"])
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(plan)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ApplyImportPlan 执行导入计划中选定的条目，请求体省略时执行全部条目
+// POST /api/import/plans/{planId}/apply
+func (h *ImportHandler) ApplyImportPlan(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 请求体可选，允许为空
+	var applyRequest models.ApplyImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&applyRequest); err != nil && err != io.EOF {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	result, err := h.importService.Apply(mux.Vars(r)["planId"], applyRequest, requestAuthor(r))
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(result)
+	json.NewEncoder(w).Encode(response)
+}
+
+// ImportFromSource 读取 chezmoi、stow 或 yadm 的本地目录并生成导入计划
+// POST /api/import/sources，请求体 {"type": "chezmoi", "path": "~/.local/share/chezmoi"}
+func (h *ImportHandler) ImportFromSource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var source models.ImportSource
+	if err := json.NewDecoder(r.Body).Decode(&source); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	plan, err := h.importService.PlanSource(source)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		var archiveErr *services.ArchiveError
+		if errors.As(err, &archiveErr) {
+			response.Data = archiveErr.Detail
+		}
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("已生成导入计划", plan)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateImportJob 创建分块上传的导入任务
+// POST /api/import/jobs，请求体 {"fileName": "configs.tar.gz", "size": 1048576}
+func (h *ImportHandler) CreateImportJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var createRequest models.CreateImportJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&createRequest); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	job, err := h.jobService.Create(createRequest)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response := models.NewSuccessMessageResponse("导入任务已创建", job)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UploadImportChunk 写入导入任务的一个分块，请求体为分块的原始字节
+// PUT /api/import/jobs/{jobId}/chunks?offset=<已接收的字节数>
+func (h *ImportHandler) UploadImportChunk(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	offset, err := strconv.ParseInt(r.URL.Query().Get("offset"), 10, 64)
+	if err != nil || offset < 0 {
+		response := models.NewErrorResponse("无效的分块偏移量: " + r.URL.Query().Get("offset"))
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	job, err := h.jobService.WriteChunk(mux.Vars(r)["jobId"], offset, r.Body)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(job)
+	json.NewEncoder(w).Encode(response)
+}
+
+// StartImportJob 开始处理已上传完整的压缩包
+// POST /api/import/jobs/{jobId}/start，请求体 {"mode": "plan", "force": false, "createMissing": false, "passphrase": ""}
+func (h *ImportHandler) StartImportJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// 请求体可选，允许为空
+	var startRequest models.StartImportJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&startRequest); err != nil && err != io.EOF {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	if startRequest.Passphrase == "" {
+		startRequest.Passphrase = r.Header.Get(passphraseHeader)
+	}
+
+	job, err := h.jobService.Start(mux.Vars(r)["jobId"], startRequest, requestAuthor(r))
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	response := models.NewSuccessMessageResponse("导入任务已开始处理", job)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetImportJob 获取导入任务的上传进度、处理进度和结果
+// GET /api/import/jobs/{jobId}
+func (h *ImportHandler) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	job, err := h.jobService.Get(mux.Vars(r)["jobId"])
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(job)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteImportJob 取消或删除导入任务，并移除已上传的临时文件
+// DELETE /api/import/jobs/{jobId}
+func (h *ImportHandler) DeleteImportJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := h.jobService.Delete(mux.Vars(r)["jobId"]); err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("导入任务已删除", nil)
+	json.NewEncoder(w).Encode(response)
+}
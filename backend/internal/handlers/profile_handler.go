@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// ListProfiles 获取所有机器配置档案
+// GET /api/profiles
+func (h *ConfigHandler) ListProfiles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	profiles, err := h.configService.ListProfiles()
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(profiles)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetProfile 获取指定的机器配置档案
+// GET /api/profiles/{id}
+func (h *ConfigHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	profileID := mux.Vars(r)["id"]
+
+	profile, err := h.configService.GetProfile(profileID)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(profile)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateProfile 新建机器配置档案
+// POST /api/profiles
+func (h *ConfigHandler) CreateProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var profile models.MachineProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	created, err := h.configService.CreateProfile(profile)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("档案创建成功", created)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateProfile 整体替换机器配置档案
+// PUT /api/profiles/{id}
+func (h *ConfigHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	profileID := mux.Vars(r)["id"]
+
+	var profile models.MachineProfile
+	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	updated, err := h.configService.UpdateProfile(profileID, profile)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("档案已更新", updated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteProfile 删除机器配置档案
+// DELETE /api/profiles/{id}
+func (h *ConfigHandler) DeleteProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	profileID := mux.Vars(r)["id"]
+
+	if err := h.configService.DeleteProfile(profileID); err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("档案已删除", nil)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetActiveProfile 解析本机的活动档案，并说明每个档案是否匹配及原因
+// GET /api/profiles/active?hostname=&os=&kernel=，提供的参数覆盖本机信息，用于检查其他机器会使用哪个档案
+func (h *ConfigHandler) GetActiveProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	query := r.URL.Query()
+
+	resolution, err := h.configService.ResolveProfile(models.SystemInfo{
+		Hostname: query.Get("hostname"),
+		OS:       query.Get("os"),
+		Kernel:   query.Get("kernel"),
+	})
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(resolution)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/services"
+)
+
+// SigningHandler 处理导出包签名密钥和受信任公钥相关的HTTP请求
+type SigningHandler struct {
+	signingService *services.SigningService
+}
+
+// NewSigningHandler 创建新的签名处理器实例
+func NewSigningHandler(signingService *services.SigningService) *SigningHandler {
+	return &SigningHandler{
+		signingService: signingService,
+	}
+}
+
+// GetSigningKey 获取本机签名公钥，首次调用时生成密钥对
+// GET /api/signing/key
+func (h *SigningHandler) GetSigningKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	key, err := h.signingService.PublicKey()
+	if err != nil {
+		response := models.NewErrorResponse("获取签名公钥失败: " + err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(key)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTrust 获取签名策略和受信任的公钥列表
+// GET /api/signing/trust
+func (h *SigningHandler) GetTrust(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	trust, err := h.signingService.Trust()
+	if err != nil {
+		response := models.NewErrorResponse("获取受信任公钥失败: " + err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(trust)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateTrustPolicy 修改导入时的签名策略
+// PUT /api/signing/trust/policy
+func (h *SigningHandler) UpdateTrustPolicy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.UpdateTrustPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	trust, err := h.signingService.SetPolicy(req.Policy)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("签名策略已更新", trust)
+	json.NewEncoder(w).Encode(response)
+}
+
+// AddTrustedKey 添加受信任的签名公钥
+// POST /api/signing/trust/keys
+func (h *SigningHandler) AddTrustedKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req models.AddTrustedKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	key, err := h.signingService.AddTrustedKey(req)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	response := models.NewSuccessMessageResponse("公钥已添加到受信任列表", key)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RemoveTrustedKey 从受信任列表中删除公钥
+// DELETE /api/signing/trust/keys/{keyId}
+func (h *SigningHandler) RemoveTrustedKey(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := h.signingService.RemoveTrustedKey(mux.Vars(r)["keyId"]); err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("公钥已从受信任列表中删除", nil)
+	json.NewEncoder(w).Encode(response)
+}
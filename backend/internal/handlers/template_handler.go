@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// RenderFile 预览模板文件的渲染结果，不写入文件
+// GET /api/files/{id}/render?profile=&hostname=&os=&kernel=&user=&homeDir=&shell=&var.<名称>=
+// 提供的参数覆盖本机的系统信息、活动档案和变量，用于预览其他机器上的结果
+func (h *ConfigHandler) RenderFile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fileID := mux.Vars(r)["id"]
+
+	result, err := h.configService.RenderFile(fileID, renderProfile(r.URL.Query()))
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(result)
+	json.NewEncoder(w).Encode(response)
+}
+
+// renderProfile 从查询参数中读取覆盖的系统信息、档案和 var. 前缀的变量
+func renderProfile(query url.Values) models.TemplateData {
+	profile := models.TemplateData{
+		Hostname: query.Get("hostname"),
+		OS:       query.Get("os"),
+		Kernel:   query.Get("kernel"),
+		User:     query.Get("user"),
+		HomeDir:  query.Get("homeDir"),
+		Shell:    query.Get("shell"),
+		Profile:  query.Get("profile"),
+		Vars:     map[string]string{},
+	}
+	for key, values := range query {
+		if name, ok := strings.CutPrefix(key, "var."); ok && name != "" {
+			profile.Vars[name] = values[0]
+		}
+	}
+	return profile
+}
+
+// GetTemplateVars 获取渲染模板时使用的变量
+// GET /api/templates/vars
+func (h *ConfigHandler) GetTemplateVars(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars, err := h.configService.GetTemplateVars()
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(vars)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateTemplateVars 整体替换渲染模板时使用的变量
+// PUT /api/templates/vars
+func (h *ConfigHandler) UpdateTemplateVars(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var vars map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&vars); err != nil {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	updated, err := h.configService.SetTemplateVars(vars)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessMessageResponse("模板变量已更新", updated)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/services"
+)
+
+// TrialHandler 处理 shell 配置试运行相关的HTTP请求
+type TrialHandler struct {
+	trialService *services.TrialService
+}
+
+// NewTrialHandler 创建新的试运行处理器实例
+func NewTrialHandler(trialService *services.TrialService) *TrialHandler {
+	return &TrialHandler{
+		trialService: trialService,
+	}
+}
+
+// TrialFile 在临时 HOME 中启动 shell 加载配置文件，不会修改真实文件
+// POST /api/files/{id}/trial
+func (h *TrialHandler) TrialFile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	fileID := mux.Vars(r)["id"]
+
+	// 请求体可选，允许为空
+	var trialRequest models.TrialRequest
+	if err := json.NewDecoder(r.Body).Decode(&trialRequest); err != nil && err != io.EOF {
+		response := models.NewErrorResponse("无效的请求数据: " + err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	result, err := h.trialService.Trial(fileID, trialRequest)
+	if err != nil {
+		response := models.NewErrorResponse(err.Error())
+		w.WriteHeader(errorStatus(err))
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	response := models.NewSuccessResponse(result)
+	json.NewEncoder(w).Encode(response)
+}
@@ -26,9 +26,13 @@ func SetupCORS() *cors.Cors {
 			"Content-Type",
 			"X-CSRF-Token",
 			"X-Requested-With",
+			"X-Config-Author",
+			"If-Match",
+			"X-Bundle-Passphrase",
 		},
 		ExposedHeaders: []string{
 			"Link",
+			"ETag",
 		},
 		AllowCredentials: true,
 		MaxAge:           300, // 5分钟
@@ -0,0 +1,25 @@
+package models
+
+// ManagedBlock 表示配置文件中由标记行包围、按名称管理的一段内容
+type ManagedBlock struct {
+	Name      string `json:"name"`
+	StartLine int    `json:"startLine"` // 开始标记所在的行号（从 1 开始）
+	EndLine   int    `json:"endLine"`   // 结束标记所在的行号
+	Content   string `json:"content"`   // 标记之间的内容，不含标记行
+}
+
+// BlockList 表示配置文件中的受管块以及标记不成对等问题
+type BlockList struct {
+	FileID   string         `json:"fileId"`
+	Blocks   []ManagedBlock `json:"blocks"`
+	Problems []Diagnostic   `json:"problems"` // 标记不成对、名称不一致或重复时的警告
+}
+
+// SetBlockRequest 表示插入或更新受管块的请求数据
+// 块已存在时原地替换内容，插入位置只对新块生效；都未指定时追加到文件末尾
+type SetBlockRequest struct {
+	Content      string `json:"content"`
+	InsertAfter  string `json:"insertAfter,omitempty"`  // 正则表达式，插入到最后一个匹配行之后；EOF 表示文件末尾
+	InsertBefore string `json:"insertBefore,omitempty"` // 正则表达式，插入到第一个匹配行之前；BOF 表示文件开头
+	Force        bool   `json:"force,omitempty"`        // 为 true 时即使修改后的文件未通过格式校验也写入
+}
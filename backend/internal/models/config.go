@@ -11,11 +11,15 @@ type ConfigFile struct {
 	Path         string    `json:"path"`
 	Category     string    `json:"category"`
 	Description  string    `json:"description"`
+	Format       string    `json:"format,omitempty"`   // 校验使用的文件格式，为空时按文件名推断
+	Tags         []string  `json:"tags,omitempty"`     // 用户定义的标签，可用于按标签导出
+	Template     bool      `json:"template,omitempty"` // 为 true 时 Content 是模板源文件，写入真实路径前用本机变量渲染
 	LastModified time.Time `json:"lastModified"`
 	Size         int64     `json:"size"`
 	IsSymlink    bool      `json:"isSymlink"`
 	BackupExists bool      `json:"backupExists"`
 	Content      string    `json:"content,omitempty"`
+	ETag         string    `json:"etag,omitempty"` // 内容的 SHA-256，用于并发修改检测
 }
 
 // ConfigCategory 表示配置文件分类的数据模型
@@ -29,20 +33,114 @@ type ConfigCategory struct {
 
 // SystemInfo 表示系统信息的数据模型
 type SystemInfo struct {
-	OS      string `json:"os"`
-	Kernel  string `json:"kernel"`
-	Shell   string `json:"shell"`
-	HomeDir string `json:"homeDir"`
-	User    string `json:"user"`
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`
+	Kernel   string `json:"kernel"`
+	Shell    string `json:"shell"`
+	HomeDir  string `json:"homeDir"`
+	User     string `json:"user"`
 }
 
 // UpdateFileRequest 表示更新文件的请求数据
 type UpdateFileRequest struct {
-	Content string `json:"content" validate:"required"`
+	Content       string `json:"content" validate:"required"`
+	SymlinkPolicy string `json:"symlinkPolicy,omitempty"` // follow（默认）写入链接目标，replace 用普通文件替换链接
+	Force         bool   `json:"force,omitempty"`         // 为 true 时即使校验出错误也写入
+}
+
+// ConflictInfo 表示 If-Match 校验失败时磁盘上的当前状态
+type ConflictInfo struct {
+	Path        string `json:"path"`
+	CurrentETag string `json:"currentEtag"`
+	Content     string `json:"content"` // 磁盘上的当前内容
+	Diff        string `json:"diff"`    // 从当前内容到提交内容的统一格式差异
+}
+
+// ImportConflict 表示导入时因本地文件在导出之后被修改而跳过的文件
+type ImportConflict struct {
+	FileID       string    `json:"fileId"`
+	Path         string    `json:"path"`
+	LastModified time.Time `json:"lastModified"` // 本地文件的修改时间
+	Diff         string    `json:"diff"`         // 从本地内容到导入内容的统一格式差异
+}
+
+// WriteResult 表示一次文件写入的结果
+type WriteResult struct {
+	ETag          string       `json:"etag"`                    // 写入后内容的 ETag
+	Path          string       `json:"path"`                    // 配置文件的真实路径
+	WrittenPath   string       `json:"writtenPath"`             // 实际写入的文件路径
+	Mode          string       `json:"mode"`                    // 写入后的权限，例如 0600
+	IsSymlink     bool         `json:"isSymlink"`               // 写入前 Path 是否为符号链接
+	SymlinkPolicy string       `json:"symlinkPolicy,omitempty"` // 对符号链接采用的策略
+	LinkTarget    string       `json:"linkTarget,omitempty"`    // 符号链接指向的目标
+	Diagnostics   []Diagnostic `json:"diagnostics,omitempty"`   // 校验产生的警告，或强制写入时被忽略的错误
+}
+
+// Revision 表示配置文件的一个历史版本
+type Revision struct {
+	Rev      string    `json:"rev"`
+	ShortRev string    `json:"shortRev"`
+	Author   string    `json:"author"`
+	Date     time.Time `json:"date"`
+	Message  string    `json:"message"`
+}
+
+// RevisionContent 表示某个历史版本的文件内容
+type RevisionContent struct {
+	Revision
+	Content string `json:"content"`
+}
+
+// RegisterFileRequest 表示登记新配置文件的请求数据
+// ID 和 Name 可省略，分别由文件名和路径推导
+type RegisterFileRequest struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Category    string   `json:"category"`
+	Description string   `json:"description"`
+	Format      string   `json:"format,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Template    bool     `json:"template,omitempty"` // 以模板方式管理，模板源文件从当前文件内容初始化
+}
+
+// UpdateFileMetaRequest 表示修改配置文件元数据的请求数据，未提供的字段保持不变
+type UpdateFileMetaRequest struct {
+	Name        *string   `json:"name,omitempty"`
+	Path        *string   `json:"path,omitempty"`
+	Category    *string   `json:"category,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Format      *string   `json:"format,omitempty"`   // 空字符串表示恢复为按文件名推断
+	Tags        *[]string `json:"tags,omitempty"`     // 整体替换标签列表，空数组表示清除所有标签
+	Template    *bool     `json:"template,omitempty"` // 开启时模板源文件从当前文件内容初始化，关闭时保留真实文件的渲染结果
 }
 
 // BackupFileResponse 表示备份文件的响应数据
 type BackupFileResponse struct {
-	Message    string `json:"message"`
-	BackupPath string `json:"backupPath"`
+	Message    string  `json:"message"`
+	BackupPath string  `json:"backupPath"`
+	Backup     *Backup `json:"backup,omitempty"`
+}
+
+// BackupFileRequest 表示创建备份的请求数据（可选）
+type BackupFileRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Backup 表示备份目录中的一个备份及其元数据
+type Backup struct {
+	ID        string    `json:"id"`
+	FileID    string    `json:"fileId"`
+	FilePath  string    `json:"filePath"`
+	Hash      string    `json:"hash"` // 内容的 SHA-256
+	Size      int64     `json:"size"`
+	Mode      uint32    `json:"mode"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// BackupPolicy 表示备份保留策略，两项均为 0 时不自动清理
+type BackupPolicy struct {
+	KeepLast      int `json:"keepLast"`      // 每个文件始终保留最新的 N 个备份
+	KeepDailyDays int `json:"keepDailyDays"` // 最近 M 天内每天额外保留当天最新的一个备份
 }
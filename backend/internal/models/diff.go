@@ -0,0 +1,46 @@
+package models
+
+// DiffResult 表示两个版本之间的差异，同时提供统一格式文本和并排视图
+type DiffResult struct {
+	From      string     `json:"from"` // 旧版本的描述，例如 "current"、"backup:<ID>"
+	To        string     `json:"to"`
+	Identical bool       `json:"identical"`
+	Added     int        `json:"added"`   // 新增的行数
+	Removed   int        `json:"removed"` // 删除的行数
+	Unified   string     `json:"unified"` // 统一格式（unified）差异文本
+	Hunks     []DiffHunk `json:"hunks"`   // 并排视图的变更块
+}
+
+// DiffHunk 表示并排视图中的一个变更块，行号从 1 开始
+type DiffHunk struct {
+	FromStart int       `json:"fromStart"`
+	FromCount int       `json:"fromCount"`
+	ToStart   int       `json:"toStart"`
+	ToCount   int       `json:"toCount"`
+	Rows      []DiffRow `json:"rows"`
+}
+
+// DiffRow 表示并排视图中的一行：equal、delete（只有左侧）、insert（只有右侧）或 change（两侧都有）
+type DiffRow struct {
+	Kind  string    `json:"kind"`
+	Left  *DiffLine `json:"left,omitempty"`
+	Right *DiffLine `json:"right,omitempty"`
+}
+
+// DiffLine 表示并排视图一侧的一行，Text 不含换行符
+type DiffLine struct {
+	Number   int           `json:"number"`
+	Text     string        `json:"text"`
+	Segments []DiffSegment `json:"segments,omitempty"` // 仅 change 行提供行内差异
+}
+
+// DiffSegment 表示行内差异中的一段文本
+type DiffSegment struct {
+	Text    string `json:"text"`
+	Changed bool   `json:"changed"`
+}
+
+// DiffRequest 表示与提议内容比较的请求数据
+type DiffRequest struct {
+	Content string `json:"content"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// DiscoveredFile 表示扫描发现的、尚未登记的候选配置文件
+type DiscoveredFile struct {
+	ConfigFile
+	Application string `json:"application"`
+	Known       bool   `json:"known"`             // 是否匹配已知应用签名，否则为通用规则猜测
+	Sensitive   bool   `json:"sensitive"`         // 文件可能包含令牌、密码等凭据
+	Warning     string `json:"warning,omitempty"` // 可能包含凭据的原因
+	Selected    bool   `json:"selected"`          // 是否建议默认选中，可能包含凭据的文件默认不选中
+}
+
+// AdoptRequest 表示将候选文件纳入管理的请求数据
+type AdoptRequest struct {
+	IDs        []string          `json:"ids"`
+	Categories map[string]string `json:"categories,omitempty"` // 可选：按候选ID覆盖建议的分类
+}
+
+// AdoptResult 表示纳入管理的结果
+type AdoptResult struct {
+	Adopted []ConfigFile `json:"adopted"`
+	Errors  []string     `json:"errors"`
+}
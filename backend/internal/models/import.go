@@ -0,0 +1,163 @@
+package models
+
+import "time"
+
+// 导入计划中条目的处理方式
+const (
+	ImportActionCreate    = "create"    // 本地文件不存在，将新建
+	ImportActionOverwrite = "overwrite" // 覆盖本地文件
+	ImportActionSkip      = "skip"      // 无法匹配、无法读取或内容相同，不做处理
+	ImportActionConflict  = "conflict"  // 本地文件在导出之后被修改过，需要强制执行
+	ImportActionRegister  = "register"  // 内容与本地文件相同，只登记文件
+)
+
+// 压缩包或条目因安全限制被拒绝时的错误码
+const (
+	ArchiveErrInvalid          = "invalid_archive"     // 无法解析的压缩包
+	ArchiveErrTooManyEntries   = "too_many_entries"    // 条目数量超过限制
+	ArchiveErrTotalTooLarge    = "archive_too_large"   // 解压后的总大小超过限制
+	ArchiveErrEntryTooLarge    = "entry_too_large"     // 单个条目解压后超过大小限制
+	ArchiveErrCompressionRatio = "compression_ratio"   // 压缩比异常，疑似压缩炸弹
+	ArchiveErrAbsolutePath     = "absolute_path"       // 条目名称是绝对路径
+	ArchiveErrPathTraversal    = "path_traversal"      // 条目名称包含 .. 路径段
+	ArchiveErrInvalidName      = "invalid_name"        // 条目名称为空或包含非法字符
+	ArchiveErrSymlink          = "symlink_entry"       // 条目是符号链接
+	ArchiveErrUnsupported      = "unsupported_entry"   // 条目不是普通文件
+	ArchiveErrDuplicateName    = "duplicate_name"      // 多个条目的名称相同
+	ArchiveErrUnreadable       = "unreadable_entry"    // 条目无法解压或校验失败
+	ArchiveErrPassphrase       = "passphrase_required" // 加密的导出包需要提供口令
+	ArchiveErrDecrypt          = "decryption_failed"   // 口令错误或加密包被篡改
+	ArchiveErrVersion          = "unsupported_version" // 不支持的加密包版本或算法
+	ArchiveErrUnsigned         = "unsigned_bundle"     // 策略要求签名但导出包没有签名
+	ArchiveErrSignature        = "invalid_signature"   // 签名与配置清单不匹配
+	ArchiveErrUntrusted        = "untrusted_signer"    // 签名者不在受信任列表中
+	ArchiveErrHashMismatch     = "hash_mismatch"       // 条目内容与已签名清单中的哈希不一致
+	ArchiveErrUnsignedEntry    = "unsigned_entry"      // 条目不在已签名的清单中
+	ArchiveErrReviewRequired   = "review_required"     // 条目需要预览导入计划并确认后才能执行
+)
+
+// ImportEntryError 表示压缩包或其中某个条目被拒绝的原因
+type ImportEntryError struct {
+	Entry   string `json:"entry,omitempty"` // 为空时表示整个压缩包被拒绝
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ImportPlanEntry 表示导入计划中压缩包的一个条目
+type ImportPlanEntry struct {
+	Entry        string     `json:"entry"`            // 条目在压缩包中的路径
+	FileID       string     `json:"fileId,omitempty"` // 匹配到的配置文件ID
+	Path         string     `json:"path,omitempty"`   // 匹配到的配置文件路径
+	Action       string     `json:"action"`
+	Reason       string     `json:"reason,omitempty"`     // skip 和 conflict 的原因
+	ErrorCode    string     `json:"errorCode,omitempty"`  // 条目因安全限制被拒绝时的错误码
+	Warning      string     `json:"warning,omitempty"`    // 未按配置清单精确匹配等需要用户确认的情况
+	Register     bool       `json:"register,omitempty"`   // 执行时会按配置清单登记该文件
+	Mode         string     `json:"mode,omitempty"`       // 执行后恢复的八进制权限
+	LinkTarget   string     `json:"linkTarget,omitempty"` // 条目是符号链接时恢复的链接内容
+	Size         int64      `json:"size"`
+	LastModified *time.Time `json:"lastModified,omitempty"` // 本地文件的修改时间
+	Diff         string     `json:"diff,omitempty"`         // 从本地内容到导入内容的统一格式差异
+}
+
+// ImportPlan 表示导入预览的结果，可在过期前通过ID执行其中的部分或全部条目
+type ImportPlan struct {
+	ID        string            `json:"id"`
+	CreatedAt time.Time         `json:"createdAt"`
+	ExpiresAt time.Time         `json:"expiresAt"`
+	Signature SignatureStatus   `json:"signature"`        // 导出包签名的校验结果
+	Source    *ImportSource     `json:"source,omitempty"` // 从其他 dotfile 管理工具导入时的来源
+	Entries   []ImportPlanEntry `json:"entries"`
+}
+
+// 支持导入的其他 dotfile 管理工具
+const (
+	ImportSourceChezmoi = "chezmoi" // chezmoi 源目录，按 dot_、private_、executable_ 等前缀还原文件名和权限
+	ImportSourceStow    = "stow"    // GNU stow 目录，每个子目录是一个软件包，包内的路径相对于目标目录
+	ImportSourceYadm    = "yadm"    // yadm 仓库或其他以主目录为工作区的 git 仓库（包括裸仓库）
+)
+
+// ImportSource 表示从其他 dotfile 管理工具的本地目录导入的请求数据
+type ImportSource struct {
+	Type          string `json:"type"`
+	Path          string `json:"path"`                    // 源目录、stow 目录或 git 仓库的路径，可以 ~ 开头
+	Target        string `json:"target,omitempty"`        // stow 的目标目录，默认为 stow 目录的上级目录
+	Dotfiles      bool   `json:"dotfiles,omitempty"`      // stow 是否按 --dotfiles 将 dot- 前缀还原为 .
+	CreateMissing bool   `json:"createMissing,omitempty"` // 为 true 时新建本地不存在的文件，本地已存在的文件总是按计划登记
+}
+
+// ApplyImportRequest 表示执行导入计划的请求数据
+type ApplyImportRequest struct {
+	Entries        []string `json:"entries"`                  // 要执行的条目路径，为空时执行全部条目
+	Force          bool     `json:"force"`                    // 为 true 时也执行 conflict 条目
+	SkipValidation bool     `json:"skipValidation,omitempty"` // 为 true 时导入内容未通过格式校验也写入
+}
+
+// ImportResult 表示导入的执行结果
+type ImportResult struct {
+	ImportedFiles int                `json:"importedFiles"`
+	SkippedFiles  int                `json:"skippedFiles"`
+	Errors        []string           `json:"errors"`
+	EntryErrors   []ImportEntryError `json:"entryErrors"` // 因安全限制被拒绝的条目
+	Warnings      []string           `json:"warnings"`
+	Signature     SignatureStatus    `json:"signature"` // 导出包签名的校验结果
+	Conflicts     []ImportConflict   `json:"conflicts"`
+	Backups       []Backup           `json:"backups"` // 执行前为被覆盖的文件自动创建的备份
+	Message       string             `json:"message"`
+}
+
+// ExportManifest 表示导出压缩包中的配置清单（配置清单.json）
+type ExportManifest struct {
+	ExportTime string           `json:"exportTime"` // 本地时间，旧版清单只有该字段
+	ExportedAt string           `json:"exportedAt"` // RFC3339 格式的导出时间
+	TotalFiles int              `json:"totalFiles"`
+	Categories []ConfigCategory `json:"categories"`
+	Files      []ManifestFile   `json:"files"`
+	Encryption *EncryptionInfo  `json:"encryption,omitempty"` // 导出包加密时的算法和密钥派生参数
+	Selection  *ExportSelection `json:"selection,omitempty"`  // 选择性导出时使用的筛选条件
+	Skipped    []string         `json:"skipped,omitempty"`    // 符合筛选条件但不存在或无法读取、未导出的文件ID
+}
+
+// ExportSelection 表示选择性导出的筛选条件
+// 符合 categories、ids、tags 中任意一项的文件都会导出，三者都为空时导出所有文件；exclude 中的文件总是排除
+// profile 指定档案时，档案中的分类和文件并入 categories 和 ids
+type ExportSelection struct {
+	Categories []string `json:"categories,omitempty"`
+	IDs        []string `json:"ids,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Exclude    []string `json:"exclude,omitempty"`
+	Profile    string   `json:"profile,omitempty"`
+}
+
+// IsEmpty 判断是否没有任何筛选条件
+func (s ExportSelection) IsEmpty() bool {
+	return len(s.Categories) == 0 && len(s.IDs) == 0 && len(s.Tags) == 0 && len(s.Exclude) == 0 && s.Profile == ""
+}
+
+// ExportRequest 表示 POST /api/export 的请求数据，口令仍通过请求头传递
+type ExportRequest struct {
+	ExportSelection
+	Format string `json:"format,omitempty"`
+}
+
+// EncryptionInfo 记录加密导出包的版本、算法和密钥派生参数，解密时据此重新派生密钥
+type EncryptionInfo struct {
+	Version    int    `json:"version"`
+	Cipher     string `json:"cipher"` // 认证加密算法，目前为 AES-256-GCM
+	KDF        string `json:"kdf"`    // 口令派生密钥的算法，目前为 PBKDF2-HMAC-SHA256
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`                // base64 编码
+	Nonce      string `json:"nonce,omitempty"`     // base64 编码，只记录在加密包头部；版本 2 为分段随机数的前缀
+	ChunkSize  int    `json:"chunkSize,omitempty"` // 版本 2 分段加密时每段明文的字节数
+	Format     string `json:"format"`              // 加密前的压缩包格式
+}
+
+// ManifestFile 表示配置清单中的一个文件
+type ManifestFile struct {
+	ConfigFile
+	ArchivePath string     `json:"archivePath,omitempty"` // 文件在压缩包中的路径，旧版清单没有该字段
+	Mode        string     `json:"mode,omitempty"`        // 导出时的八进制权限，例如 0600
+	ModTime     *time.Time `json:"modTime,omitempty"`     // 导出时的修改时间
+	LinkTarget  string     `json:"linkTarget,omitempty"`  // 文件是符号链接时链接的内容
+	SHA256      string     `json:"sha256,omitempty"`      // 压缩包中条目内容的 SHA-256，随清单一起签名
+}
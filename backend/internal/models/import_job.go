@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// 导入任务的状态
+const (
+	ImportJobUploading  = "uploading"  // 正在接收上传的分块
+	ImportJobUploaded   = "uploaded"   // 已接收完整的压缩包，等待开始处理
+	ImportJobProcessing = "processing" // 正在生成导入计划或导入文件
+	ImportJobCompleted  = "completed"
+	ImportJobFailed     = "failed"
+)
+
+// 导入任务的处理方式
+const (
+	ImportJobModePlan   = "plan"   // 只生成导入计划，之后通过 /api/import/plans/{planId}/apply 执行
+	ImportJobModeImport = "import" // 生成计划并立即导入全部条目
+)
+
+// ImportJob 表示一次导入任务：上传的压缩包先写入临时文件，再在后台生成导入计划或导入
+type ImportJob struct {
+	ID          string            `json:"id"`
+	Status      string            `json:"status"`
+	Mode        string            `json:"mode,omitempty"`
+	FileName    string            `json:"fileName"`
+	Size        int64             `json:"size"`     // 压缩包的总大小
+	Received    int64             `json:"received"` // 已接收的字节数，分块上传中断后从该位置继续
+	Stage       string            `json:"stage,omitempty"`
+	Processed   int               `json:"processed"` // 当前阶段已处理的条目数
+	Total       int               `json:"total"`     // 当前阶段的条目总数，未知时为 0
+	Plan        *ImportPlan       `json:"plan,omitempty"`
+	Result      *ImportResult     `json:"result,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	ErrorDetail *ImportEntryError `json:"errorDetail,omitempty"` // 压缩包因安全限制被拒绝时的错误码
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+	ExpiresAt   time.Time         `json:"expiresAt"`
+}
+
+// CreateImportJobRequest 表示创建分块上传任务的请求数据
+type CreateImportJobRequest struct {
+	FileName string `json:"fileName"`
+	Size     int64  `json:"size"`
+}
+
+// StartImportJobRequest 表示开始处理已上传压缩包的请求数据
+type StartImportJobRequest struct {
+	Mode           string `json:"mode"` // plan（默认）或 import
+	Force          bool   `json:"force,omitempty"`
+	SkipValidation bool   `json:"skipValidation,omitempty"` // 为 true 时导入内容未通过格式校验也写入
+	CreateMissing  bool   `json:"createMissing,omitempty"`
+	Passphrase     string `json:"passphrase,omitempty"`
+}
@@ -0,0 +1,45 @@
+package models
+
+// MachineProfile 表示一个机器配置档案：适用于哪些机器、使用哪些变量、部署哪些文件
+type MachineProfile struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Match       ProfileMatch      `json:"match"`
+	Priority    int               `json:"priority,omitempty"`   // 多个档案匹配时优先级高的生效
+	Vars        map[string]string `json:"vars,omitempty"`       // 渲染模板时覆盖同名的全局变量
+	Categories  []string          `json:"categories,omitempty"` // 部署的分类，与 Files 取并集，都为空时部署全部文件
+	Files       []string          `json:"files,omitempty"`      // 部署的文件ID
+}
+
+// ProfileMatch 表示档案的机器匹配规则，各字段为不区分大小写的通配符，为空的字段不参与匹配
+// 所有规则都为空的档案匹配任何机器，可作为默认档案
+type ProfileMatch struct {
+	Hostname string `json:"hostname,omitempty"`
+	OS       string `json:"os,omitempty"`
+	Kernel   string `json:"kernel,omitempty"`
+}
+
+// ProfileResolution 表示活动档案的解析结果
+type ProfileResolution struct {
+	Active     *MachineProfile    `json:"active"` // 没有档案匹配时为 null
+	System     SystemInfo         `json:"system"` // 参与匹配的机器信息
+	Reason     string             `json:"reason"` // 活动档案被选中（或没有档案匹配）的原因
+	Candidates []ProfileCandidate `json:"candidates"`
+}
+
+// ProfileCandidate 表示单个档案的匹配情况
+type ProfileCandidate struct {
+	ProfileID string              `json:"profileId"`
+	Priority  int                 `json:"priority"`
+	Matched   bool                `json:"matched"`
+	Rules     []ProfileRuleResult `json:"rules"`
+}
+
+// ProfileRuleResult 表示单条匹配规则的结果
+type ProfileRuleResult struct {
+	Field   string `json:"field"`   // hostname、os 或 kernel
+	Pattern string `json:"pattern"` // 档案中的通配符
+	Value   string `json:"value"`   // 机器的实际值
+	Matched bool   `json:"matched"`
+}
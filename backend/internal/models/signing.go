@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// 导入时对签名的处理策略
+const (
+	SignaturePolicyWarn    = "warn"    // 未签名或校验失败时仍可导入，在计划和结果中给出警告
+	SignaturePolicyRequire = "require" // 只导入由受信任密钥签名且内容一致的导出包
+)
+
+// 导出包签名的校验结果
+const (
+	SignatureValid     = "valid"     // 签名有效且签名者受信任
+	SignatureUnsigned  = "unsigned"  // 没有签名或配置清单
+	SignatureUntrusted = "untrusted" // 签名有效但签名者不在受信任列表中
+	SignatureInvalid   = "invalid"   // 签名与配置清单不匹配
+)
+
+// SigningKey 表示本机用于签名导出包的公钥
+type SigningKey struct {
+	KeyID     string    `json:"keyId"`     // 公钥 SHA-256 的前 16 个十六进制字符
+	Algorithm string    `json:"algorithm"` // 目前为 ed25519
+	PublicKey string    `json:"publicKey"` // base64 编码
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// TrustedKey 表示受信任的签名公钥
+type TrustedKey struct {
+	KeyID     string    `json:"keyId"`
+	Name      string    `json:"name"`
+	PublicKey string    `json:"publicKey"` // base64 编码的 ed25519 公钥
+	AddedAt   time.Time `json:"addedAt"`
+}
+
+// TrustSettings 表示签名策略和受信任的公钥列表，本机密钥始终受信任
+type TrustSettings struct {
+	Policy string       `json:"policy"`
+	Keys   []TrustedKey `json:"keys"`
+}
+
+// AddTrustedKeyRequest 表示添加受信任公钥的请求数据
+type AddTrustedKeyRequest struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"publicKey"`
+}
+
+// UpdateTrustPolicyRequest 表示修改签名策略的请求数据
+type UpdateTrustPolicyRequest struct {
+	Policy string `json:"policy"`
+}
+
+// ManifestSignature 表示导出包中配置清单的签名（配置清单.sig）
+type ManifestSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId"`
+	PublicKey string `json:"publicKey"` // base64 编码
+	Signature string `json:"signature"` // 对配置清单原始字节的签名，base64 编码
+}
+
+// SignatureStatus 表示导入时对导出包签名的校验结果
+type SignatureStatus struct {
+	Status  string `json:"status"`
+	KeyID   string `json:"keyId,omitempty"`
+	Signer  string `json:"signer,omitempty"` // 受信任公钥的名称
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// TemplateData 表示渲染模板文件时可用的数据，模板中以 {{ .Hostname }}、{{ .Vars.email }} 等方式引用
+type TemplateData struct {
+	Hostname string            `json:"hostname"`
+	OS       string            `json:"os"`
+	Kernel   string            `json:"kernel"`
+	User     string            `json:"user"`
+	HomeDir  string            `json:"homeDir"`
+	Shell    string            `json:"shell"`
+	Profile  string            `json:"profile"` // 活动档案的ID，没有档案匹配时为空
+	Vars     map[string]string `json:"vars"`    // 全局变量与活动档案的变量合并后的结果
+}
+
+// RenderResult 表示模板文件的渲染结果
+type RenderResult struct {
+	FileID  string       `json:"fileId"`
+	Path    string       `json:"path"`
+	Content string       `json:"content"`        // 渲染后的内容
+	Data    TemplateData `json:"data"`           // 渲染使用的数据
+	Diff    string       `json:"diff,omitempty"` // 从磁盘上的当前内容到渲染结果的统一格式差异
+}
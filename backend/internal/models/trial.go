@@ -0,0 +1,20 @@
+package models
+
+// TrialRequest 表示试运行 shell 配置的请求数据，所有字段均可省略
+type TrialRequest struct {
+	Content   *string `json:"content,omitempty"`   // 要试运行的内容，省略时使用磁盘上的当前内容
+	Shell     string  `json:"shell,omitempty"`     // 指定 shell（bash、zsh、sh），省略时按文件格式或用户的登录 shell 选择
+	TimeoutMs int     `json:"timeoutMs,omitempty"` // 超时时间（毫秒），省略时使用默认值
+}
+
+// TrialResult 表示在临时 HOME 中启动 shell 的结果
+type TrialResult struct {
+	Shell      string `json:"shell"`      // 实际使用的 shell 路径
+	ExitCode   int    `json:"exitCode"`   // 退出码，超时被终止时为 -1
+	Stdout     string `json:"stdout"`     // 标准输出（超出上限的部分被截断）
+	Stderr     string `json:"stderr"`     // 标准错误（超出上限的部分被截断）
+	DurationMs int64  `json:"durationMs"` // 从启动到退出的耗时
+	TimedOut   bool   `json:"timedOut"`
+	Warning    string `json:"warning,omitempty"` // 后台进程在 shell 退出后被终止等情况
+	Clean      bool   `json:"clean"`             // 退出码为 0、没有超时、没有警告且 stderr 为空
+}
@@ -0,0 +1,22 @@
+package models
+
+// Diagnostic 表示校验发现的一个问题，行号和列号从 1 开始，0 表示无法定位
+type Diagnostic struct {
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"` // error 会阻止保存，warning 仅作提示
+	Message  string `json:"message"`
+	Source   string `json:"source"` // 产生诊断的校验器，例如 bash、gitconfig
+}
+
+// ValidationResult 表示一次格式校验的结果
+type ValidationResult struct {
+	Format      string       `json:"format"` // 使用的文件格式，为空表示没有对应的校验器
+	Valid       bool         `json:"valid"`  // 没有 error 级别的诊断
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// ValidateFileRequest 表示校验文件的请求数据，Content 省略时校验磁盘上的当前内容
+type ValidateFileRequest struct {
+	Content *string `json:"content,omitempty"`
+}
@@ -16,24 +16,92 @@ func SetupRoutes() *mux.Router {
 	// 创建服务实例
 	configService := services.NewConfigService()
 	systemService := services.NewSystemService()
+	discoveryService := services.NewDiscoveryService(configService)
+	trialService := services.NewTrialService(configService, systemService)
+	signingService := services.NewSigningService()
+	importService := services.NewImportService(configService, signingService)
+	importJobService := services.NewImportJobService(importService)
+	exportService := services.NewExportService(configService, signingService)
 
 	// 创建处理器实例
 	configHandler := handlers.NewConfigHandler(configService)
 	systemHandler := handlers.NewSystemHandler(systemService)
+	discoveryHandler := handlers.NewDiscoveryHandler(discoveryService)
+	trialHandler := handlers.NewTrialHandler(trialService)
+	importHandler := handlers.NewImportHandler(importService, importJobService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	signingHandler := handlers.NewSigningHandler(signingService)
 
 	// API 路由组
 	api := r.PathPrefix("/api").Subrouter()
 
 	// 配置文件相关路由
 	api.HandleFunc("/categories", configHandler.GetCategories).Methods("GET")
+	api.HandleFunc("/categories", configHandler.CreateCategory).Methods("POST")
+	api.HandleFunc("/categories/{id}", configHandler.UpdateCategory).Methods("PUT")
+	api.HandleFunc("/categories/{id}", configHandler.DeleteCategory).Methods("DELETE")
 	api.HandleFunc("/files", configHandler.GetFiles).Methods("GET")
+	api.HandleFunc("/files", configHandler.RegisterFile).Methods("POST")
 	api.HandleFunc("/files/{id}", configHandler.GetFile).Methods("GET")
 	api.HandleFunc("/files/{id}", configHandler.UpdateFile).Methods("PUT")
+	api.HandleFunc("/files/{id}", configHandler.UpdateFileMeta).Methods("PATCH")
+	api.HandleFunc("/files/{id}", configHandler.UnregisterFile).Methods("DELETE")
+	api.HandleFunc("/files/{id}/validate", configHandler.ValidateFile).Methods("POST")
+	api.HandleFunc("/files/{id}/trial", trialHandler.TrialFile).Methods("POST")
+	api.HandleFunc("/files/{id}/diff", configHandler.DiffProposed).Methods("POST")
+	api.HandleFunc("/files/{id}/diff", configHandler.DiffAgainst).Methods("GET")
 	api.HandleFunc("/files/{id}/backup", configHandler.BackupFile).Methods("POST")
+	api.HandleFunc("/files/{id}/backups", configHandler.ListFileBackups).Methods("GET")
+	api.HandleFunc("/files/{id}/history", configHandler.GetFileHistory).Methods("GET")
+	api.HandleFunc("/files/{id}/history/{rev}", configHandler.GetFileRevision).Methods("GET")
+	api.HandleFunc("/files/{id}/restore/{rev}", configHandler.RestoreFileRevision).Methods("POST")
+	api.HandleFunc("/files/{id}/render", configHandler.RenderFile).Methods("GET")
+	api.HandleFunc("/files/{id}/blocks", configHandler.ListBlocks).Methods("GET")
+	api.HandleFunc("/files/{id}/blocks/{name}", configHandler.SetBlock).Methods("PUT")
+	api.HandleFunc("/files/{id}/blocks/{name}", configHandler.RemoveBlock).Methods("DELETE")
 	
+	// 备份相关路由
+	api.HandleFunc("/backups", configHandler.ListBackups).Methods("GET")
+	api.HandleFunc("/backups/policy", configHandler.GetBackupPolicy).Methods("GET")
+	api.HandleFunc("/backups/policy", configHandler.UpdateBackupPolicy).Methods("PUT")
+	api.HandleFunc("/backups/{backupId}/restore", configHandler.RestoreBackup).Methods("POST")
+	api.HandleFunc("/backups/{backupId}", configHandler.DeleteBackup).Methods("DELETE")
+
+	// 模板变量相关路由
+	api.HandleFunc("/templates/vars", configHandler.GetTemplateVars).Methods("GET")
+	api.HandleFunc("/templates/vars", configHandler.UpdateTemplateVars).Methods("PUT")
+
+	// 机器配置档案相关路由
+	api.HandleFunc("/profiles", configHandler.ListProfiles).Methods("GET")
+	api.HandleFunc("/profiles", configHandler.CreateProfile).Methods("POST")
+	api.HandleFunc("/profiles/active", configHandler.GetActiveProfile).Methods("GET")
+	api.HandleFunc("/profiles/{id}", configHandler.GetProfile).Methods("GET")
+	api.HandleFunc("/profiles/{id}", configHandler.UpdateProfile).Methods("PUT")
+	api.HandleFunc("/profiles/{id}", configHandler.DeleteProfile).Methods("DELETE")
+
+	// 配置文件自动发现路由
+	api.HandleFunc("/discover", discoveryHandler.Discover).Methods("GET")
+	api.HandleFunc("/discover/adopt", discoveryHandler.Adopt).Methods("POST")
+
 	// 导入导出相关路由
-	api.HandleFunc("/export", configHandler.ExportConfigs).Methods("GET")
-	api.HandleFunc("/import", configHandler.ImportConfigs).Methods("POST")
+	api.HandleFunc("/export", exportHandler.ExportConfigs).Methods("GET")
+	api.HandleFunc("/export", exportHandler.ExportSelected).Methods("POST")
+	api.HandleFunc("/import", importHandler.ImportConfigs).Methods("POST")
+	api.HandleFunc("/import/plans/{planId}", importHandler.GetImportPlan).Methods("GET")
+	api.HandleFunc("/import/plans/{planId}/apply", importHandler.ApplyImportPlan).Methods("POST")
+	api.HandleFunc("/import/sources", importHandler.ImportFromSource).Methods("POST")
+	api.HandleFunc("/import/jobs", importHandler.CreateImportJob).Methods("POST")
+	api.HandleFunc("/import/jobs/{jobId}", importHandler.GetImportJob).Methods("GET")
+	api.HandleFunc("/import/jobs/{jobId}", importHandler.DeleteImportJob).Methods("DELETE")
+	api.HandleFunc("/import/jobs/{jobId}/chunks", importHandler.UploadImportChunk).Methods("PUT")
+	api.HandleFunc("/import/jobs/{jobId}/start", importHandler.StartImportJob).Methods("POST")
+
+	// 导出包签名相关路由
+	api.HandleFunc("/signing/key", signingHandler.GetSigningKey).Methods("GET")
+	api.HandleFunc("/signing/trust", signingHandler.GetTrust).Methods("GET")
+	api.HandleFunc("/signing/trust/policy", signingHandler.UpdateTrustPolicy).Methods("PUT")
+	api.HandleFunc("/signing/trust/keys", signingHandler.AddTrustedKey).Methods("POST")
+	api.HandleFunc("/signing/trust/keys/{keyId}", signingHandler.RemoveTrustedKey).Methods("DELETE")
 
 	// 系统信息相关路由
 	api.HandleFunc("/system", systemHandler.GetSystemInfo).Methods("GET")
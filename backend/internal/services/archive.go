@@ -0,0 +1,346 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// archiveLimits 读取导入压缩包时的安全限制
+type archiveLimits struct {
+	maxEntries   int   // 压缩包中的最大条目数（包括目录）
+	maxEntrySize int64 // 单个条目解压后的最大字节数
+	maxTotalSize int64 // 所有条目解压后的最大总字节数
+	maxRatio     int64 // 单个条目允许的最大压缩比
+}
+
+// defaultArchiveLimits 导入压缩包的默认限制，配置文件通常只有几 KB；上传限制更大时由 archiveLimitsFor 放宽
+var defaultArchiveLimits = archiveLimits{
+	maxEntries:   1000,
+	maxEntrySize: 4 << 20,
+	maxTotalSize: 64 << 20,
+	maxRatio:     100,
+}
+
+// archiveLimitsFor 按上传限制放宽默认限制：解压后的总大小不小于上传限制，单个条目不小于上传限制的 1/16
+func archiveLimitsFor(maxUploadSize int64) archiveLimits {
+	limits := defaultArchiveLimits
+	if maxUploadSize > limits.maxTotalSize {
+		limits.maxTotalSize = maxUploadSize
+	}
+	if maxUploadSize/16 > limits.maxEntrySize {
+		limits.maxEntrySize = maxUploadSize / 16
+	}
+	return limits
+}
+
+// maxArchiveSize 压缩包本身允许的最大字节数：解压后的总大小加上每个条目的头部
+// 用于限制解密后的压缩包，读取之前就能拒绝明显超限的内容
+func (l archiveLimits) maxArchiveSize() int64 {
+	return l.maxTotalSize + int64(l.maxEntries)*tarHeaderAllowance
+}
+
+// ratioCheckThreshold 解压后小于该大小的条目不检查压缩比，避免误判内容重复的小文件
+const ratioCheckThreshold = 64 << 10
+
+// ArchiveError 表示整个压缩包因安全限制或格式错误被拒绝
+type ArchiveError struct {
+	Detail models.ImportEntryError
+}
+
+func (e *ArchiveError) Error() string { return e.Detail.Message }
+
+func (e *ArchiveError) Unwrap() error { return ErrInvalidInput }
+
+// archiveErrorf 创建拒绝整个压缩包的错误
+func archiveErrorf(code, format string, args ...interface{}) error {
+	return &ArchiveError{Detail: models.ImportEntryError{Code: code, Message: fmt.Sprintf(format, args...)}}
+}
+
+// archiveEntry 通过安全检查读取的压缩包条目，rejected 非空时条目被拒绝且 content 为空
+type archiveEntry struct {
+	name     string
+	size     int64
+	content  []byte
+	meta     entryMeta
+	rejected *models.ImportEntryError
+}
+
+// entryMeta 压缩包记录的文件元数据，零值表示没有记录（ZIP 导出不保存元数据）
+type entryMeta struct {
+	mode       fs.FileMode // 权限位
+	modTime    time.Time
+	linkTarget string // 非空时条目是符号链接
+}
+
+// reject 将条目标记为被拒绝
+func (e *archiveEntry) reject(code, format string, args ...interface{}) {
+	e.content = nil
+	e.rejected = &models.ImportEntryError{Entry: e.name, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// readArchive 按内容识别压缩包格式（gzip 压缩的 tar 或 ZIP）并按安全限制读取
+func readArchive(r io.ReaderAt, size int64, limits archiveLimits) ([]archiveEntry, error) {
+	magic := make([]byte, 2)
+	if n, _ := r.ReadAt(magic, 0); n == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return readTarGzArchive(io.NewSectionReader(r, 0, size), size, limits)
+	}
+	return readZipArchive(r, size, limits)
+}
+
+// readZipArchive 按安全限制读取 ZIP 压缩包中的普通文件条目，跳过目录
+// 单个条目不安全时只拒绝该条目；条目过多或解压总大小超限时拒绝整个压缩包
+func readZipArchive(r io.ReaderAt, size int64, limits archiveLimits) ([]archiveEntry, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "无法读取ZIP文件: %v", err)
+	}
+	if len(zipReader.File) > limits.maxEntries {
+		return nil, archiveErrorf(models.ArchiveErrTooManyEntries, "压缩包包含 %d 个条目，超过上限 %d", len(zipReader.File), limits.maxEntries)
+	}
+
+	var entries []archiveEntry
+	var total int64
+	for _, zipFile := range zipReader.File {
+		mode := zipFile.Mode()
+		if mode.IsDir() {
+			continue
+		}
+
+		entry := archiveEntry{name: zipFile.Name, size: int64(zipFile.UncompressedSize64)}
+		switch code, message := checkEntryName(zipFile.Name); {
+		case code != "":
+			entry.reject(code, "%s", message)
+		case mode&fs.ModeSymlink != 0:
+			entry.reject(models.ArchiveErrSymlink, "不支持符号链接条目")
+		case !mode.IsRegular():
+			entry.reject(models.ArchiveErrUnsupported, "不支持的条目类型: %s", mode.Type())
+		case zipFile.UncompressedSize64 > uint64(limits.maxEntrySize):
+			entry.reject(models.ArchiveErrEntryTooLarge, "条目解压后 %d 字节，超过上限 %d 字节", zipFile.UncompressedSize64, limits.maxEntrySize)
+		default:
+			if err := readZipFile(zipFile, &entry, limits, &total); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, entry)
+	}
+	rejectDuplicates(entries)
+	return entries, nil
+}
+
+// readTarGzArchive 按安全限制读取 gzip 压缩的 tar 包，保留条目的权限、修改时间和符号链接
+// tar 包没有单个条目的压缩大小，压缩比按整个压缩包计算
+func readTarGzArchive(r io.Reader, size int64, limits archiveLimits) ([]archiveEntry, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "无法读取 tar.gz 文件: %v", err)
+	}
+	defer gzipReader.Close()
+	entries, total, err := readTarStream(gzipReader, limits)
+	if err != nil {
+		return nil, err
+	}
+	if total >= ratioCheckThreshold && total > limits.maxRatio*size {
+		return nil, archiveErrorf(models.ArchiveErrCompressionRatio, "压缩包压缩比超过 %d:1，疑似压缩炸弹", limits.maxRatio)
+	}
+	return entries, nil
+}
+
+// readTarArchive 按安全限制读取未压缩的 tar 流，不检查压缩比
+func readTarArchive(r io.Reader, limits archiveLimits) ([]archiveEntry, error) {
+	entries, _, err := readTarStream(r, limits)
+	return entries, err
+}
+
+// readTarStream 按安全限制读取 tar 流中的条目，返回读取的条目内容总字节数
+func readTarStream(r io.Reader, limits archiveLimits) ([]archiveEntry, int64, error) {
+	// 跳过的条目也需要读取，限制读取的总字节数，避免被拒绝的超大条目耗尽 CPU
+	stream := &boundedReader{r: r, remaining: limits.maxArchiveSize()}
+	tarReader := tar.NewReader(stream)
+
+	var entries []archiveEntry
+	var total int64
+	for count := 0; ; count++ {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, errStreamTooLarge) {
+			return nil, 0, archiveErrorf(models.ArchiveErrTotalTooLarge, "压缩包解压后超过总大小上限 %d 字节", limits.maxTotalSize)
+		}
+		if err != nil {
+			return nil, 0, archiveErrorf(models.ArchiveErrInvalid, "无法读取 tar 数据: %v", err)
+		}
+		if count >= limits.maxEntries {
+			return nil, 0, archiveErrorf(models.ArchiveErrTooManyEntries, "压缩包的条目超过上限 %d", limits.maxEntries)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		entry := archiveEntry{
+			name: header.Name,
+			size: header.Size,
+			meta: entryMeta{mode: fs.FileMode(header.Mode).Perm(), modTime: header.ModTime},
+		}
+		switch code, message := checkEntryName(header.Name); {
+		case code != "":
+			entry.reject(code, "%s", message)
+		case header.Typeflag == tar.TypeSymlink:
+			// 符号链接只恢复到登记的文件路径，不会在其他位置解压，因此允许任意链接内容
+			if header.Linkname == "" || strings.ContainsRune(header.Linkname, 0) {
+				entry.reject(models.ArchiveErrInvalidName, "符号链接的内容为空或包含非法字符")
+			} else {
+				entry.size = 0
+				entry.meta.mode = 0
+				entry.meta.linkTarget = header.Linkname
+			}
+		case header.Typeflag != tar.TypeReg:
+			entry.reject(models.ArchiveErrUnsupported, "不支持的条目类型: %q", header.Typeflag)
+		case header.Size > limits.maxEntrySize:
+			entry.reject(models.ArchiveErrEntryTooLarge, "条目解压后 %d 字节，超过上限 %d 字节", header.Size, limits.maxEntrySize)
+		default:
+			content, err := readLimited(tarReader, limits, total)
+			if err != nil {
+				return nil, 0, err
+			}
+			if content == nil {
+				entry.reject(models.ArchiveErrEntryTooLarge, "条目解压后超过上限 %d 字节", limits.maxEntrySize)
+				break
+			}
+			total += int64(len(content))
+			entry.size = int64(len(content))
+			entry.content = content
+		}
+		entries = append(entries, entry)
+	}
+
+	rejectDuplicates(entries)
+	return entries, total, nil
+}
+
+// readLimited 读取条目内容，单个条目超限时返回 nil 内容，解压总大小超限时拒绝整个压缩包
+func readLimited(r io.Reader, limits archiveLimits, total int64) ([]byte, error) {
+	limit := limits.maxEntrySize
+	if remaining := limits.maxTotalSize - total; remaining < limit {
+		limit = remaining
+	}
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if errors.Is(err, errStreamTooLarge) {
+		return nil, archiveErrorf(models.ArchiveErrTotalTooLarge, "压缩包解压后超过总大小上限 %d 字节", limits.maxTotalSize)
+	}
+	if err != nil {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "无法读取条目: %v", err)
+	}
+
+	n := int64(len(content))
+	if n > limits.maxEntrySize {
+		return nil, nil
+	}
+	if n > limit {
+		return nil, archiveErrorf(models.ArchiveErrTotalTooLarge, "压缩包解压后超过总大小上限 %d 字节", limits.maxTotalSize)
+	}
+	return content, nil
+}
+
+// tarHeaderAllowance 计算 tar 解压总字节数上限时为每个条目的头部预留的字节数
+const tarHeaderAllowance = 4 << 10
+
+// errStreamTooLarge 解压的数据超过上限
+var errStreamTooLarge = errors.New("解压的数据超过上限")
+
+// boundedReader 读取的字节数超过上限后返回 errStreamTooLarge
+type boundedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (b *boundedReader) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, errStreamTooLarge
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// rejectDuplicates 拒绝所有同名条目，无法确定以哪个为准
+func rejectDuplicates(entries []archiveEntry) {
+	names := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		names[path.Clean(entry.name)]++
+	}
+	for i := range entries {
+		if names[path.Clean(entries[i].name)] > 1 && entries[i].rejected == nil {
+			entries[i].reject(models.ArchiveErrDuplicateName, "压缩包中有多个同名条目")
+		}
+	}
+}
+
+// readZipFile 读取单个条目的内容，不信任头部声明的大小，按实际解压的字节数检查限制
+func readZipFile(zipFile *zip.File, entry *archiveEntry, limits archiveLimits, total *int64) error {
+	rc, err := zipFile.Open()
+	if err != nil {
+		entry.reject(models.ArchiveErrUnreadable, "无法读取条目: %v", err)
+		return nil
+	}
+	defer rc.Close()
+
+	limit := limits.maxEntrySize
+	if remaining := limits.maxTotalSize - *total; remaining < limit {
+		limit = remaining
+	}
+	content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		entry.reject(models.ArchiveErrUnreadable, "无法读取条目: %v", err)
+		return nil
+	}
+
+	n := int64(len(content))
+	if n > limits.maxEntrySize {
+		entry.reject(models.ArchiveErrEntryTooLarge, "条目解压后超过上限 %d 字节", limits.maxEntrySize)
+		return nil
+	}
+	if n > limit {
+		return archiveErrorf(models.ArchiveErrTotalTooLarge, "压缩包解压后超过总大小上限 %d 字节", limits.maxTotalSize)
+	}
+	if n >= ratioCheckThreshold && n > limits.maxRatio*int64(zipFile.CompressedSize64) {
+		entry.reject(models.ArchiveErrCompressionRatio, "条目压缩比超过 %d:1，疑似压缩炸弹", limits.maxRatio)
+		return nil
+	}
+
+	*total += n
+	entry.size = n
+	entry.content = content
+	return nil
+}
+
+// checkEntryName 检查条目名称是否可能指向目标目录之外或无法解析，返回错误码和原因，安全时错误码为空
+func checkEntryName(name string) (string, string) {
+	switch {
+	case name == "" || strings.ContainsRune(name, 0):
+		return models.ArchiveErrInvalidName, "条目名称为空或包含非法字符"
+	case strings.Contains(name, `\`):
+		return models.ArchiveErrInvalidName, "条目名称包含反斜杠"
+	case strings.HasPrefix(name, "/") || (len(name) >= 2 && name[1] == ':'):
+		return models.ArchiveErrAbsolutePath, "条目名称是绝对路径"
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if segment == ".." {
+			return models.ArchiveErrPathTraversal, "条目名称包含 .. 路径段"
+		}
+	}
+	return "", ""
+}
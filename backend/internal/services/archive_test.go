@@ -0,0 +1,198 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestReadZipArchiveRejectsUnsafeEntries(t *testing.T) {
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	add := func(header *zip.FileHeader, content string) {
+		w, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(&zip.FileHeader{Name: "Shell 配置/.bashrc", Method: zip.Deflate}, "echo ok\n")
+	add(&zip.FileHeader{Name: "../../.bashrc"}, "x")
+	add(&zip.FileHeader{Name: "/etc/passwd"}, "x")
+	add(&zip.FileHeader{Name: `a\..\b`}, "x")
+	link := &zip.FileHeader{Name: "link"}
+	link.SetMode(fs.ModeSymlink | 0777)
+	add(link, "/etc/shadow")
+	add(&zip.FileHeader{Name: "dup"}, "1")
+	add(&zip.FileHeader{Name: "./dup"}, "2")
+	add(&zip.FileHeader{Name: "bomb", Method: zip.Deflate}, strings.Repeat("\x00", 1<<20))
+	add(&zip.FileHeader{Name: "large"}, strings.Repeat("x", 2<<20))
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	limits := archiveLimits{maxEntries: 100, maxEntrySize: 1<<20 + 1, maxTotalSize: 8 << 20, maxRatio: 100}
+	entries, err := readZipArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()), limits)
+	if err != nil {
+		t.Fatalf("读取压缩包失败: %v", err)
+	}
+
+	codes := map[string]string{}
+	for _, entry := range entries {
+		if entry.rejected != nil {
+			codes[entry.name] = entry.rejected.Code
+			if entry.content != nil {
+				t.Errorf("被拒绝的条目 %s 不应有内容", entry.name)
+			}
+		} else {
+			codes[entry.name] = ""
+		}
+	}
+	want := map[string]string{
+		"Shell 配置/.bashrc": "",
+		"../../.bashrc":    models.ArchiveErrPathTraversal,
+		"/etc/passwd":      models.ArchiveErrAbsolutePath,
+		`a\..\b`:           models.ArchiveErrInvalidName,
+		"link":             models.ArchiveErrSymlink,
+		"dup":              models.ArchiveErrDuplicateName,
+		"./dup":            models.ArchiveErrDuplicateName,
+		"bomb":             models.ArchiveErrCompressionRatio,
+		"large":            models.ArchiveErrEntryTooLarge,
+	}
+	for name, code := range want {
+		if got, ok := codes[name]; !ok || got != code {
+			t.Errorf("%s 的错误码错误: got %q want %q", name, got, code)
+		}
+	}
+
+	// 解压总大小超限时拒绝整个压缩包
+	limits.maxTotalSize = 4
+	_, err = readZipArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()), limits)
+	var archiveErr *ArchiveError
+	if !errors.As(err, &archiveErr) || archiveErr.Detail.Code != models.ArchiveErrTotalTooLarge {
+		t.Errorf("应拒绝解压后过大的压缩包, got %v", err)
+	}
+
+	limits.maxEntries = 3
+	_, err = readZipArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()), limits)
+	if !errors.As(err, &archiveErr) || archiveErr.Detail.Code != models.ArchiveErrTooManyEntries {
+		t.Errorf("应拒绝条目过多的压缩包, got %v", err)
+	}
+
+	if _, err := readZipArchive(strings.NewReader("not a zip"), 9, defaultArchiveLimits); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("无效的压缩包应返回 ErrInvalidInput, got %v", err)
+	}
+}
+
+func FuzzReadZipArchive(f *testing.F) {
+	seeds := [][][2]string{
+		{{"Shell 配置/.bashrc", "echo ok\n"}, {manifestName, "{}"}},
+		{{"../escape", "x"}, {"/abs", "y"}},
+		{{"dup", "1"}, {"dup", "2"}},
+		{{"big", strings.Repeat("a", 4096)}},
+	}
+	for _, entries := range seeds {
+		archive := buildTestZip(f, entries)
+		data := make([]byte, archive.Len())
+		archive.Read(data)
+		f.Add(data)
+	}
+	f.Add([]byte("PK\x05\x06" + strings.Repeat("\x00", 18)))
+
+	limits := archiveLimits{maxEntries: 16, maxEntrySize: 1024, maxTotalSize: 2048, maxRatio: 10}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entries, err := readZipArchive(bytes.NewReader(data), int64(len(data)), limits)
+		checkFuzzedArchive(t, entries, err, limits)
+	})
+}
+
+func FuzzReadTarArchive(f *testing.F) {
+	home := testutil.SetupHome(f)
+	writeTestFile(f, filepath.Join(home, ".bashrc"), "echo ok\n")
+	writeTestFile(f, filepath.Join(home, ".ssh", "config"), "Host example\n")
+	if err := os.Symlink(".bashrc", filepath.Join(home, ".profile")); err != nil {
+		f.Fatal(err)
+	}
+	var export bytes.Buffer
+	if err := NewExportService(NewConfigService(), NewSigningService()).Export(&export, ExportOptions{Format: ExportFormatTarGz}); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(export.Bytes())
+
+	// 手工构造的不安全条目
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, header := range []*tar.Header{
+		{Name: "../escape", Mode: 0644, Size: 1},
+		{Name: "/abs", Mode: 0644, Size: 1},
+		{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc/shadow"},
+		{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755},
+	} {
+		if err := tarWriter.WriteHeader(header); err != nil {
+			f.Fatal(err)
+		}
+		if header.Size > 0 {
+			tarWriter.Write([]byte("x"))
+		}
+	}
+	tarWriter.Close()
+	gzipWriter.Close()
+	f.Add(buf.Bytes())
+	f.Add([]byte("\x1f\x8b"))
+
+	limits := archiveLimits{maxEntries: 16, maxEntrySize: 1024, maxTotalSize: 2048, maxRatio: 10}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entries, err := readTarGzArchive(bytes.NewReader(data), int64(len(data)), limits)
+		checkFuzzedArchive(t, entries, err, limits)
+		for _, entry := range entries {
+			if entry.rejected == nil && strings.ContainsRune(entry.meta.linkTarget, 0) {
+				t.Fatalf("接受了包含 NUL 的链接目标 %q", entry.meta.linkTarget)
+			}
+		}
+	})
+}
+
+// checkFuzzedArchive 检查读取任意输入的结果：错误必须带有错误码，接受的条目满足名称和大小限制
+func checkFuzzedArchive(t *testing.T, entries []archiveEntry, err error, limits archiveLimits) {
+	t.Helper()
+	if err != nil {
+		var archiveErr *ArchiveError
+		if !errors.As(err, &archiveErr) || archiveErr.Detail.Code == "" {
+			t.Fatalf("错误应带有错误码: %v", err)
+		}
+		return
+	}
+
+	var total int64
+	for _, entry := range entries {
+		if entry.rejected != nil {
+			if entry.rejected.Code == "" || entry.content != nil {
+				t.Fatalf("被拒绝的条目信息错误: %+v", entry)
+			}
+			continue
+		}
+		if code, _ := checkEntryName(entry.name); code != "" {
+			t.Fatalf("接受了不安全的条目名称 %q", entry.name)
+		}
+		if int64(len(entry.content)) > limits.maxEntrySize {
+			t.Fatalf("条目 %q 超过大小限制: %d", entry.name, len(entry.content))
+		}
+		total += int64(len(entry.content))
+	}
+	if total > limits.maxTotalSize {
+		t.Fatalf("解压总大小超过限制: %d", total)
+	}
+}
@@ -0,0 +1,342 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// 备份原因
+const (
+	BackupReasonManual     = "manual"      // 用户手动创建
+	BackupReasonPreRestore = "pre-restore" // 恢复备份前自动创建
+	BackupReasonImport     = "import"      // 导入覆盖文件前自动创建
+)
+
+// defaultBackupPolicy 默认保留策略：保留最新 10 个，并保留最近 7 天每天最新的一个
+var defaultBackupPolicy = models.BackupPolicy{KeepLast: 10, KeepDailyDays: 7}
+
+// backupCatalog 备份目录索引文件的结构
+type backupCatalog struct {
+	Backups []models.Backup `json:"backups"`
+}
+
+// BackupService 在专用目录中保存配置文件备份，并按保留策略自动清理
+// 备份内容保存为 <dir>/<文件ID>/<备份ID>，元数据保存在 <dir>/catalog.json
+type BackupService struct {
+	mu         sync.Mutex
+	dir        string
+	policyPath string
+	loaded     bool
+	catalog    backupCatalog
+}
+
+// NewBackupService 创建新的备份服务实例
+func NewBackupService(dir, policyPath string) *BackupService {
+	return &BackupService{dir: dir, policyPath: policyPath}
+}
+
+// Create 为文件创建备份，随后按保留策略清理该文件的旧备份
+func (b *BackupService) Create(fileID, filePath, realPath, reason string) (*models.Backup, error) {
+	return b.create(fileID, filePath, realPath, reason, "")
+}
+
+// CreatePreRestore 在从备份 restoringID 恢复之前备份当前文件，清理旧备份时保留正在恢复的备份
+func (b *BackupService) CreatePreRestore(fileID, filePath, realPath, restoringID string) (*models.Backup, error) {
+	return b.create(fileID, filePath, realPath, BackupReasonPreRestore, restoringID)
+}
+
+// create 创建备份并执行保留策略，pinned 非空时该备份不会被清理
+func (b *BackupService) create(fileID, filePath, realPath, reason, pinned string) (*models.Backup, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(realPath)
+	if os.IsNotExist(err) {
+		return nil, notFoundf("文件不存在: %s", realPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取文件信息 %s: %w", realPath, err)
+	}
+	content, err := os.ReadFile(realPath)
+	if err != nil {
+		return nil, fmt.Errorf("无法读取原文件 %s: %w", realPath, err)
+	}
+
+	now := time.Now()
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	if reason == "" {
+		reason = BackupReasonManual
+	}
+
+	backup := models.Backup{
+		ID:        fmt.Sprintf("%s-%s-%s", fileID, now.Format("20060102-150405.000000"), hash[:8]),
+		FileID:    fileID,
+		FilePath:  filePath,
+		Hash:      hash,
+		Size:      int64(len(content)),
+		Mode:      uint32(info.Mode().Perm()),
+		Reason:    reason,
+		CreatedAt: now,
+	}
+
+	backupPath := b.contentPath(backup)
+	if err := os.MkdirAll(filepath.Dir(backupPath), 0700); err != nil {
+		return nil, fmt.Errorf("无法创建备份目录: %w", err)
+	}
+	if err := os.WriteFile(backupPath, content, 0600); err != nil {
+		return nil, fmt.Errorf("无法创建备份文件 %s: %w", backupPath, err)
+	}
+
+	b.catalog.Backups = append(b.catalog.Backups, backup)
+	if err := b.enforce(fileID, now, pinned); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// List 返回备份列表（最新的在前），fileID 为空时返回所有文件的备份
+func (b *BackupService) List(fileID string) ([]models.Backup, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+
+	backups := []models.Backup{}
+	for _, backup := range b.catalog.Backups {
+		if fileID == "" || backup.FileID == fileID {
+			backups = append(backups, backup)
+		}
+	}
+	sortBackupsNewestFirst(backups)
+	return backups, nil
+}
+
+// HasBackup 判断文件是否存在至少一个备份
+func (b *BackupService) HasBackup(fileID string) bool {
+	backups, err := b.List(fileID)
+	return err == nil && len(backups) > 0
+}
+
+// Get 返回备份的元数据和内容
+func (b *BackupService) Get(backupID string) (*models.Backup, []byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.load(); err != nil {
+		return nil, nil, err
+	}
+
+	i := b.indexOf(backupID)
+	if i < 0 {
+		return nil, nil, notFoundf("备份未找到: %s", backupID)
+	}
+
+	backup := b.catalog.Backups[i]
+	content, err := os.ReadFile(b.contentPath(backup))
+	if err != nil {
+		return nil, nil, fmt.Errorf("无法读取备份 %s: %w", backupID, err)
+	}
+	return &backup, content, nil
+}
+
+// Delete 删除备份
+func (b *BackupService) Delete(backupID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.load(); err != nil {
+		return err
+	}
+
+	i := b.indexOf(backupID)
+	if i < 0 {
+		return notFoundf("备份未找到: %s", backupID)
+	}
+
+	backup := b.catalog.Backups[i]
+	if err := os.Remove(b.contentPath(backup)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("无法删除备份 %s: %w", backupID, err)
+	}
+
+	b.catalog.Backups = append(b.catalog.Backups[:i:i], b.catalog.Backups[i+1:]...)
+	return b.save()
+}
+
+// Policy 返回当前的保留策略
+func (b *BackupService) Policy() (models.BackupPolicy, error) {
+	content, err := os.ReadFile(b.policyPath)
+	if os.IsNotExist(err) {
+		return defaultBackupPolicy, nil
+	}
+	if err != nil {
+		return models.BackupPolicy{}, fmt.Errorf("无法读取备份保留策略: %w", err)
+	}
+
+	var policy models.BackupPolicy
+	if err := json.Unmarshal(content, &policy); err != nil {
+		return models.BackupPolicy{}, fmt.Errorf("备份保留策略格式错误: %w", err)
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存新的保留策略并立即对所有文件执行清理
+func (b *BackupService) SetPolicy(policy models.BackupPolicy) (*models.BackupPolicy, error) {
+	if policy.KeepLast < 0 || policy.KeepDailyDays < 0 {
+		return nil, invalidf("保留数量和天数不能为负数")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	if err := writeJSONFile(b.policyPath, policy); err != nil {
+		return nil, err
+	}
+
+	fileIDs := make(map[string]bool)
+	for _, backup := range b.catalog.Backups {
+		fileIDs[backup.FileID] = true
+	}
+	now := time.Now()
+	for fileID := range fileIDs {
+		if err := b.enforce(fileID, now, ""); err != nil {
+			return nil, err
+		}
+	}
+	return &policy, nil
+}
+
+// enforce 按保留策略清理指定文件的备份并保存索引，pinned 指定的备份始终保留，调用方需持有锁
+func (b *BackupService) enforce(fileID string, now time.Time, pinned string) error {
+	policy, err := b.Policy()
+	if err != nil {
+		return err
+	}
+
+	var mine []models.Backup
+	var others []models.Backup
+	for _, backup := range b.catalog.Backups {
+		if backup.FileID == fileID {
+			mine = append(mine, backup)
+		} else {
+			others = append(others, backup)
+		}
+	}
+	sortBackupsNewestFirst(mine)
+
+	keep := retainedBackups(mine, policy, now)
+	kept := others
+	for _, backup := range mine {
+		if keep[backup.ID] || backup.ID == pinned {
+			kept = append(kept, backup)
+			continue
+		}
+		if err := os.Remove(b.contentPath(backup)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("无法清理过期备份 %s: %w", backup.ID, err)
+		}
+	}
+
+	b.catalog.Backups = kept
+	return b.save()
+}
+
+// retainedBackups 计算需要保留的备份ID，backups 需按时间从新到旧排列
+func retainedBackups(backups []models.Backup, policy models.BackupPolicy, now time.Time) map[string]bool {
+	keep := make(map[string]bool, len(backups))
+	if policy.KeepLast == 0 && policy.KeepDailyDays == 0 {
+		for _, backup := range backups {
+			keep[backup.ID] = true
+		}
+		return keep
+	}
+
+	for i, backup := range backups {
+		if i < policy.KeepLast {
+			keep[backup.ID] = true
+		}
+	}
+
+	if policy.KeepDailyDays > 0 {
+		cutoff := now.AddDate(0, 0, -policy.KeepDailyDays)
+		days := make(map[string]bool)
+		for _, backup := range backups {
+			if backup.CreatedAt.Before(cutoff) {
+				continue
+			}
+			day := backup.CreatedAt.Local().Format("2006-01-02")
+			if !days[day] {
+				days[day] = true
+				keep[backup.ID] = true
+			}
+		}
+	}
+	return keep
+}
+
+// sortBackupsNewestFirst 按创建时间从新到旧排序
+func sortBackupsNewestFirst(backups []models.Backup) {
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+}
+
+// indexOf 返回备份在索引中的下标，不存在时返回 -1
+func (b *BackupService) indexOf(backupID string) int {
+	for i, backup := range b.catalog.Backups {
+		if backup.ID == backupID {
+			return i
+		}
+	}
+	return -1
+}
+
+// contentPath 返回备份内容的保存路径
+func (b *BackupService) contentPath(backup models.Backup) string {
+	return filepath.Join(b.dir, backup.FileID, backup.ID)
+}
+
+// load 首次访问时读取备份索引
+func (b *BackupService) load() error {
+	if b.loaded {
+		return nil
+	}
+
+	path := filepath.Join(b.dir, "catalog.json")
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		b.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("无法读取备份索引 %s: %w", path, err)
+	}
+	if err := json.Unmarshal(content, &b.catalog); err != nil {
+		return fmt.Errorf("备份索引格式错误 %s: %w", path, err)
+	}
+
+	b.loaded = true
+	return nil
+}
+
+// save 写回备份索引
+func (b *BackupService) save() error {
+	return writeJSONFile(filepath.Join(b.dir, "catalog.json"), b.catalog)
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestRetainedBackups(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.Local)
+
+	// 每 6 小时一个备份，共 5 天
+	var backups []models.Backup
+	for i := 0; i < 20; i++ {
+		backups = append(backups, models.Backup{
+			ID:        fmt.Sprintf("b%02d", i),
+			CreatedAt: now.Add(-time.Duration(i) * 6 * time.Hour),
+		})
+	}
+
+	keep := retainedBackups(backups, models.BackupPolicy{KeepLast: 3, KeepDailyDays: 2}, now)
+
+	// 最新 3 个 + 最近两天内每天最新的一个（5/10 即 b00，5/9 为 b03，5/8 为 b07）
+	want := []string{"b00", "b01", "b02", "b03", "b07"}
+	if len(keep) != len(want) {
+		t.Errorf("保留数量错误: got %v want %v", keep, want)
+	}
+	for _, id := range want {
+		if !keep[id] {
+			t.Errorf("应保留备份 %s", id)
+		}
+	}
+
+	all := retainedBackups(backups, models.BackupPolicy{}, now)
+	if len(all) != len(backups) {
+		t.Errorf("策略为空时应保留全部备份: got %d", len(all))
+	}
+}
+
+func TestBackupCreateAndRestore(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	vimrc := filepath.Join(home, ".vimrc")
+	writeTestFile(t, vimrc, "set number\n")
+
+	service := NewConfigService()
+	if _, err := service.SetBackupPolicy(models.BackupPolicy{KeepLast: 2}); err != nil {
+		t.Fatalf("设置保留策略失败: %v", err)
+	}
+
+	created, err := service.BackupFile("vimrc", "")
+	if err != nil {
+		t.Fatalf("创建备份失败: %v", err)
+	}
+	if created.Backup.Reason != BackupReasonManual || created.Backup.Size != int64(len("set number\n")) {
+		t.Errorf("备份元数据错误: %+v", created.Backup)
+	}
+
+	files, _ := service.GetFiles()
+	for _, f := range files {
+		if f.ID == "vimrc" && !f.BackupExists {
+			t.Errorf("创建备份后 BackupExists 应为 true")
+		}
+	}
+
+	writeTestFile(t, vimrc, "set nonumber\n")
+	if _, err := service.RestoreBackup(created.Backup.ID, ""); err != nil {
+		t.Fatalf("恢复备份失败: %v", err)
+	}
+	content, _ := os.ReadFile(vimrc)
+	if string(content) != "set number\n" {
+		t.Errorf("恢复后的内容错误: %q", content)
+	}
+
+	// 恢复较旧的备份时，恢复前的自动备份不会把它清理掉
+	newer, err := service.BackupFile("vimrc", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups, _ := service.ListBackups("vimrc")
+	oldest := backups[len(backups)-1]
+	writeTestFile(t, vimrc, "set ruler\n")
+	restored, err := service.RestoreBackup(oldest.ID, "")
+	if err != nil {
+		t.Fatalf("恢复备份失败: %v", err)
+	}
+	if _, _, err := service.backups.Get(restored.ID); err != nil {
+		t.Errorf("正在恢复的备份不应被清理: %v", err)
+	}
+	if _, _, err := service.backups.Get(newer.Backup.ID); err != nil {
+		t.Errorf("较新的备份不应被清理: %v", err)
+	}
+
+	// 恢复前会自动备份，连同更多手动备份一起受 KeepLast 限制
+	for i := 0; i < 3; i++ {
+		if _, err := service.BackupFile("vimrc", "manual"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups, _ = service.ListBackups("vimrc")
+	if len(backups) != 2 {
+		t.Errorf("保留策略未生效: got %d backups", len(backups))
+	}
+
+	if err := service.DeleteBackup(backups[0].ID); err != nil {
+		t.Fatalf("删除备份失败: %v", err)
+	}
+	if _, _, err := service.backups.Get(backups[0].ID); err == nil {
+		t.Errorf("删除后不应还能读取备份")
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// blockMarkerText 受管块标记行中注释符之后的固定文本，完整的标记行形如
+// # BEGIN LINUX-CONFIG-MANAGER BLOCK conda
+// # END LINUX-CONFIG-MANAGER BLOCK conda
+const blockMarkerText = "LINUX-CONFIG-MANAGER BLOCK"
+
+// blockMarkerPattern 匹配受管块标记行，接受常见配置格式的注释符
+var blockMarkerPattern = regexp.MustCompile(`^\s*(?:#|"|;|//|--)\s*(BEGIN|END) ` + blockMarkerText + ` (.*?)\s*$`)
+
+// blockCommentPrefix 返回写入标记行时使用的注释符，vim 配置使用双引号，其他格式使用 #
+func blockCommentPrefix(file models.ConfigFile) string {
+	base := path.Base(file.Path)
+	if strings.HasSuffix(base, "vimrc") || strings.HasSuffix(base, ".vim") {
+		return `"`
+	}
+	return "#"
+}
+
+// parseBlocks 找出内容中成对的受管块，并以警告诊断报告不成对、名称不一致或重复的标记
+func parseBlocks(content string) ([]models.ManagedBlock, []models.Diagnostic) {
+	lines := contentLines(content)
+	blocks := []models.ManagedBlock{}
+	problems := []models.Diagnostic{}
+	problem := func(line int, format string, args ...interface{}) {
+		problems = append(problems, models.Diagnostic{
+			Line:     line,
+			Column:   1,
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf(format, args...),
+			Source:   "managed-block",
+		})
+	}
+
+	seen := make(map[string]bool)
+	open, openName := -1, ""
+	for i, line := range lines {
+		match := blockMarkerPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		kind, name := match[1], match[2]
+		switch {
+		case kind == "BEGIN":
+			if open >= 0 {
+				problem(open+1, "受管块 %s 没有结束标记（第 %d 行开始了新的块）", openName, i+1)
+			}
+			open, openName = i, name
+		case open < 0:
+			problem(i+1, "受管块 %s 的结束标记没有对应的开始标记", name)
+		case name != openName:
+			problem(i+1, "结束标记 %s 与第 %d 行的开始标记 %s 不一致", name, open+1, openName)
+			open = -1
+		default:
+			if seen[name] {
+				problem(open+1, "受管块 %s 重复出现", name)
+			} else {
+				seen[name] = true
+				body := ""
+				if i > open+1 {
+					body = strings.Join(lines[open+1:i], "\n") + "\n"
+				}
+				blocks = append(blocks, models.ManagedBlock{Name: name, StartLine: open + 1, EndLine: i + 1, Content: body})
+			}
+			open = -1
+		}
+	}
+	if open >= 0 {
+		problem(open+1, "受管块 %s 没有结束标记", openName)
+	}
+	return blocks, problems
+}
+
+// setBlock 用 body 替换名为 name 的受管块的内容，块不存在时按 req 指定的位置插入新块
+func setBlock(content, prefix, name string, req models.SetBlockRequest) (string, error) {
+	blocks, err := editableBlocks(content, name)
+	if err != nil {
+		return "", err
+	}
+	bodyLines := contentLines(req.Content)
+	for _, line := range bodyLines {
+		if blockMarkerPattern.MatchString(line) {
+			return "", invalidf("受管块的内容不能包含标记行: %s", line)
+		}
+	}
+
+	lines := contentLines(content)
+	for _, block := range blocks {
+		if block.Name == name {
+			updated := append(append(append([]string{}, lines[:block.StartLine]...), bodyLines...), lines[block.EndLine-1:]...)
+			return joinLines(updated), nil
+		}
+	}
+
+	at, err := blockInsertPosition(lines, req)
+	if err != nil {
+		return "", err
+	}
+	blockLines := append(append([]string{prefix + " BEGIN " + blockMarkerText + " " + name}, bodyLines...), prefix+" END "+blockMarkerText+" "+name)
+	updated := append(append(append([]string{}, lines[:at]...), blockLines...), lines[at:]...)
+	return joinLines(updated), nil
+}
+
+// removeBlock 删除名为 name 的受管块及其标记行
+func removeBlock(content, name string) (string, error) {
+	blocks, err := editableBlocks(content, name)
+	if err != nil {
+		return "", err
+	}
+	lines := contentLines(content)
+	for _, block := range blocks {
+		if block.Name == name {
+			return joinLines(append(append([]string{}, lines[:block.StartLine-1]...), lines[block.EndLine:]...)), nil
+		}
+	}
+	return "", notFoundf("受管块不存在: %s", name)
+}
+
+// editableBlocks 校验块名称并解析受管块，标记不完整时拒绝修改，避免覆盖用户手动编辑的内容
+func editableBlocks(content, name string) ([]models.ManagedBlock, error) {
+	if !fileIDPattern.MatchString(name) {
+		return nil, invalidf("无效的受管块名称: %q（只允许字母、数字和 . _ -）", name)
+	}
+	blocks, problems := parseBlocks(content)
+	if len(problems) > 0 {
+		return nil, conflictf("受管块标记不完整，请先手动修复: 第 %d 行 %s", problems[0].Line, problems[0].Message)
+	}
+	return blocks, nil
+}
+
+// blockInsertPosition 返回新块插入的行下标：insertBefore 插入到第一个匹配行之前，
+// insertAfter 插入到最后一个匹配行之后，没有匹配行或都未指定时插入到末尾
+func blockInsertPosition(lines []string, req models.SetBlockRequest) (int, error) {
+	if req.InsertBefore != "" && req.InsertAfter != "" {
+		return 0, invalidf("insertBefore 和 insertAfter 不能同时指定")
+	}
+	switch {
+	case req.InsertBefore == "BOF":
+		return 0, nil
+	case req.InsertBefore != "":
+		pattern, err := regexp.Compile(req.InsertBefore)
+		if err != nil {
+			return 0, invalidf("无效的 insertBefore 正则表达式: %v", err)
+		}
+		for i, line := range lines {
+			if pattern.MatchString(line) {
+				return i, nil
+			}
+		}
+	case req.InsertAfter != "" && req.InsertAfter != "EOF":
+		pattern, err := regexp.Compile(req.InsertAfter)
+		if err != nil {
+			return 0, invalidf("无效的 insertAfter 正则表达式: %v", err)
+		}
+		for i := len(lines) - 1; i >= 0; i-- {
+			if pattern.MatchString(lines[i]) {
+				return i + 1, nil
+			}
+		}
+	}
+	return len(lines), nil
+}
+
+// contentLines 按行拆分内容，末尾的换行不产生空行
+func contentLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// joinLines 将行重新拼接为以换行结尾的内容
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
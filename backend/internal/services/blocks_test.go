@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestParseBlocks(t *testing.T) {
+	content := strings.Join([]string{
+		"export PATH=$HOME/bin:$PATH",
+		"# BEGIN LINUX-CONFIG-MANAGER BLOCK aliases",
+		"alias ll='ls -l'",
+		"# END LINUX-CONFIG-MANAGER BLOCK aliases",
+		"# BEGIN LINUX-CONFIG-MANAGER BLOCK prompt",
+		"PS1='$ '",
+		"# END LINUX-CONFIG-MANAGER BLOCK other",
+		"# END LINUX-CONFIG-MANAGER BLOCK stray",
+		"# BEGIN LINUX-CONFIG-MANAGER BLOCK open",
+	}, "\n") + "\n"
+
+	blocks, problems := parseBlocks(content)
+	if len(blocks) != 1 || blocks[0].Name != "aliases" || blocks[0].StartLine != 2 || blocks[0].EndLine != 4 || blocks[0].Content != "alias ll='ls -l'\n" {
+		t.Errorf("受管块解析错误: %+v", blocks)
+	}
+	var lines []int
+	for _, problem := range problems {
+		if problem.Severity != SeverityWarning {
+			t.Errorf("标记问题应为警告: %+v", problem)
+		}
+		lines = append(lines, problem.Line)
+	}
+	if len(lines) != 3 || lines[0] != 7 || lines[1] != 8 || lines[2] != 9 {
+		t.Errorf("标记问题错误: %+v", problems)
+	}
+}
+
+func TestManagedBlocks(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	bashrc := filepath.Join(home, ".bashrc")
+	writeTestFile(t, bashrc, "# ~/.bashrc\nexport EDITOR=vim\n")
+	configService := NewConfigService()
+
+	// 新块按 insertAfter 插入，已有的块原地替换
+	if _, err := configService.SetBlock("bashrc", "aliases", models.SetBlockRequest{Content: "alias ll='ls -l'", InsertAfter: "^# ~/"}, UpdateOptions{}); err != nil {
+		t.Fatalf("插入受管块失败: %v", err)
+	}
+	if _, err := configService.SetBlock("bashrc", "path", models.SetBlockRequest{Content: "PATH=$HOME/bin:$PATH\n"}, UpdateOptions{}); err != nil {
+		t.Fatalf("插入受管块失败: %v", err)
+	}
+	if _, err := configService.SetBlock("bashrc", "aliases", models.SetBlockRequest{Content: "alias la='ls -a'\n", InsertBefore: "BOF"}, UpdateOptions{}); err != nil {
+		t.Fatalf("更新受管块失败: %v", err)
+	}
+	want := "# ~/.bashrc\n" +
+		"# BEGIN LINUX-CONFIG-MANAGER BLOCK aliases\nalias la='ls -a'\n# END LINUX-CONFIG-MANAGER BLOCK aliases\n" +
+		"export EDITOR=vim\n" +
+		"# BEGIN LINUX-CONFIG-MANAGER BLOCK path\nPATH=$HOME/bin:$PATH\n# END LINUX-CONFIG-MANAGER BLOCK path\n"
+	if got, _ := os.ReadFile(bashrc); string(got) != want {
+		t.Errorf("受管块写入结果错误:\n%s\nwant:\n%s", got, want)
+	}
+
+	list, err := configService.ListBlocks("bashrc")
+	if err != nil {
+		t.Fatalf("列出受管块失败: %v", err)
+	}
+	if len(list.Blocks) != 2 || list.Blocks[1].Name != "path" || len(list.Problems) != 0 {
+		t.Errorf("受管块列表错误: %+v", list)
+	}
+
+	if _, err := configService.RemoveBlock("bashrc", "path", UpdateOptions{}); err != nil {
+		t.Fatalf("删除受管块失败: %v", err)
+	}
+	if _, err := configService.RemoveBlock("bashrc", "path", UpdateOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除不存在的块应返回 ErrNotFound, got %v", err)
+	}
+	if _, err := configService.SetBlock("bashrc", "bad name", models.SetBlockRequest{}, UpdateOptions{}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("无效的块名称应返回 ErrInvalidInput, got %v", err)
+	}
+
+	// 手动编辑删掉结束标记时保存成功但给出警告，之后拒绝修改受管块
+	file, err := configService.GetFileByID("bashrc")
+	if err != nil {
+		t.Fatalf("读取文件失败: %v", err)
+	}
+	broken := strings.Replace(file.Content, "# END LINUX-CONFIG-MANAGER BLOCK aliases\n", "", 1)
+	result, err := configService.UpdateFile("bashrc", broken, UpdateOptions{IfMatch: file.ETag})
+	if err != nil {
+		t.Fatalf("保存文件失败: %v", err)
+	}
+	warned := false
+	for _, d := range result.Diagnostics {
+		warned = warned || (d.Source == "managed-block" && d.Line == 2 && d.Severity == SeverityWarning)
+	}
+	if !warned {
+		t.Errorf("破坏标记后应给出警告: %+v", result.Diagnostics)
+	}
+	if _, err := configService.SetBlock("bashrc", "aliases", models.SetBlockRequest{Content: "x\n"}, UpdateOptions{}); !errors.Is(err, ErrConflict) {
+		t.Errorf("标记不完整时应返回 ErrConflict, got %v", err)
+	}
+
+	// 文件未通过格式校验时，删除受管块与插入一样需要 Force
+	if _, err := exec.LookPath("bash"); err == nil {
+		block := "# BEGIN LINUX-CONFIG-MANAGER BLOCK conda\nconda init\n# END LINUX-CONFIG-MANAGER BLOCK conda\n"
+		writeTestFile(t, bashrc, block+"if then\n")
+		var validationErr *ValidationError
+		if _, err := configService.RemoveBlock("bashrc", "conda", UpdateOptions{}); !errors.As(err, &validationErr) {
+			t.Errorf("未通过格式校验时应拒绝删除, got %v", err)
+		}
+		if _, err := configService.RemoveBlock("bashrc", "conda", UpdateOptions{Force: true}); err != nil {
+			t.Fatalf("Force 时应删除受管块: %v", err)
+		}
+		if got, _ := os.ReadFile(bashrc); string(got) != "if then\n" {
+			t.Errorf("删除受管块后的内容错误: %q", got)
+		}
+	}
+
+	// 模板文件的标记检查针对模板源文件，与 ListBlocks 一致
+	writeTestFile(t, filepath.Join(home, ".blockrc"), "")
+	if _, err := configService.SetTemplateVars(map[string]string{"end": "# END LINUX-CONFIG-MANAGER BLOCK a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := configService.RegisterFile(models.RegisterFileRequest{ID: "blockrc", Path: "~/.blockrc", Category: "shell", Template: true}); err != nil {
+		t.Fatalf("登记模板文件失败: %v", err)
+	}
+	result, err = configService.UpdateFile("blockrc", "# BEGIN LINUX-CONFIG-MANAGER BLOCK a\nx\n{{ .Vars.end }}\n", UpdateOptions{})
+	if err != nil {
+		t.Fatalf("保存模板文件失败: %v", err)
+	}
+	list, _ = configService.ListBlocks("blockrc")
+	if len(result.Diagnostics) != 1 || result.Diagnostics[0].Source != "managed-block" || len(list.Problems) != 1 {
+		t.Errorf("模板源文件的标记不完整时应给出警告: %+v, %+v", result.Diagnostics, list)
+	}
+}
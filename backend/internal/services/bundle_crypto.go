@@ -0,0 +1,273 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// 加密导出包的格式：bundleMagic、4 字节大端序的头部长度、JSON 头部（models.EncryptionInfo），
+// 之后是 AES-256-GCM 加密的压缩包，头部作为每一段的附加认证数据，篡改头部同样会导致解密失败
+// 版本 2 将压缩包按 chunkSize 分段加密，随机数由头部记录的 7 字节前缀、4 字节大端序段序号和
+// 1 字节末段标记组成，可以边读边解密，删除、重排或截断分段都会导致解密失败；版本 1 整体加密，只支持读取
+const (
+	bundleMagic           = "LCM-ENC\n"
+	bundleVersion         = 2
+	bundleCipher          = "AES-256-GCM"
+	bundleKDF             = "PBKDF2-HMAC-SHA256"
+	bundleIterations      = 600000
+	bundleSaltSize        = 16
+	bundleKeySize         = 32
+	bundleChunkSize       = 64 << 10
+	bundleNoncePrefixSize = 7
+)
+
+// maxBundleIterations 解密时接受的最大迭代次数，防止伪造的头部耗尽 CPU
+const maxBundleIterations = 10000000
+
+// maxBundleHeaderSize 加密包头部的最大字节数
+const maxBundleHeaderSize = 4 << 10
+
+// maxBundleChunkSize 解密时接受的最大分段长度
+const maxBundleChunkSize = 1 << 20
+
+// isEncryptedBundle 判断数据是否以加密导出包的标识开头
+func isEncryptedBundle(r io.ReaderAt) bool {
+	magic := make([]byte, len(bundleMagic))
+	n, _ := r.ReadAt(magic, 0)
+	return n == len(bundleMagic) && string(magic) == bundleMagic
+}
+
+// newEncryptionInfo 生成新的随机盐和默认的密钥派生参数
+func newEncryptionInfo(format string) (*models.EncryptionInfo, error) {
+	salt := make([]byte, bundleSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("无法生成随机盐: %w", err)
+	}
+	return &models.EncryptionInfo{
+		Version:    bundleVersion,
+		Cipher:     bundleCipher,
+		KDF:        bundleKDF,
+		Iterations: bundleIterations,
+		Salt:       base64.StdEncoding.EncodeToString(salt),
+		ChunkSize:  bundleChunkSize,
+		Format:     format,
+	}, nil
+}
+
+// sealBundle 用口令派生的密钥分段加密压缩包，按加密包格式写入 w
+func sealBundle(w io.Writer, plaintext []byte, passphrase string, info models.EncryptionInfo) error {
+	salt, err := base64.StdEncoding.DecodeString(info.Salt)
+	if err != nil {
+		return fmt.Errorf("无效的盐: %w", err)
+	}
+	aead, err := newBundleAEAD(passphrase, salt, info.Iterations)
+	if err != nil {
+		return err
+	}
+
+	prefix := make([]byte, bundleNoncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return fmt.Errorf("无法生成随机数: %w", err)
+	}
+	info.Nonce = base64.StdEncoding.EncodeToString(prefix)
+	header, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	var head bytes.Buffer
+	head.WriteString(bundleMagic)
+	binary.Write(&head, binary.BigEndian, uint32(len(header)))
+	head.Write(header)
+	if _, err := w.Write(head.Bytes()); err != nil {
+		return err
+	}
+
+	// 空的压缩包也写入一个末段，解密时据此确认内容完整
+	for index, offset := uint32(0), 0; ; index++ {
+		end := offset + info.ChunkSize
+		if end > len(plaintext) {
+			end = len(plaintext)
+		}
+		last := end == len(plaintext)
+		if _, err := w.Write(aead.Seal(nil, chunkNonce(prefix, index, last), plaintext[offset:end], header)); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		offset = end
+	}
+}
+
+// openBundle 解析加密包头部并用口令边读边解密，把解密后的压缩包写入 w，返回头部信息
+// 解密后的内容超过 limit 字节时拒绝整个导出包；每一段都通过认证后才写入 w
+func openBundle(r io.Reader, w io.Writer, passphrase string, limit int64) (*models.EncryptionInfo, error) {
+	prefix := make([]byte, len(bundleMagic)+4)
+	if _, err := io.ReadFull(r, prefix); err != nil || string(prefix[:len(bundleMagic)]) != bundleMagic {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "加密包头部不完整")
+	}
+	headerSize := binary.BigEndian.Uint32(prefix[len(bundleMagic):])
+	if headerSize > maxBundleHeaderSize {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "加密包头部长度无效")
+	}
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "加密包头部长度无效")
+	}
+
+	var info models.EncryptionInfo
+	if err := json.Unmarshal(header, &info); err != nil {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "无法解析加密包头部: %v", err)
+	}
+	if (info.Version != 1 && info.Version != bundleVersion) || info.Cipher != bundleCipher || info.KDF != bundleKDF {
+		return nil, archiveErrorf(models.ArchiveErrVersion, "不支持的加密包: 版本 %d，%s，%s", info.Version, info.Cipher, info.KDF)
+	}
+	if info.Iterations <= 0 || info.Iterations > maxBundleIterations {
+		return nil, archiveErrorf(models.ArchiveErrVersion, "不支持的迭代次数: %d", info.Iterations)
+	}
+	if info.Version == bundleVersion && (info.ChunkSize <= 0 || info.ChunkSize > maxBundleChunkSize) {
+		return nil, archiveErrorf(models.ArchiveErrVersion, "不支持的分段长度: %d", info.ChunkSize)
+	}
+	if passphrase == "" {
+		return nil, archiveErrorf(models.ArchiveErrPassphrase, "导出包已加密，请提供口令")
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(info.Salt)
+	if err != nil {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "加密包头部的盐无效")
+	}
+	nonce, err := base64.StdEncoding.DecodeString(info.Nonce)
+	if err != nil {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "加密包头部的随机数无效")
+	}
+	aead, err := newBundleAEAD(passphrase, salt, info.Iterations)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Version == 1 {
+		if len(nonce) != aead.NonceSize() {
+			return nil, archiveErrorf(models.ArchiveErrInvalid, "加密包头部的随机数长度无效")
+		}
+		return &info, openWholeBundle(r, w, aead, nonce, header, limit)
+	}
+	if len(nonce) != bundleNoncePrefixSize {
+		return nil, archiveErrorf(models.ArchiveErrInvalid, "加密包头部的随机数长度无效")
+	}
+	return &info, openChunkedBundle(r, w, aead, nonce, header, info.ChunkSize, limit)
+}
+
+// openChunkedBundle 逐段解密版本 2 的加密包
+func openChunkedBundle(r io.Reader, w io.Writer, aead cipher.AEAD, prefix, header []byte, chunkSize int, limit int64) error {
+	reader := bufio.NewReader(r)
+	chunk := make([]byte, chunkSize+aead.Overhead())
+	var total int64
+	for index := uint32(0); ; index++ {
+		n, err := io.ReadFull(reader, chunk)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return archiveErrorf(models.ArchiveErrDecrypt, "解密失败：导出包不完整")
+		}
+		// 不足一整段，或整段之后没有更多数据时是末段
+		last := err == io.ErrUnexpectedEOF
+		if !last {
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				last = true
+			}
+		}
+
+		plaintext, err := aead.Open(chunk[:0], chunkNonce(prefix, index, last), chunk[:n], header)
+		if err != nil {
+			return archiveErrorf(models.ArchiveErrDecrypt, "解密失败：口令错误或导出包已被篡改")
+		}
+		if total += int64(len(plaintext)); total > limit {
+			return archiveErrorf(models.ArchiveErrTotalTooLarge, "解密后的压缩包超过大小上限 %d 字节", limit)
+		}
+		if _, err := w.Write(plaintext); err != nil {
+			return fmt.Errorf("无法写入解密后的压缩包: %w", err)
+		}
+		if last {
+			return nil
+		}
+	}
+}
+
+// openWholeBundle 解密版本 1 的加密包，密文整体读入内存，因此先按 limit 限制读取的字节数
+func openWholeBundle(r io.Reader, w io.Writer, aead cipher.AEAD, nonce, header []byte, limit int64) error {
+	ciphertext, err := io.ReadAll(io.LimitReader(r, limit+int64(aead.Overhead())+1))
+	if err != nil {
+		return fmt.Errorf("无法读取导出包: %w", err)
+	}
+	if int64(len(ciphertext)) > limit+int64(aead.Overhead()) {
+		return archiveErrorf(models.ArchiveErrTotalTooLarge, "解密后的压缩包超过大小上限 %d 字节", limit)
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return archiveErrorf(models.ArchiveErrDecrypt, "解密失败：口令错误或导出包已被篡改")
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return fmt.Errorf("无法写入解密后的压缩包: %w", err)
+	}
+	return nil
+}
+
+// chunkNonce 由随机前缀、段序号和末段标记组成分段的随机数
+func chunkNonce(prefix []byte, index uint32, last bool) []byte {
+	nonce := make([]byte, 0, len(prefix)+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, index)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// newBundleAEAD 由口令派生 AES-256 密钥并创建 GCM 实例
+func newBundleAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key := pbkdf2Key(sha256.New, []byte(passphrase), salt, iterations, bundleKeySize)
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2Key 按 RFC 8018 实现 PBKDF2
+func pbkdf2Key(newHash func() hash.Hash, password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(newHash, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	key := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		u = prf.Sum(u[:0])
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestPBKDF2Key(t *testing.T) {
+	// RFC 7914 第 11 节的 PBKDF2-HMAC-SHA256 测试向量
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	got := hex.EncodeToString(pbkdf2Key(sha256.New, []byte("passwd"), []byte("salt"), 1, 64))
+	if got != want {
+		t.Errorf("PBKDF2 结果错误:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestEncryptedBundleRoundTrip(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	writeTestFile(t, filepath.Join(home, ".gitconfig"), "[github]\n\ttoken = secret-token\n")
+
+	configService := NewConfigService()
+	var buf bytes.Buffer
+	if err := NewExportService(configService, NewSigningService()).Export(&buf, ExportOptions{Passphrase: "correct horse"}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	bundle := buf.Bytes()
+	if !isEncryptedBundle(bytes.NewReader(bundle)) || bytes.Contains(bundle, []byte("secret-token")) {
+		t.Fatalf("导出包未加密")
+	}
+
+	var decrypted bytes.Buffer
+	info, err := openBundle(bytes.NewReader(bundle), &decrypted, "correct horse", defaultArchiveLimits.maxArchiveSize())
+	if err != nil {
+		t.Fatalf("解密失败: %v", err)
+	}
+	if info.Version != bundleVersion || info.Format != ExportFormatZip || info.Iterations != bundleIterations || info.ChunkSize != bundleChunkSize {
+		t.Errorf("加密包头部错误: %+v", info)
+	}
+	plaintext := decrypted.Bytes()
+	entries, err := readArchive(bytes.NewReader(plaintext), int64(len(plaintext)), defaultArchiveLimits)
+	if err != nil {
+		t.Fatalf("读取解密后的压缩包失败: %v", err)
+	}
+	manifest := readImportManifest(entries)
+	if manifest == nil || manifest.Encryption == nil || manifest.Encryption.Salt != info.Salt {
+		t.Errorf("清单应记录密钥派生参数: %+v", manifest)
+	}
+
+	importService := NewImportService(configService, NewSigningService())
+	archive := bytes.NewReader(bundle)
+	checkCode := func(err error, code string) {
+		t.Helper()
+		var archiveErr *ArchiveError
+		if !errors.As(err, &archiveErr) || archiveErr.Detail.Code != code {
+			t.Errorf("应返回错误码 %s, got %v", code, err)
+		}
+	}
+	_, err = importService.Plan(archive, archive.Size(), ImportOptions{})
+	checkCode(err, models.ArchiveErrPassphrase)
+	_, err = importService.Plan(archive, archive.Size(), ImportOptions{Passphrase: "wrong"})
+	checkCode(err, models.ArchiveErrDecrypt)
+
+	tampered := append([]byte(nil), bundle...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = openBundle(bytes.NewReader(tampered), io.Discard, "correct horse", defaultArchiveLimits.maxArchiveSize())
+	checkCode(err, models.ArchiveErrDecrypt)
+	_, err = openBundle(bytes.NewReader(bundle), io.Discard, "correct horse", int64(len(plaintext)-1))
+	checkCode(err, models.ArchiveErrTotalTooLarge)
+
+	plan, err := importService.Plan(archive, archive.Size(), ImportOptions{Passphrase: "correct horse"})
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	if len(plan.Entries) != 1 || plan.Entries[0].FileID != "gitconfig" || plan.Entries[0].Reason != "内容与本地文件相同" {
+		t.Errorf("导入计划错误: %+v", plan.Entries)
+	}
+}
+
+func TestChunkedBundle(t *testing.T) {
+	info := models.EncryptionInfo{Version: bundleVersion, Cipher: bundleCipher, KDF: bundleKDF, Iterations: 1, Salt: "c2FsdA==", ChunkSize: 16}
+	open := func(bundle []byte) ([]byte, error) {
+		var out bytes.Buffer
+		_, err := openBundle(bytes.NewReader(bundle), &out, "pw", 1<<20)
+		return out.Bytes(), err
+	}
+
+	// 空内容、正好整段和跨段的内容都能还原
+	for _, size := range []int{0, 16, 40} {
+		plaintext := bytes.Repeat([]byte{'x'}, size)
+		var bundle bytes.Buffer
+		if err := sealBundle(&bundle, plaintext, "pw", info); err != nil {
+			t.Fatalf("加密失败: %v", err)
+		}
+		got, err := open(bundle.Bytes())
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("%d 字节的内容解密结果错误: %v", size, err)
+		}
+		// 截去末段后剩下的内容无法通过认证
+		if size > 16 {
+			if _, err := open(bundle.Bytes()[:bundle.Len()-size%16-16]); err == nil {
+				t.Errorf("截断的导出包应解密失败")
+			}
+		}
+	}
+}
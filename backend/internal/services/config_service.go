@@ -1,25 +1,54 @@
 package services
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/fs"
+	"log"
 	"os"
+	"path/filepath"
 	"strings"
-	"time"
+	"sync"
 
 	"linux-config-manager-backend/internal/models"
 )
 
 // ConfigService 处理配置文件相关的业务逻辑
-type ConfigService struct{}
+type ConfigService struct {
+	writeMu   sync.Mutex // 串行化文件写入，保证 If-Match 校验与写入之间不被其他请求插入
+	registry  *FileRegistry
+	history   *HistoryService
+	backups   *BackupService
+	templates *TemplateService
+	profiles  *ProfileService
+}
 
 // NewConfigService 创建新的配置服务实例
 func NewConfigService() *ConfigService {
-	return &ConfigService{}
+	registry := NewFileRegistry(filepath.Join(configDir(), "registry.json"))
+	systemService := NewSystemService()
+	profiles := NewProfileService(filepath.Join(configDir(), "profiles.json"), registry, systemService)
+	return &ConfigService{
+		registry:  registry,
+		history:   NewHistoryService(filepath.Join(dataDir(), "history")),
+		backups:   NewBackupService(filepath.Join(dataDir(), "backups"), filepath.Join(configDir(), "backup-policy.json")),
+		templates: NewTemplateService(systemService, profiles),
+		profiles:  profiles,
+	}
+}
+
+// UpdateOptions 控制 UpdateFile 的附加行为
+type UpdateOptions struct {
+	Author        string // 记录到历史版本中的修改者，为空时使用系统用户
+	Reason        string // 历史版本的提交说明，为空时使用默认说明
+	SymlinkPolicy string // 文件为符号链接时的写入策略，为空时使用 SymlinkFollow
+	IfMatch       string // 非空时要求磁盘上的当前内容与该 ETag 一致，否则返回 PreconditionError
+	Force         bool   // 为 true 时跳过格式校验错误，否则内容未通过校验会返回 ValidationError
 }
 
-// 预定义的配置分类
-var configCategories = []models.ConfigCategory{
+// 内置的配置分类，作为登记表的初始分类
+var defaultCategories = []models.ConfigCategory{
 	{ID: "shell", Name: "Shell 配置", Icon: "Terminal", Color: "bg-green-500", Description: "Shell 环境配置文件"},
 	{ID: "editor", Name: "编辑器配置", Icon: "FileText", Color: "bg-blue-500", Description: "文本编辑器配置"},
 	{ID: "git", Name: "Git 配置", Icon: "GitBranch", Color: "bg-orange-500", Description: "Git 版本控制配置"},
@@ -28,8 +57,8 @@ var configCategories = []models.ConfigCategory{
 	{ID: "app", Name: "应用配置", Icon: "Package", Color: "bg-indigo-500", Description: "应用程序配置"},
 }
 
-// 预定义的常用配置文件
-var commonConfigFiles = []models.ConfigFile{
+// 内置的常用配置文件，作为登记表的初始内容
+var defaultConfigFiles = []models.ConfigFile{
 	{ID: "bashrc", Name: ".bashrc", Path: "~/.bashrc", Category: "shell", Description: "Bash shell 配置"},
 	{ID: "zshrc", Name: ".zshrc", Path: "~/.zshrc", Category: "shell", Description: "Zsh shell 配置"},
 	{ID: "profile", Name: ".profile", Path: "~/.profile", Category: "shell", Description: "Shell 环境变量"},
@@ -39,21 +68,154 @@ var commonConfigFiles = []models.ConfigFile{
 }
 
 // GetCategories 获取所有配置分类
-func (s *ConfigService) GetCategories() []models.ConfigCategory {
-	return configCategories
+func (s *ConfigService) GetCategories() ([]models.ConfigCategory, error) {
+	return s.registry.Categories()
+}
+
+// CreateCategory 新建配置分类
+func (s *ConfigService) CreateCategory(category models.ConfigCategory) (*models.ConfigCategory, error) {
+	return s.registry.AddCategory(category)
+}
+
+// UpdateCategory 修改配置分类的名称、图标、颜色和描述
+func (s *ConfigService) UpdateCategory(categoryID string, category models.ConfigCategory) (*models.ConfigCategory, error) {
+	return s.registry.UpdateCategory(categoryID, category)
+}
+
+// DeleteCategory 删除配置分类
+// 仍有文件引用该分类时，reassignTo 为空则拒绝删除，否则将这些文件移到 reassignTo 分类
+func (s *ConfigService) DeleteCategory(categoryID, reassignTo string) error {
+	if err := s.registry.RemoveCategory(categoryID, reassignTo); err != nil {
+		return err
+	}
+	// 档案改为引用文件改归的分类，避免按档案导出或修改档案时引用不存在的分类
+	if err := s.profiles.ReplaceCategoryRefs(categoryID, reassignTo); err != nil {
+		log.Printf("更新档案中对分类 %s 的引用失败: %v", categoryID, err)
+	}
+	return nil
+}
+
+// RegisterFile 登记新的配置文件，以模板方式登记时用文件的当前内容初始化模板源文件
+func (s *ConfigService) RegisterFile(req models.RegisterFileRequest) (*models.ConfigFile, error) {
+	file, err := s.registry.Add(req)
+	if err != nil || !file.Template {
+		return file, err
+	}
+	if err := s.initTemplateSource(file); err != nil {
+		if removeErr := s.registry.Remove(file.ID); removeErr != nil {
+			log.Printf("撤销登记 %s 失败: %v", file.ID, removeErr)
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// UpdateFileMeta 修改已登记配置文件的元数据，开启模板方式时用文件的当前内容初始化模板源文件
+func (s *ConfigService) UpdateFileMeta(fileID string, req models.UpdateFileMetaRequest) (*models.ConfigFile, error) {
+	file, err := s.registry.Update(fileID, req)
+	if err != nil || req.Template == nil || !*req.Template {
+		return file, err
+	}
+	if err := s.initTemplateSource(file); err != nil {
+		off := false
+		if _, revertErr := s.registry.Update(file.ID, models.UpdateFileMetaRequest{Template: &off}); revertErr != nil {
+			log.Printf("关闭 %s 的模板方式失败: %v", file.ID, revertErr)
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+// UnregisterFile 取消登记配置文件，磁盘上的文件保持不变，模板源文件和档案中的引用随登记一起删除
+func (s *ConfigService) UnregisterFile(fileID string) error {
+	if err := s.registry.Remove(fileID); err != nil {
+		return err
+	}
+	if err := os.Remove(s.templates.SourcePath(fileID)); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除 %s 的模板源文件失败: %v", fileID, err)
+	}
+	if err := s.profiles.RemoveFileRefs(fileID); err != nil {
+		log.Printf("从档案中移除文件 %s 失败: %v", fileID, err)
+	}
+	return nil
+}
+
+// initTemplateSource 用真实文件的当前内容初始化模板源文件，源文件已存在或真实文件不存在时不做处理
+func (s *ConfigService) initTemplateSource(file *models.ConfigFile) error {
+	sourcePath := s.templates.SourcePath(file.ID)
+	if _, err := os.Lstat(sourcePath); err == nil {
+		return nil
+	}
+	realPath, err := expandHome(file.Path)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(realPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("无法读取文件 %s: %w", realPath, err)
+	}
+	return writeTemplateSource(sourcePath, content)
+}
+
+// writeTemplateSource 原子地写入模板源文件，模板目录只允许当前用户访问
+func writeTemplateSource(sourcePath string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(sourcePath), 0700); err != nil {
+		return fmt.Errorf("无法创建模板目录: %w", err)
+	}
+	_, err := atomicWriteFile(sourcePath, content, SymlinkFollow)
+	return err
+}
+
+// contentPath 返回保存文件内容的路径：模板文件为模板源文件，其他文件为真实路径
+// 编辑、历史版本、备份和比较都针对该路径上的内容
+func (s *ConfigService) contentPath(file *models.ConfigFile, realPath string) string {
+	if file.Template {
+		return s.templates.SourcePath(file.ID)
+	}
+	return realPath
+}
+
+// renderSource 用本机的系统信息和变量渲染模板文件的源内容
+func (s *ConfigService) renderSource(file *models.ConfigFile, source string) (string, error) {
+	data, err := s.templates.MachineData()
+	if err != nil {
+		return "", err
+	}
+	return renderTemplate(file.ID, source, data)
+}
+
+// resolveFile 通过登记表查找文件并返回展开后的真实路径
+func (s *ConfigService) resolveFile(fileID string) (*models.ConfigFile, string, error) {
+	file, err := s.registry.Get(fileID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	realPath, err := expandHome(file.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return file, realPath, nil
 }
 
 // GetFiles 获取所有配置文件列表
 func (s *ConfigService) GetFiles() ([]models.ConfigFile, error) {
-	homeDir, err := os.UserHomeDir()
+	registered, err := s.registry.List()
 	if err != nil {
-		return nil, fmt.Errorf("无法获取用户主目录: %w", err)
+		return nil, err
 	}
 
 	var files []models.ConfigFile
 
-	for _, file := range commonConfigFiles {
-		realPath := strings.Replace(file.Path, "~", homeDir, 1)
+	for _, file := range registered {
+		realPath, err := expandHome(file.Path)
+		if err != nil {
+			return nil, err
+		}
 
 		// 检查文件是否存在并获取文件信息
 		if info, err := os.Lstat(realPath); err == nil {
@@ -61,11 +223,8 @@ func (s *ConfigService) GetFiles() ([]models.ConfigFile, error) {
 			file.Size = info.Size()
 			file.IsSymlink = info.Mode()&fs.ModeSymlink != 0
 
-			// 检查备份是否存在
-			backupPath := realPath + ".backup"
-			if _, err := os.Stat(backupPath); err == nil {
-				file.BackupExists = true
-			}
+			// 检查备份目录中是否存在该文件的备份
+			file.BackupExists = s.backups.HasBackup(file.ID)
 
 			// 只添加存在的文件到列表中
 			files = append(files, file)
@@ -77,30 +236,34 @@ func (s *ConfigService) GetFiles() ([]models.ConfigFile, error) {
 
 // GetFileByID 根据ID获取配置文件详情
 func (s *ConfigService) GetFileByID(fileID string) (*models.ConfigFile, error) {
-	// 查找文件
-	var targetFile *models.ConfigFile
-	for _, file := range commonConfigFiles {
-		if file.ID == fileID {
-			fileCopy := file // 创建副本避免修改原始数据
-			targetFile = &fileCopy
-			break
-		}
-	}
-
-	if targetFile == nil {
-		return nil, fmt.Errorf("文件未找到: %s", fileID)
-	}
-
-	homeDir, err := os.UserHomeDir()
+	// 通过登记表查找文件
+	targetFile, realPath, err := s.resolveFile(fileID)
 	if err != nil {
-		return nil, fmt.Errorf("无法获取用户主目录: %w", err)
+		return nil, err
 	}
 
-	realPath := strings.Replace(targetFile.Path, "~", homeDir, 1)
+	// 模板文件返回模板源文件的内容
+	if targetFile.Template {
+		content, err := os.ReadFile(s.templates.SourcePath(targetFile.ID))
+		if os.IsNotExist(err) {
+			return nil, notFoundf("模板源文件不存在: %s", targetFile.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("无法读取 %s 的模板源文件: %w", targetFile.ID, err)
+		}
+		targetFile.Content = string(content)
+		targetFile.ETag = contentETag(content)
+		if info, err := os.Lstat(realPath); err == nil {
+			targetFile.LastModified = info.ModTime()
+			targetFile.Size = info.Size()
+			targetFile.IsSymlink = info.Mode()&fs.ModeSymlink != 0
+		}
+		return targetFile, nil
+	}
 
 	// 检查文件是否存在
 	if _, err := os.Stat(realPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("文件不存在: %s", realPath)
+		return nil, notFoundf("文件不存在: %s", realPath)
 	}
 
 	// 读取文件内容
@@ -110,6 +273,7 @@ func (s *ConfigService) GetFileByID(fileID string) (*models.ConfigFile, error) {
 	}
 
 	targetFile.Content = string(content)
+	targetFile.ETag = contentETag(content)
 
 	// 更新文件信息
 	if info, err := os.Lstat(realPath); err == nil {
@@ -121,79 +285,564 @@ func (s *ConfigService) GetFileByID(fileID string) (*models.ConfigFile, error) {
 	return targetFile, nil
 }
 
-// UpdateFile 更新配置文件内容
-func (s *ConfigService) UpdateFile(fileID, content string) error {
-	// 查找文件
-	var targetFile *models.ConfigFile
-	for _, file := range commonConfigFiles {
-		if file.ID == fileID {
-			targetFile = &file
-			break
+// UpdateFile 原子地更新配置文件内容（保留权限、属主和扩展属性），并将修改提交到历史仓库
+// 模板文件的 content 是模板源文件：保存源文件后把本机渲染的结果写入真实路径，格式校验针对渲染结果
+func (s *ConfigService) UpdateFile(fileID, content string, opts UpdateOptions) (*models.WriteResult, error) {
+	// 通过登记表查找文件
+	file, realPath, err := s.resolveFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := content
+	if file.Template {
+		if rendered, err = s.renderSource(file, content); err != nil {
+			return nil, err
 		}
 	}
 
-	if targetFile == nil {
-		return fmt.Errorf("文件未找到: %s", fileID)
+	// 格式校验可能启动子进程，放在写锁之外执行
+	validation := validateTemplate(*file, content, rendered)
+	if !validation.Valid && !opts.Force {
+		return nil, &ValidationError{Result: validation}
 	}
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("无法获取用户主目录: %w", err)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	storePath := s.contentPath(file, realPath)
+	previous, readErr := os.ReadFile(storePath)
+	exists := readErr == nil
+	if readErr != nil && !os.IsNotExist(readErr) {
+		return nil, fmt.Errorf("无法读取文件 %s: %w", storePath, readErr)
+	}
+
+	// 客户端读取之后文件被其他程序修改时拒绝覆盖
+	if opts.IfMatch != "" {
+		currentETag := ""
+		if exists {
+			currentETag = contentETag(previous)
+		}
+		if !etagMatches(opts.IfMatch, currentETag, exists) {
+			return nil, &PreconditionError{Conflict: &models.ConflictInfo{
+				Path:        storePath,
+				CurrentETag: currentETag,
+				Content:     string(previous),
+				Diff:        UnifiedDiff("当前文件 "+storePath, "提交的内容", string(previous), content),
+			}}
+		}
+	}
+
+	// 首次修改前先记录原始内容，保证可以恢复到修改前的状态
+	if exists {
+		if err := s.history.RecordBaseline(fileID, file.Path, string(previous)); err != nil {
+			log.Printf("记录 %s 的初始版本失败: %v", fileID, err)
+		}
 	}
 
-	realPath := strings.Replace(targetFile.Path, "~", homeDir, 1)
+	// 模板文件的真实路径保存的是上次的渲染结果，源文件保存失败时用于恢复
+	var renderedBefore []byte
+	renderedExists := false
+	if file.Template {
+		renderedBefore, readErr = os.ReadFile(realPath)
+		renderedExists = readErr == nil
+		if readErr != nil && !os.IsNotExist(readErr) {
+			return nil, fmt.Errorf("无法读取文件 %s: %w", realPath, readErr)
+		}
+	}
 
 	// 写入文件
-	err = os.WriteFile(realPath, []byte(content), 0644)
+	result, err := atomicWriteFile(realPath, []byte(rendered), opts.SymlinkPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	// 模板文件在渲染结果写入真实路径后再保存源文件，失败时恢复真实路径，保持源文件与渲染结果一致
+	if file.Template {
+		if err := writeTemplateSource(storePath, []byte(content)); err != nil {
+			s.restoreRendered(realPath, renderedBefore, renderedExists, opts.SymlinkPolicy)
+			return nil, err
+		}
+	}
+	result.ETag = contentETag([]byte(content))
+	if len(validation.Diagnostics) > 0 {
+		result.Diagnostics = validation.Diagnostics
+	}
+
+	// 历史记录失败不影响保存结果
+	reason := opts.Reason
+	if reason == "" {
+		reason = "更新 " + fileID
+	}
+	if _, err := s.history.Record(fileID, file.Path, content, opts.Author, reason); err != nil {
+		log.Printf("提交 %s 的历史版本失败: %v", fileID, err)
+	}
+
+	return result, nil
+}
+
+// restoreRendered 将模板文件的真实路径恢复为写入前的内容，写入前不存在时删除
+func (s *ConfigService) restoreRendered(realPath string, previous []byte, exists bool, symlinkPolicy string) {
+	var err error
+	if exists {
+		_, err = atomicWriteFile(realPath, previous, symlinkPolicy)
+	} else {
+		err = os.Remove(realPath)
+	}
 	if err != nil {
-		return fmt.Errorf("无法写入文件 %s: %w", realPath, err)
+		log.Printf("恢复 %s 的渲染结果失败: %v", realPath, err)
 	}
+}
 
+// ReplaceWithSymlink 将登记的文件原子地替换为指向 target 的符号链接，用于导入保留了符号链接的压缩包
+// 使用 opts 中的 IfMatch、Author 和 Reason：IfMatch 非空时要求当前文件内容的 ETag 与之匹配；
+// 历史版本中记录“符号链接 -> 目标”形式的文本
+func (s *ConfigService) ReplaceWithSymlink(fileID, target string, opts UpdateOptions) error {
+	file, realPath, err := s.resolveFile(fileID)
+	if err != nil {
+		return err
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	previous, readErr := os.ReadFile(realPath)
+	exists := readErr == nil
+	if opts.IfMatch != "" {
+		currentETag := ""
+		if exists {
+			currentETag = contentETag(previous)
+		}
+		if !etagMatches(opts.IfMatch, currentETag, exists) {
+			return &PreconditionError{Conflict: &models.ConflictInfo{
+				Path:        realPath,
+				CurrentETag: currentETag,
+				Content:     string(previous),
+			}}
+		}
+	}
+
+	// 首次修改前先记录原始内容，原来就是符号链接时记录链接内容
+	if link, err := os.Readlink(realPath); err == nil {
+		previous, exists = []byte(describeSymlink(link)), true
+	}
+	if exists {
+		if err := s.history.RecordBaseline(fileID, file.Path, string(previous)); err != nil {
+			log.Printf("记录 %s 的初始版本失败: %v", fileID, err)
+		}
+	}
+
+	if err := atomicSymlink(realPath, target); err != nil {
+		return err
+	}
+
+	// 历史记录失败不影响替换结果
+	reason := opts.Reason
+	if reason == "" {
+		reason = "将 " + fileID + " 替换为符号链接"
+	}
+	if _, err := s.history.Record(fileID, file.Path, describeSymlink(target), opts.Author, reason); err != nil {
+		log.Printf("提交 %s 的历史版本失败: %v", fileID, err)
+	}
 	return nil
 }
 
-// BackupFile 创建配置文件备份
-func (s *ConfigService) BackupFile(fileID string) (*models.BackupFileResponse, error) {
-	// 查找文件
-	var targetFile *models.ConfigFile
-	for _, file := range commonConfigFiles {
-		if file.ID == fileID {
-			targetFile = &file
-			break
+// ValidateFile 按文件格式校验内容但不写入，content 为 nil 时校验磁盘上的当前内容
+// 模板文件的 content 是模板源文件，格式校验针对本机渲染后的结果，受管块标记检查针对源文件
+func (s *ConfigService) ValidateFile(fileID string, content *string) (*models.ValidationResult, error) {
+	file, realPath, err := s.resolveFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if content != nil {
+		rendered := *content
+		if file.Template {
+			if rendered, err = s.renderSource(file, *content); err != nil {
+				return nil, err
+			}
 		}
+		return validateTemplate(*file, *content, rendered), nil
 	}
 
-	if targetFile == nil {
-		return nil, fmt.Errorf("文件未找到: %s", fileID)
+	current, err := os.ReadFile(realPath)
+	if os.IsNotExist(err) {
+		return nil, notFoundf("文件不存在: %s", realPath)
 	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取文件 %s: %w", realPath, err)
+	}
+	source := current
+	if file.Template {
+		if source, err = os.ReadFile(s.templates.SourcePath(file.ID)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("无法读取 %s 的模板源文件: %w", file.ID, err)
+		}
+	}
+	return validateTemplate(*file, string(source), string(current)), nil
+}
 
-	homeDir, err := os.UserHomeDir()
+// RenderFile 渲染模板文件并与真实路径上的当前内容比较，不写入任何文件
+// overrides 中非空的字段覆盖本机的系统信息，用于预览其他机器上的渲染结果：
+// 指定 Profile 时使用该档案，否则按覆盖后的系统信息解析活动档案；其中的变量最后合并
+func (s *ConfigService) RenderFile(fileID string, overrides models.TemplateData) (*models.RenderResult, error) {
+	file, realPath, err := s.resolveFile(fileID)
 	if err != nil {
-		return nil, fmt.Errorf("无法获取用户主目录: %w", err)
+		return nil, err
+	}
+	if !file.Template {
+		return nil, invalidf("文件 %s 不是模板文件", fileID)
 	}
 
-	realPath := strings.Replace(targetFile.Path, "~", homeDir, 1)
+	source, err := os.ReadFile(s.templates.SourcePath(file.ID))
+	if os.IsNotExist(err) {
+		return nil, notFoundf("模板源文件不存在: %s", file.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取 %s 的模板源文件: %w", file.ID, err)
+	}
 
-	// 检查文件是否存在
-	if _, err := os.Stat(realPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("文件不存在: %s", realPath)
+	info, err := s.templates.systemService.GetSystemInfo()
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range []struct{ value, override *string }{
+		{&info.Hostname, &overrides.Hostname},
+		{&info.OS, &overrides.OS},
+		{&info.Kernel, &overrides.Kernel},
+		{&info.User, &overrides.User},
+		{&info.HomeDir, &overrides.HomeDir},
+		{&info.Shell, &overrides.Shell},
+	} {
+		if *field.override != "" {
+			*field.value = *field.override
+		}
+	}
+	data, err := s.templates.DataFor(*info, overrides.Profile)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range overrides.Vars {
+		data.Vars[name] = value
+	}
+
+	rendered, err := renderTemplate(file.ID, string(source), data)
+	if err != nil {
+		return nil, err
+	}
+	current, err := readCurrentContent(realPath)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RenderResult{
+		FileID:  file.ID,
+		Path:    file.Path,
+		Content: rendered,
+		Data:    data,
+		Diff:    UnifiedDiff("当前文件 "+file.Path, "渲染结果", current, rendered),
+	}, nil
+}
+
+// GetTemplateVars 获取渲染模板时使用的用户定义变量
+func (s *ConfigService) GetTemplateVars() (map[string]string, error) {
+	return s.templates.Vars()
+}
+
+// SetTemplateVars 整体替换渲染模板时使用的用户定义变量，已渲染的文件需重新保存才会更新
+func (s *ConfigService) SetTemplateVars(vars map[string]string) (map[string]string, error) {
+	return s.templates.SetVars(vars)
+}
+
+// ListProfiles 获取所有机器配置档案
+func (s *ConfigService) ListProfiles() ([]models.MachineProfile, error) {
+	return s.profiles.List()
+}
+
+// GetProfile 根据ID获取机器配置档案
+func (s *ConfigService) GetProfile(profileID string) (*models.MachineProfile, error) {
+	return s.profiles.Get(profileID)
+}
+
+// CreateProfile 新建机器配置档案
+func (s *ConfigService) CreateProfile(profile models.MachineProfile) (*models.MachineProfile, error) {
+	return s.profiles.Create(profile)
+}
+
+// UpdateProfile 修改机器配置档案，档案ID不可修改
+func (s *ConfigService) UpdateProfile(profileID string, profile models.MachineProfile) (*models.MachineProfile, error) {
+	return s.profiles.Update(profileID, profile)
+}
+
+// DeleteProfile 删除机器配置档案
+func (s *ConfigService) DeleteProfile(profileID string) error {
+	return s.profiles.Delete(profileID)
+}
+
+// ResolveProfile 解析活动档案并说明原因，overrides 中非空的字段覆盖本机的主机名、操作系统和内核版本
+func (s *ConfigService) ResolveProfile(overrides models.SystemInfo) (*models.ProfileResolution, error) {
+	info, err := s.templates.systemService.GetSystemInfo()
+	if err != nil {
+		return nil, err
+	}
+	if overrides.Hostname != "" {
+		info.Hostname = overrides.Hostname
+	}
+	if overrides.OS != "" {
+		info.OS = overrides.OS
+	}
+	if overrides.Kernel != "" {
+		info.Kernel = overrides.Kernel
+	}
+	return s.profiles.Resolve(*info)
+}
+
+// ListBlocks 列出配置文件中的受管块及标记问题，模板文件针对模板源文件，文件不存在时没有受管块
+func (s *ConfigService) ListBlocks(fileID string) (*models.BlockList, error) {
+	file, realPath, err := s.resolveFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := readCurrentContent(s.contentPath(file, realPath))
+	if err != nil {
+		return nil, err
+	}
+	blocks, problems := parseBlocks(content)
+	return &models.BlockList{FileID: file.ID, Blocks: blocks, Problems: problems}, nil
+}
+
+// SetBlock 插入或更新配置文件中的受管块，块以外的内容保持不变
+func (s *ConfigService) SetBlock(fileID, name string, req models.SetBlockRequest, opts UpdateOptions) (*models.WriteResult, error) {
+	if opts.Reason == "" {
+		opts.Reason = fmt.Sprintf("更新 %s 的受管块 %s", fileID, name)
+	}
+	opts.Force = req.Force
+	return s.editBlocks(fileID, opts, func(file *models.ConfigFile, content string) (string, error) {
+		return setBlock(content, blockCommentPrefix(*file), name, req)
+	})
+}
+
+// RemoveBlock 删除配置文件中的受管块及其标记行
+func (s *ConfigService) RemoveBlock(fileID, name string, opts UpdateOptions) (*models.WriteResult, error) {
+	if opts.Reason == "" {
+		opts.Reason = fmt.Sprintf("删除 %s 的受管块 %s", fileID, name)
+	}
+	return s.editBlocks(fileID, opts, func(_ *models.ConfigFile, content string) (string, error) {
+		return removeBlock(content, name)
+	})
+}
+
+// editBlocks 读取当前内容、修改受管块后通过 UpdateFile 写回
+// 未指定 IfMatch 时使用读取时的 ETag，避免覆盖读取之后其他程序追加的内容
+func (s *ConfigService) editBlocks(fileID string, opts UpdateOptions, edit func(*models.ConfigFile, string) (string, error)) (*models.WriteResult, error) {
+	file, realPath, err := s.resolveFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := os.ReadFile(s.contentPath(file, realPath))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("无法读取文件 %s: %w", realPath, err)
+	}
+	if opts.IfMatch == "" && err == nil {
+		opts.IfMatch = contentETag(current)
+	}
+
+	updated, err := edit(file, string(current))
+	if err != nil {
+		return nil, err
+	}
+	return s.UpdateFile(fileID, updated, opts)
+}
+
+// DiffProposed 比较磁盘上的当前内容与提议的内容，文件不存在时当前内容视为空
+func (s *ConfigService) DiffProposed(fileID, content string) (*models.DiffResult, error) {
+	file, realPath, err := s.resolveFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := readCurrentContent(s.contentPath(file, realPath))
+	if err != nil {
+		return nil, err
+	}
+	return ComputeDiff("current", "proposed", current, content), nil
+}
+
+// DiffAgainst 比较指定的备份或历史版本与磁盘上的当前内容
+// against 的格式为 backup:<备份ID> 或 rev:<版本号>
+func (s *ConfigService) DiffAgainst(fileID, against string) (*models.DiffResult, error) {
+	file, realPath, err := s.resolveFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	var base string
+	kind, ref, _ := strings.Cut(against, ":")
+	switch {
+	case kind == "backup" && ref != "":
+		backup, content, err := s.backups.Get(ref)
+		if err != nil {
+			return nil, err
+		}
+		if backup.FileID != fileID {
+			return nil, invalidf("备份 %s 不属于文件 %s", ref, fileID)
+		}
+		base = string(content)
+	case kind == "rev" && ref != "":
+		revision, err := s.history.Show(fileID, file.Path, ref)
+		if err != nil {
+			return nil, err
+		}
+		base = revision.Content
+	default:
+		return nil, invalidf("无效的比较对象: %q（应为 backup:<备份ID> 或 rev:<版本号>）", against)
 	}
 
-	backupPath := realPath + ".backup." + time.Now().Format("20060102-150405")
+	current, err := readCurrentContent(s.contentPath(file, realPath))
+	if err != nil {
+		return nil, err
+	}
+	return ComputeDiff(against, "current", base, current), nil
+}
 
-	// 复制文件作为备份
+// readCurrentContent 读取文件的当前内容，文件不存在时返回空字符串
+func readCurrentContent(realPath string) (string, error) {
 	content, err := os.ReadFile(realPath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("无法读取文件 %s: %w", realPath, err)
+	}
+	return string(content), nil
+}
+
+// contentETag 计算内容的强 ETag（带引号的 SHA-256）
+func contentETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// etagMatches 按 If-Match 语义判断当前 ETag 是否匹配，支持 * 和逗号分隔的多个值
+func etagMatches(ifMatch, currentETag string, exists bool) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" {
+			return exists
+		}
+		if !strings.HasPrefix(candidate, `"`) {
+			candidate = `"` + candidate + `"`
+		}
+		if exists && candidate == currentETag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetFileHistory 获取配置文件的历史版本列表
+// 历史按文件ID和登记路径区分，路径变更后从新的历史开始
+func (s *ConfigService) GetFileHistory(fileID string) ([]models.Revision, error) {
+	file, err := s.registry.Get(fileID)
+	if err != nil {
+		return nil, err
+	}
+	return s.history.Log(fileID, file.Path)
+}
+
+// GetFileRevision 获取配置文件在指定版本的内容
+func (s *ConfigService) GetFileRevision(fileID, rev string) (*models.RevisionContent, error) {
+	file, err := s.registry.Get(fileID)
 	if err != nil {
-		return nil, fmt.Errorf("无法读取原文件 %s: %w", realPath, err)
+		return nil, err
 	}
+	return s.history.Show(fileID, file.Path, rev)
+}
 
-	err = os.WriteFile(backupPath, content, 0644)
+// RestoreFileRevision 将配置文件恢复为指定版本的内容，恢复操作本身也会记录为新版本
+func (s *ConfigService) RestoreFileRevision(fileID, rev, author string) (*models.RevisionContent, error) {
+	revision, err := s.GetFileRevision(fileID, rev)
 	if err != nil {
-		return nil, fmt.Errorf("无法创建备份文件 %s: %w", backupPath, err)
+		return nil, err
+	}
+
+	// 恢复的是曾经保存过的内容，不再做格式校验
+	reason := fmt.Sprintf("恢复 %s 到版本 %s", fileID, revision.ShortRev)
+	if _, err := s.UpdateFile(fileID, revision.Content, UpdateOptions{Author: author, Reason: reason, Force: true}); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// BackupFile 在备份目录中创建配置文件备份
+func (s *ConfigService) BackupFile(fileID, reason string) (*models.BackupFileResponse, error) {
+	// 通过登记表查找文件
+	file, realPath, err := s.resolveFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	backup, err := s.backups.Create(file.ID, file.Path, s.contentPath(file, realPath), reason)
+	if err != nil {
+		return nil, err
 	}
 
 	return &models.BackupFileResponse{
 		Message:    "备份创建成功",
-		BackupPath: backupPath,
+		BackupPath: s.backups.contentPath(*backup),
+		Backup:     backup,
 	}, nil
 }
+
+// ListBackups 获取备份列表，fileID 为空时返回所有备份
+func (s *ConfigService) ListBackups(fileID string) ([]models.Backup, error) {
+	if fileID != "" {
+		if _, err := s.registry.Get(fileID); err != nil {
+			return nil, err
+		}
+	}
+	return s.backups.List(fileID)
+}
+
+// DeleteBackup 删除备份
+func (s *ConfigService) DeleteBackup(backupID string) error {
+	return s.backups.Delete(backupID)
+}
+
+// RestoreBackup 将备份内容写回对应的配置文件，写入前会自动备份当前内容
+func (s *ConfigService) RestoreBackup(backupID, author string) (*models.Backup, error) {
+	backup, content, err := s.backups.Get(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, realPath, err := s.resolveFile(backup.FileID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 恢复前的备份不能按保留策略清理掉正在恢复的备份
+	storePath := s.contentPath(file, realPath)
+	if _, err := os.Stat(storePath); err == nil {
+		if _, err := s.backups.CreatePreRestore(file.ID, file.Path, storePath, backup.ID); err != nil {
+			return nil, fmt.Errorf("恢复前备份当前文件失败: %w", err)
+		}
+	}
+
+	// 恢复的是曾经存在过的内容，不再做格式校验
+	reason := fmt.Sprintf("从备份 %s 恢复 %s", backup.ID, file.ID)
+	if _, err := s.UpdateFile(file.ID, string(content), UpdateOptions{Author: author, Reason: reason, Force: true}); err != nil {
+		return nil, err
+	}
+	return backup, nil
+}
+
+// GetBackupPolicy 获取备份保留策略
+func (s *ConfigService) GetBackupPolicy() (models.BackupPolicy, error) {
+	return s.backups.Policy()
+}
+
+// SetBackupPolicy 修改备份保留策略，并立即按新策略清理
+func (s *ConfigService) SetBackupPolicy(policy models.BackupPolicy) (*models.BackupPolicy, error) {
+	return s.backups.SetPolicy(policy)
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// diffContextLines 统一格式差异中每个变更块前后保留的上下文行数
+const diffContextLines = 3
+
+// 差异操作类型
+const (
+	diffEqual  = ' '
+	diffDelete = '-'
+	diffInsert = '+'
+)
+
+// diffOp 表示一行的差异操作，Text 包含行尾换行符（最后一行可能没有）
+type diffOp struct {
+	Kind byte
+	Text string
+}
+
+// splitLines 按行拆分内容并保留换行符，便于区分文件末尾是否有换行
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines 计算两组行之间的最短编辑脚本
+func diffLines(a, b []string) []diffOp {
+	// 先去掉公共前后缀，缩小 Myers 算法的规模
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{Kind: diffEqual, Text: line})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{Kind: diffEqual, Text: line})
+	}
+	return ops
+}
+
+// UnifiedDiff 生成统一格式（unified）的差异文本，内容相同时返回空字符串
+func UnifiedDiff(fromName, toName, from, to string) string {
+	ops := diffLines(splitLines(from), splitLines(to))
+	return formatUnified(fromName, toName, groupHunks(ops, diffContextLines))
+}
+
+// ComputeDiff 计算两个版本的差异，返回统一格式文本以及带行内差异的并排视图
+func ComputeDiff(fromName, toName, from, to string) *models.DiffResult {
+	ops := diffLines(splitLines(from), splitLines(to))
+	hunks := groupHunks(ops, diffContextLines)
+
+	result := &models.DiffResult{
+		From:      fromName,
+		To:        toName,
+		Identical: len(hunks) == 0,
+		Unified:   formatUnified(fromName, toName, hunks),
+		Hunks:     make([]models.DiffHunk, 0, len(hunks)),
+	}
+	for _, op := range ops {
+		switch op.Kind {
+		case diffInsert:
+			result.Added++
+		case diffDelete:
+			result.Removed++
+		}
+	}
+	for _, hunk := range hunks {
+		result.Hunks = append(result.Hunks, sideBySideHunk(hunk))
+	}
+	return result
+}
+
+// formatUnified 将变更块格式化为统一格式文本
+func formatUnified(fromName, toName string, hunks []diffHunk) string {
+	var b strings.Builder
+	for _, hunk := range hunks {
+		if b.Len() == 0 {
+			fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(hunk.fromStart, hunk.fromCount), hunkRange(hunk.toStart, hunk.toCount))
+		for _, op := range hunk.ops {
+			b.WriteByte(op.Kind)
+			b.WriteString(op.Text)
+			if !strings.HasSuffix(op.Text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// diffHunk 统一格式差异中的一个变更块，行号从 1 开始
+type diffHunk struct {
+	fromStart, fromCount int
+	toStart, toCount     int
+	ops                  []diffOp
+}
+
+// groupHunks 将编辑脚本按上下文行数分组为变更块
+func groupHunks(ops []diffOp, context int) []diffHunk {
+	var hunks []diffHunk
+
+	i := 0
+	fromLine, toLine := 1, 1
+	for i < len(ops) {
+		// 跳到下一个变更
+		for i < len(ops) && ops[i].Kind == diffEqual {
+			i++
+			fromLine++
+			toLine++
+		}
+		if i >= len(ops) {
+			break
+		}
+
+		// 变更块从变更前最多 context 行开始
+		start := max(i-context, 0)
+		lead := i - start
+		hunk := diffHunk{fromStart: fromLine - lead, toStart: toLine - lead}
+
+		// 变更块在连续超过 2*context 个未变化行处结束
+		end := i
+		for end < len(ops) {
+			if ops[end].Kind != diffEqual {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].Kind == diffEqual {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end += min(context, run-end)
+				break
+			}
+			end = run
+		}
+
+		hunk.ops = ops[start:end]
+		for _, op := range hunk.ops {
+			if op.Kind != diffInsert {
+				hunk.fromCount++
+			}
+			if op.Kind != diffDelete {
+				hunk.toCount++
+			}
+		}
+		hunks = append(hunks, hunk)
+
+		for _, op := range ops[i:end] {
+			if op.Kind != diffInsert {
+				fromLine++
+			}
+			if op.Kind != diffDelete {
+				toLine++
+			}
+		}
+		i = end
+	}
+	return hunks
+}
+
+// hunkRange 生成变更块头部的行号范围，例如 "3,4"；空范围按惯例使用前一行的行号
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// 并排视图的行类型
+const (
+	DiffRowEqual  = "equal"
+	DiffRowDelete = "delete"
+	DiffRowInsert = "insert"
+	DiffRowChange = "change"
+)
+
+// sideBySideHunk 将变更块转换为并排视图：连续的删除行和插入行按顺序两两配对为 change 行
+func sideBySideHunk(hunk diffHunk) models.DiffHunk {
+	out := models.DiffHunk{
+		FromStart: hunk.fromStart,
+		FromCount: hunk.fromCount,
+		ToStart:   hunk.toStart,
+		ToCount:   hunk.toCount,
+		Rows:      []models.DiffRow{},
+	}
+
+	fromLine, toLine := hunk.fromStart, hunk.toStart
+	ops := hunk.ops
+	for i := 0; i < len(ops); {
+		if ops[i].Kind == diffEqual {
+			text := strings.TrimSuffix(ops[i].Text, "\n")
+			out.Rows = append(out.Rows, models.DiffRow{
+				Kind:  DiffRowEqual,
+				Left:  &models.DiffLine{Number: fromLine, Text: text},
+				Right: &models.DiffLine{Number: toLine, Text: text},
+			})
+			fromLine++
+			toLine++
+			i++
+			continue
+		}
+
+		var deleted, inserted []string
+		for ; i < len(ops) && ops[i].Kind != diffEqual; i++ {
+			if ops[i].Kind == diffDelete {
+				deleted = append(deleted, strings.TrimSuffix(ops[i].Text, "\n"))
+			} else {
+				inserted = append(inserted, strings.TrimSuffix(ops[i].Text, "\n"))
+			}
+		}
+
+		for k := 0; k < max(len(deleted), len(inserted)); k++ {
+			switch {
+			case k < len(deleted) && k < len(inserted):
+				left, right := intralineDiff(deleted[k], inserted[k])
+				out.Rows = append(out.Rows, models.DiffRow{
+					Kind:  DiffRowChange,
+					Left:  &models.DiffLine{Number: fromLine, Text: deleted[k], Segments: left},
+					Right: &models.DiffLine{Number: toLine, Text: inserted[k], Segments: right},
+				})
+				fromLine++
+				toLine++
+			case k < len(deleted):
+				out.Rows = append(out.Rows, models.DiffRow{
+					Kind: DiffRowDelete,
+					Left: &models.DiffLine{Number: fromLine, Text: deleted[k]},
+				})
+				fromLine++
+			default:
+				out.Rows = append(out.Rows, models.DiffRow{
+					Kind:  DiffRowInsert,
+					Right: &models.DiffLine{Number: toLine, Text: inserted[k]},
+				})
+				toLine++
+			}
+		}
+	}
+	return out
+}
+
+// intralineDiff 以单词、空白和标点为单位计算两行之间的行内差异
+func intralineDiff(from, to string) (left, right []models.DiffSegment) {
+	for _, op := range diffLines(tokenizeLine(from), tokenizeLine(to)) {
+		switch op.Kind {
+		case diffEqual:
+			left = appendSegment(left, op.Text, false)
+			right = appendSegment(right, op.Text, false)
+		case diffDelete:
+			left = appendSegment(left, op.Text, true)
+		case diffInsert:
+			right = appendSegment(right, op.Text, true)
+		}
+	}
+	return left, right
+}
+
+// appendSegment 追加一段文本，与前一段状态相同时合并
+func appendSegment(segments []models.DiffSegment, text string, changed bool) []models.DiffSegment {
+	if n := len(segments); n > 0 && segments[n-1].Changed == changed {
+		segments[n-1].Text += text
+		return segments
+	}
+	return append(segments, models.DiffSegment{Text: text, Changed: changed})
+}
+
+// tokenizeLine 将一行拆分为单词（字母、数字、下划线）、连续空白和单个其他字符
+func tokenizeLine(line string) []string {
+	class := func(r rune) int {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			return 1
+		case unicode.IsSpace(r):
+			return 2
+		default:
+			return 0
+		}
+	}
+
+	var tokens []string
+	start := 0
+	prev := -1
+	for i, r := range line {
+		c := class(r)
+		if i > start && (c != prev || c == 0) {
+			tokens = append(tokens, line[start:i])
+			start = i
+		}
+		prev = c
+	}
+	if start < len(line) {
+		tokens = append(tokens, line[start:])
+	}
+	return tokens
+}
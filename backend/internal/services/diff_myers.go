@@ -0,0 +1,97 @@
+package services
+
+// maxDiffTraceCells Myers 算法回溯表的最大单元数，超出时退化为整体替换
+const maxDiffTraceCells = 4 << 20
+
+// myersDiff 使用 Myers O(ND) 算法计算差异
+func myersDiff(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	total := n + m
+	if total == 0 {
+		return nil
+	}
+
+	offset := total
+	v := make([]int, 2*total+2)
+	var trace [][]int
+
+	for d := 0; d <= total; d++ {
+		if (d+1)*len(v) > maxDiffTraceCells {
+			return replaceAll(a, b)
+		}
+		trace = append(trace, append([]int(nil), v...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrackDiff(trace, a, b, offset)
+			}
+		}
+	}
+	return replaceAll(a, b)
+}
+
+// backtrackDiff 根据 Myers 算法的回溯表还原编辑脚本
+func backtrackDiff(trace [][]int, a, b []string, offset int) []diffOp {
+	x, y := len(a), len(b)
+	var reversed []diffOp
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, diffOp{Kind: diffEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, diffOp{Kind: diffInsert, Text: b[y-1]})
+				y--
+			} else {
+				reversed = append(reversed, diffOp{Kind: diffDelete, Text: a[x-1]})
+				x--
+			}
+		}
+	}
+
+	ops := make([]diffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
+
+// replaceAll 差异过大时的退化结果：删除全部旧行再插入全部新行
+func replaceAll(a, b []string) []diffOp {
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a {
+		ops = append(ops, diffOp{Kind: diffDelete, Text: line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{Kind: diffInsert, Text: line})
+	}
+	return ops
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	from := "a\nb\nc\n"
+	to := "a\nB\nc\nd"
+	want := "--- old\n+++ new\n@@ -1,3 +1,4 @@\n a\n-b\n+B\n c\n+d\n\\ No newline at end of file\n"
+	if got := UnifiedDiff("old", "new", from, to); got != want {
+		t.Errorf("统一格式差异错误:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+	if got := UnifiedDiff("old", "new", from, from); got != "" {
+		t.Errorf("内容相同时应返回空字符串, got %q", got)
+	}
+}
+
+func TestComputeDiffSideBySide(t *testing.T) {
+	from := "[user]\n\tname = Alice\n\temail = alice@example.com\n"
+	to := "[user]\n\tname = Bob\n\temail = alice@example.com\n[core]\n"
+
+	result := ComputeDiff("current", "proposed", from, to)
+	if result.Identical || result.Added != 2 || result.Removed != 1 {
+		t.Fatalf("差异统计错误: %+v", result)
+	}
+	if len(result.Hunks) != 1 {
+		t.Fatalf("应只有一个变更块, got %d", len(result.Hunks))
+	}
+
+	rows := result.Hunks[0].Rows
+	kinds := []string{DiffRowEqual, DiffRowChange, DiffRowEqual, DiffRowInsert}
+	if len(rows) != len(kinds) {
+		t.Fatalf("行数错误: got %d want %d", len(rows), len(kinds))
+	}
+	for i, kind := range kinds {
+		if rows[i].Kind != kind {
+			t.Errorf("第 %d 行类型错误: got %s want %s", i, rows[i].Kind, kind)
+		}
+	}
+
+	change := rows[1]
+	if change.Left.Number != 2 || change.Right.Number != 2 {
+		t.Errorf("行号错误: left=%d right=%d", change.Left.Number, change.Right.Number)
+	}
+	wantLeft := []models.DiffSegment{{Text: "\tname = ", Changed: false}, {Text: "Alice", Changed: true}}
+	wantRight := []models.DiffSegment{{Text: "\tname = ", Changed: false}, {Text: "Bob", Changed: true}}
+	if !equalSegments(change.Left.Segments, wantLeft) || !equalSegments(change.Right.Segments, wantRight) {
+		t.Errorf("行内差异错误: left=%+v right=%+v", change.Left.Segments, change.Right.Segments)
+	}
+	if rows[3].Left != nil || rows[3].Right.Number != 4 || rows[3].Right.Text != "[core]" {
+		t.Errorf("新增行错误: %+v", rows[3])
+	}
+
+	if identical := ComputeDiff("a", "b", from, from); !identical.Identical || len(identical.Hunks) != 0 {
+		t.Errorf("内容相同时应标记为 identical: %+v", identical)
+	}
+}
+
+func equalSegments(a, b []models.DiffSegment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// maxDiscoveredFileSize 超过该大小的文件不视为配置文件
+const maxDiscoveredFileSize = 1 << 20
+
+// appSignature 已知应用的配置文件特征
+type appSignature struct {
+	ID          string
+	Application string
+	Path        string // 以 ~/ 开头为主目录相对路径，否则为 $XDG_CONFIG_HOME 相对路径
+	Category    string
+	Description string
+	Sensitive   string // 非空时表示文件常含凭据，内容为提示原因
+}
+
+// knownSignatures 已知应用的配置文件签名表
+var knownSignatures = []appSignature{
+	{ID: "bashrc", Application: "bash", Path: "~/.bashrc", Category: "shell", Description: "Bash shell 配置"},
+	{ID: "bash_profile", Application: "bash", Path: "~/.bash_profile", Category: "shell", Description: "Bash 登录 shell 配置"},
+	{ID: "bash_aliases", Application: "bash", Path: "~/.bash_aliases", Category: "shell", Description: "Bash 别名"},
+	{ID: "zshrc", Application: "zsh", Path: "~/.zshrc", Category: "shell", Description: "Zsh shell 配置"},
+	{ID: "zprofile", Application: "zsh", Path: "~/.zprofile", Category: "shell", Description: "Zsh 登录 shell 配置"},
+	{ID: "zshenv", Application: "zsh", Path: "~/.zshenv", Category: "shell", Description: "Zsh 环境变量"},
+	{ID: "profile", Application: "sh", Path: "~/.profile", Category: "shell", Description: "Shell 环境变量"},
+	{ID: "inputrc", Application: "readline", Path: "~/.inputrc", Category: "shell", Description: "Readline 按键配置"},
+	{ID: "fish", Application: "fish", Path: "fish/config.fish", Category: "shell", Description: "Fish shell 配置"},
+	{ID: "starship", Application: "starship", Path: "starship.toml", Category: "shell", Description: "Starship 提示符配置"},
+	{ID: "vimrc", Application: "vim", Path: "~/.vimrc", Category: "editor", Description: "Vim 编辑器配置"},
+	{ID: "nvim", Application: "neovim", Path: "nvim/init.lua", Category: "editor", Description: "Neovim 配置"},
+	{ID: "nvim-vim", Application: "neovim", Path: "nvim/init.vim", Category: "editor", Description: "Neovim 配置（Vimscript）"},
+	{ID: "helix", Application: "helix", Path: "helix/config.toml", Category: "editor", Description: "Helix 编辑器配置"},
+	{ID: "editorconfig", Application: "editorconfig", Path: "~/.editorconfig", Category: "editor", Description: "EditorConfig 全局配置"},
+	{ID: "gitconfig", Application: "git", Path: "~/.gitconfig", Category: "git", Description: "Git 全局配置"},
+	{ID: "git-xdg", Application: "git", Path: "git/config", Category: "git", Description: "Git 全局配置（XDG）"},
+	{ID: "gitignore-global", Application: "git", Path: "git/ignore", Category: "git", Description: "Git 全局忽略规则"},
+	{ID: "sshconfig", Application: "ssh", Path: "~/.ssh/config", Category: "ssh", Description: "SSH 客户端配置", Sensitive: "可能包含跳板机、代理命令和私钥路径等敏感信息"},
+	{ID: "tmux", Application: "tmux", Path: "~/.tmux.conf", Category: "app", Description: "tmux 终端复用器配置"},
+	{ID: "tmux-xdg", Application: "tmux", Path: "tmux/tmux.conf", Category: "app", Description: "tmux 终端复用器配置（XDG）"},
+	{ID: "kitty", Application: "kitty", Path: "kitty/kitty.conf", Category: "app", Description: "Kitty 终端配置"},
+	{ID: "alacritty", Application: "alacritty", Path: "alacritty/alacritty.toml", Category: "app", Description: "Alacritty 终端配置"},
+	{ID: "alacritty-yml", Application: "alacritty", Path: "alacritty/alacritty.yml", Category: "app", Description: "Alacritty 终端配置（旧格式）"},
+	{ID: "wezterm", Application: "wezterm", Path: "wezterm/wezterm.lua", Category: "app", Description: "WezTerm 终端配置"},
+	{ID: "foot", Application: "foot", Path: "foot/foot.ini", Category: "app", Description: "Foot 终端配置"},
+	{ID: "i3", Application: "i3", Path: "i3/config", Category: "system", Description: "i3 窗口管理器配置"},
+	{ID: "sway", Application: "sway", Path: "sway/config", Category: "system", Description: "Sway 窗口管理器配置"},
+	{ID: "hyprland", Application: "hyprland", Path: "hypr/hyprland.conf", Category: "system", Description: "Hyprland 窗口管理器配置"},
+	{ID: "xresources", Application: "xorg", Path: "~/.Xresources", Category: "system", Description: "X 资源配置"},
+	{ID: "xinitrc", Application: "xorg", Path: "~/.xinitrc", Category: "system", Description: "X 启动脚本"},
+	{ID: "npmrc", Application: "npm", Path: "~/.npmrc", Category: "app", Description: "npm 配置", Sensitive: "常含 _authToken 等仓库访问令牌"},
+	{ID: "curlrc", Application: "curl", Path: "~/.curlrc", Category: "app", Description: "curl 默认参数"},
+	{ID: "wgetrc", Application: "wget", Path: "~/.wgetrc", Category: "app", Description: "wget 默认参数"},
+}
+
+// ignoredDotfiles 主目录下不属于配置的常见点文件
+var ignoredDotfiles = []string{
+	".bash_history", ".zsh_history", ".python_history", ".node_repl_history", ".lesshst",
+	".viminfo", ".wget-hsts", ".Xauthority", ".ICEauthority", ".sudo_as_admin_successful",
+	".xsession-errors", ".zcompdump", ".DS_Store",
+}
+
+// sensitiveDotfiles 主目录下专门保存凭据的点文件
+var sensitiveDotfiles = map[string]string{
+	".netrc":           "保存各主机的登录密码",
+	".pgpass":          "保存 PostgreSQL 密码",
+	".git-credentials": "保存 Git 仓库访问令牌",
+	".pypirc":          "常含 PyPI 上传令牌",
+	".my.cnf":          "常含 MySQL 密码",
+}
+
+// credentialPattern 匹配配置内容中常见的凭据赋值
+var credentialPattern = regexp.MustCompile(`(?i)(_authtoken|password|passwd|secret|api[_-]?key|access[_-]?token)\s*[=:]`)
+
+// genericConfigNames 未知应用目录中可能是主配置文件的文件名，%s 会替换为目录名
+var genericConfigNames = []string{
+	"config", "config.toml", "config.yaml", "config.yml", "config.json", "config.ini",
+	"%s.conf", "%s.toml", "%s.yaml", "%s.yml", "%s.json", "%src", "init.lua",
+}
+
+// DiscoveryService 扫描主目录和 XDG 配置目录，发现尚未登记的配置文件
+type DiscoveryService struct {
+	configService *ConfigService
+}
+
+// NewDiscoveryService 创建新的发现服务实例
+func NewDiscoveryService(configService *ConfigService) *DiscoveryService {
+	return &DiscoveryService{
+		configService: configService,
+	}
+}
+
+// Discover 返回所有尚未登记的候选配置文件，已知应用排在前面
+func (s *DiscoveryService) Discover() ([]models.DiscoveredFile, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("无法获取用户主目录: %w", err)
+	}
+
+	registered, err := s.configService.registry.List()
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.configService.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	d := &discovery{
+		homeDir:    homeDir,
+		xdgDir:     xdgConfigHome(),
+		trackedIDs: make(map[string]bool),
+		seen:       make(map[string]bool),
+		categories: make(map[string]bool),
+	}
+	for _, file := range registered {
+		d.trackedIDs[file.ID] = true
+		if realPath, err := expandHome(file.Path); err == nil {
+			d.seen[realPath] = true
+		}
+	}
+	for _, category := range categories {
+		d.categories[category.ID] = true
+	}
+
+	d.scanSignatures()
+	d.scanHomeDotfiles()
+	d.scanXDGDirs()
+
+	sort.SliceStable(d.found, func(i, j int) bool {
+		return d.found[i].Known && !d.found[j].Known
+	})
+	return d.found, nil
+}
+
+// Adopt 将选中的候选文件登记到登记表中
+func (s *DiscoveryService) Adopt(req models.AdoptRequest) (*models.AdoptResult, error) {
+	candidates, err := s.Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]models.DiscoveredFile, len(candidates))
+	for _, candidate := range candidates {
+		byID[candidate.ID] = candidate
+	}
+
+	result := &models.AdoptResult{Adopted: []models.ConfigFile{}, Errors: []string{}}
+	for _, id := range req.IDs {
+		candidate, ok := byID[id]
+		if !ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("候选文件不存在或已登记: %s", id))
+			continue
+		}
+
+		category := candidate.Category
+		if override, ok := req.Categories[id]; ok {
+			category = override
+		}
+
+		file, err := s.configService.RegisterFile(models.RegisterFileRequest{
+			ID:          candidate.ID,
+			Name:        candidate.Name,
+			Path:        candidate.Path,
+			Category:    category,
+			Description: candidate.Description,
+		})
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("登记 %s 失败: %v", id, err))
+			continue
+		}
+		result.Adopted = append(result.Adopted, *file)
+	}
+
+	return result, nil
+}
+
+// discovery 单次扫描的状态
+type discovery struct {
+	homeDir    string
+	xdgDir     string
+	trackedIDs map[string]bool
+	seen       map[string]bool // 已登记或已发现的真实路径
+	categories map[string]bool
+	found      []models.DiscoveredFile
+}
+
+// scanSignatures 按签名表检查已知应用的配置文件
+func (d *discovery) scanSignatures() {
+	for _, sig := range knownSignatures {
+		realPath := filepath.Join(d.xdgDir, sig.Path)
+		if strings.HasPrefix(sig.Path, "~/") {
+			realPath = filepath.Join(d.homeDir, strings.TrimPrefix(sig.Path, "~/"))
+		}
+		d.add(realPath, sig.ID, sig.Application, sig.Category, sig.Description, sig.Sensitive, true)
+	}
+}
+
+// scanHomeDotfiles 检查主目录下未知的顶层点文件
+func (d *discovery) scanHomeDotfiles() {
+	entries, err := os.ReadDir(d.homeDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, ".") || entry.IsDir() || isIgnoredDotfile(name) {
+			continue
+		}
+		application := strings.TrimSuffix(strings.TrimPrefix(name, "."), "rc")
+		d.add(filepath.Join(d.homeDir, name), slugifyID(name), application, "app", "", sensitiveDotfiles[name], false)
+	}
+}
+
+// scanXDGDirs 检查 $XDG_CONFIG_HOME 下各应用目录中的主配置文件
+func (d *discovery) scanXDGDirs() {
+	entries, err := os.ReadDir(d.xdgDir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		dirName := entry.Name()
+		if !entry.IsDir() || dirName == appDirName || strings.HasPrefix(dirName, ".") {
+			continue
+		}
+		for _, pattern := range genericConfigNames {
+			fileName := pattern
+			if strings.Contains(pattern, "%s") {
+				fileName = fmt.Sprintf(pattern, dirName)
+			}
+			id := slugifyID(dirName)
+			if fileName != "config" {
+				id = slugifyID(dirName + "-" + fileName)
+			}
+			d.add(filepath.Join(d.xdgDir, dirName, fileName), id, dirName, "app", "", "", false)
+		}
+	}
+}
+
+// add 在文件存在且尚未登记时加入候选列表，sensitive 为签名表给出的凭据提示
+func (d *discovery) add(realPath, id, application, category, description, sensitive string, known bool) {
+	if d.seen[realPath] {
+		return
+	}
+
+	// 符号链接指向的目标也需要是普通文件
+	info, err := os.Stat(realPath)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > maxDiscoveredFileSize {
+		return
+	}
+	linfo, err := os.Lstat(realPath)
+	if err != nil {
+		return
+	}
+	d.seen[realPath] = true
+
+	if !d.categories[category] {
+		category = "app"
+	}
+	if description == "" {
+		description = fmt.Sprintf("%s 配置（自动发现）", application)
+	}
+
+	file := models.ConfigFile{
+		ID:           d.uniqueID(id),
+		Name:         filepath.Base(realPath),
+		Path:         collapseHome(realPath),
+		Category:     category,
+		Description:  description,
+		LastModified: info.ModTime(),
+		Size:         info.Size(),
+		IsSymlink:    linfo.Mode()&fs.ModeSymlink != 0,
+	}
+
+	if sensitive == "" && containsCredentials(realPath) {
+		sensitive = "内容中有类似密码或令牌的赋值"
+	}
+	warning := ""
+	if sensitive != "" {
+		warning = fmt.Sprintf("该文件可能包含凭据（%s），纳入管理后会进入历史、备份和导出包", sensitive)
+	}
+
+	d.found = append(d.found, models.DiscoveredFile{
+		ConfigFile:  file,
+		Application: application,
+		Known:       known,
+		Sensitive:   sensitive != "",
+		Warning:     warning,
+		Selected:    sensitive == "",
+	})
+}
+
+// containsCredentials 粗略检查文件内容中是否有凭据赋值
+func containsCredentials(realPath string) bool {
+	data, err := os.ReadFile(realPath)
+	if err != nil {
+		return false
+	}
+	return credentialPattern.Match(data)
+}
+
+// uniqueID 避免候选ID与已登记或已发现的ID重复
+func (d *discovery) uniqueID(id string) string {
+	if id == "" {
+		id = "file"
+	}
+	candidate := id
+	for n := 2; d.trackedIDs[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", id, n)
+	}
+	d.trackedIDs[candidate] = true
+	return candidate
+}
+
+// isIgnoredDotfile 判断点文件是否属于历史记录、缓存等非配置文件
+func isIgnoredDotfile(name string) bool {
+	for _, ignored := range ignoredDotfiles {
+		if name == ignored {
+			return true
+		}
+	}
+	lower := strings.ToLower(name)
+	return strings.Contains(lower, "history") || strings.HasSuffix(lower, ".lock") ||
+		strings.HasSuffix(lower, ".log") || strings.HasPrefix(lower, ".zcompdump")
+}
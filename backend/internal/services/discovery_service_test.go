@@ -0,0 +1,80 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestDiscoverAndAdopt(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	writeTestFile(t, filepath.Join(home, ".bashrc"), "export A=1\n")
+	writeTestFile(t, filepath.Join(home, ".tmux.conf"), "set -g mouse on\n")
+	writeTestFile(t, filepath.Join(home, ".bash_history"), "ls\n")
+	writeTestFile(t, filepath.Join(home, ".config", "kitty", "kitty.conf"), "font_size 12\n")
+	writeTestFile(t, filepath.Join(home, ".config", "foo", "foo.toml"), "a = 1\n")
+	writeTestFile(t, filepath.Join(home, ".npmrc"), "//registry.npmjs.org/:_authToken=abc\n")
+	writeTestFile(t, filepath.Join(home, ".config", "bar", "config"), "api_key = xyz\n")
+
+	service := NewDiscoveryService(NewConfigService())
+	candidates, err := service.Discover()
+	if err != nil {
+		t.Fatalf("扫描失败: %v", err)
+	}
+
+	byID := make(map[string]models.DiscoveredFile)
+	for _, c := range candidates {
+		byID[c.ID] = c
+	}
+	if _, ok := byID["bashrc"]; ok {
+		t.Errorf("已登记的 .bashrc 不应出现在候选列表中")
+	}
+	if _, ok := byID["bash_history"]; ok {
+		t.Errorf("历史记录文件不应出现在候选列表中")
+	}
+	if c, ok := byID["kitty"]; !ok || !c.Known || c.Path != "~/.config/kitty/kitty.conf" {
+		t.Errorf("未正确发现 kitty 配置: %+v", c)
+	}
+	if c, ok := byID["foo-foo.toml"]; !ok || c.Known {
+		t.Errorf("未通过通用规则发现 foo 配置: %+v", c)
+	}
+	if c := byID["kitty"]; c.Sensitive || !c.Selected {
+		t.Errorf("普通配置应默认选中: %+v", c)
+	}
+	// 可能包含凭据的文件给出警告且默认不选中
+	for _, id := range []string{"npmrc", "bar"} {
+		if c, ok := byID[id]; !ok || !c.Sensitive || c.Selected || c.Warning == "" {
+			t.Errorf("%s 应标记为可能包含凭据: %+v", id, c)
+		}
+	}
+
+	result, err := service.Adopt(models.AdoptRequest{IDs: []string{"kitty", "tmux", "missing"}})
+	if err != nil {
+		t.Fatalf("纳入管理失败: %v", err)
+	}
+	if len(result.Adopted) != 2 || len(result.Errors) != 1 {
+		t.Errorf("纳入结果错误: %+v", result)
+	}
+
+	candidates, _ = service.Discover()
+	for _, c := range candidates {
+		if c.ID == "kitty" || c.ID == "tmux" {
+			t.Errorf("已纳入管理的文件不应再次出现: %s", c.ID)
+		}
+	}
+}
+
+// writeTestFile 写入测试文件并创建父目录
+func writeTestFile(t testing.TB, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// 业务错误类别，处理器据此决定 HTTP 状态码
+var (
+	ErrNotFound      = errors.New("资源未找到")
+	ErrAlreadyExists = errors.New("资源已存在")
+	ErrInvalidInput  = errors.New("无效的输入")
+	ErrConflict      = errors.New("资源冲突")
+	ErrPrecondition  = errors.New("前置条件不满足")
+	ErrValidation    = errors.New("内容校验失败")
+	ErrTooLarge      = errors.New("内容过大")
+)
+
+// serviceError 携带错误类别的业务错误，Error() 只返回具体描述
+type serviceError struct {
+	kind error
+	msg  string
+}
+
+func (e *serviceError) Error() string { return e.msg }
+
+func (e *serviceError) Unwrap() error { return e.kind }
+
+// notFoundf 创建“未找到”类别的错误
+func notFoundf(format string, args ...interface{}) error {
+	return &serviceError{kind: ErrNotFound, msg: fmt.Sprintf(format, args...)}
+}
+
+// alreadyExistsf 创建“已存在”类别的错误
+func alreadyExistsf(format string, args ...interface{}) error {
+	return &serviceError{kind: ErrAlreadyExists, msg: fmt.Sprintf(format, args...)}
+}
+
+// conflictf 创建“冲突”类别的错误，用于当前状态不允许执行的操作
+func conflictf(format string, args ...interface{}) error {
+	return &serviceError{kind: ErrConflict, msg: fmt.Sprintf(format, args...)}
+}
+
+// tooLargef 创建“内容过大”类别的错误，用于超过大小限制的上传
+func tooLargef(format string, args ...interface{}) error {
+	return &serviceError{kind: ErrTooLarge, msg: fmt.Sprintf(format, args...)}
+}
+
+// invalidf 创建“无效输入”类别的错误
+func invalidf(format string, args ...interface{}) error {
+	return &serviceError{kind: ErrInvalidInput, msg: fmt.Sprintf(format, args...)}
+}
+
+// PreconditionError 表示文件在客户端读取之后已被修改（If-Match 不匹配）
+type PreconditionError struct {
+	Conflict *models.ConflictInfo
+}
+
+func (e *PreconditionError) Error() string {
+	return "文件已被其他程序修改: " + e.Conflict.Path
+}
+
+func (e *PreconditionError) Unwrap() error { return ErrPrecondition }
+
+// ValidationError 表示提交的内容未通过格式校验
+type ValidationError struct {
+	Result *models.ValidationResult
+}
+
+func (e *ValidationError) Error() string {
+	for _, d := range e.Result.Diagnostics {
+		if d.Severity == SeverityError {
+			if d.Line > 0 {
+				return fmt.Sprintf("内容未通过 %s 格式校验: 第 %d 行: %s", e.Result.Format, d.Line, d.Message)
+			}
+			return fmt.Sprintf("内容未通过 %s 格式校验: %s", e.Result.Format, d.Message)
+		}
+	}
+	return fmt.Sprintf("内容未通过 %s 格式校验", e.Result.Format)
+}
+
+func (e *ValidationError) Unwrap() error { return ErrValidation }
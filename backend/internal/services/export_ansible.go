@@ -0,0 +1,257 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// ansibleRoleName Ansible 角色的名称，也是导出包的根目录
+const ansibleRoleName = "linux_configs"
+
+// ansibleDefaults 角色的默认变量
+const ansibleDefaults = `---
+# 文件安装到的主目录，默认为远程用户的主目录
+linux_configs_home: "{{ ansible_facts['user_dir'] }}"
+
+# 覆盖已存在的文件前，在同一目录下创建带时间戳的备份
+linux_configs_backup: true
+
+# 目标已存在且不是符号链接时是否替换为符号链接（被替换的文件不会备份）
+linux_configs_force_links: false
+
+# 只安装这些文件ID，为空时安装全部
+linux_configs_files: []
+`
+
+// ansibleMeta 角色的 meta/main.yml
+const ansibleMeta = `---
+galaxy_info:
+  role_name: ` + ansibleRoleName + `
+  description: Linux 配置管理器导出的配置文件
+  min_ansible_version: "2.10"
+dependencies: []
+`
+
+// ansibleRole 生成 Ansible 角色：tasks/main.yml 按文件ID逐个复制 files/ 下的文件并保留权限，
+// 模板文件转换为 Jinja 模板放在 templates/ 下由 template 任务在远程渲染，data 提供档案和变量的默认值；
+// 覆盖前在远程备份已存在的目标；符号链接按原来的链接内容创建，主目录以外的文件以 become 安装
+func ansibleRole(items []exportItem, manifest *models.ExportManifest, data models.TemplateData) []dirBundleFile {
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.WriteString("# Linux 配置管理器导出的 Ansible 角色\n")
+	fmt.Fprintf(&b, "# 导出时间: %s\n", manifest.ExportedAt)
+	if len(manifest.Skipped) > 0 {
+		fmt.Fprintf(&b, "# 未导出（文件不存在或无法读取）: %s\n", scriptComment(strings.Join(manifest.Skipped, ", ")))
+	}
+
+	// copy 模块不创建上级目录，先创建主目录中用到的目录
+	dirSet := make(map[string]bool)
+	for _, item := range items {
+		if dir := path.Dir(strings.TrimPrefix(item.file.Path, "~/")); strings.HasPrefix(item.file.Path, "~/") && dir != "." {
+			dirSet[dir] = true
+		}
+	}
+	if len(dirSet) > 0 {
+		dirs := make([]string, 0, len(dirSet))
+		for dir := range dirSet {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		b.WriteString("\n- name: 创建主目录中的上级目录\n")
+		b.WriteString("  ansible.builtin.file:\n")
+		b.WriteString("    path: \"{{ linux_configs_home }}/{{ item }}\"\n")
+		b.WriteString("    state: directory\n")
+		b.WriteString("  loop:\n")
+		for _, dir := range dirs {
+			fmt.Fprintf(&b, "    - %s\n", yamlString(dir))
+		}
+		b.WriteString("  tags: [linux_configs]\n")
+	}
+
+	var files []dirBundleFile
+	usedVars := make(map[string]bool)
+	templated := false
+	for _, item := range items {
+		dest, src := yamlQuote(jinjaText(item.file.Path)), "root"+item.file.Path
+		if strings.HasPrefix(item.file.Path, "~/") {
+			homePath := strings.TrimPrefix(item.file.Path, "~/")
+			dest, src = yamlQuote("{{ linux_configs_home }}/"+jinjaText(homePath)), homePath
+		}
+
+		b.WriteString("\n")
+		if item.file.LinkTarget != "" {
+			fmt.Fprintf(&b, "- name: %s\n", yamlString(fmt.Sprintf("链接 %s -> %s", item.file.Path, item.file.LinkTarget)))
+			b.WriteString("  ansible.builtin.file:\n")
+			fmt.Fprintf(&b, "    src: %s\n", yamlString(item.file.LinkTarget))
+			fmt.Fprintf(&b, "    path: %s\n", dest)
+			b.WriteString("    state: link\n")
+			b.WriteString("    force: \"{{ linux_configs_force_links }}\"\n")
+		} else if source, ok := jinjaTemplate(item, usedVars); ok {
+			templated = true
+			files = append(files, dirBundleFile{name: "templates/" + src + ".j2", data: []byte(source), mode: item.mode.Perm()})
+			fmt.Fprintf(&b, "- name: %s\n", yamlString("渲染 "+item.file.Path))
+			b.WriteString("  ansible.builtin.template:\n")
+			fmt.Fprintf(&b, "    src: %s\n", yamlString(src+".j2"))
+			fmt.Fprintf(&b, "    dest: %s\n", dest)
+			fmt.Fprintf(&b, "    mode: \"%04o\"\n", item.mode.Perm())
+			b.WriteString("    backup: \"{{ linux_configs_backup }}\"\n")
+		} else {
+			files = append(files, dirBundleFile{name: "files/" + src, data: item.installContent(), mode: item.mode.Perm()})
+			if item.file.Template {
+				b.WriteString("# 模板使用了条件、函数等无法转换为 Jinja 的语法，安装导出时本机的渲染结果\n")
+			}
+			fmt.Fprintf(&b, "- name: %s\n", yamlString("安装 "+item.file.Path))
+			b.WriteString("  ansible.builtin.copy:\n")
+			fmt.Fprintf(&b, "    src: %s\n", yamlString(src))
+			fmt.Fprintf(&b, "    dest: %s\n", dest)
+			fmt.Fprintf(&b, "    mode: \"%04o\"\n", item.mode.Perm())
+			b.WriteString("    backup: \"{{ linux_configs_backup }}\"\n")
+		}
+		if !strings.HasPrefix(item.file.Path, "~/") {
+			b.WriteString("  become: true\n")
+		}
+		fmt.Fprintf(&b, "  when: linux_configs_files | length == 0 or %s in linux_configs_files\n", yamlString(item.file.ID))
+		fmt.Fprintf(&b, "  tags: [linux_configs, %s]\n", yamlString(item.file.Category))
+	}
+
+	defaults := ansibleDefaults
+	if templated {
+		defaults += ansibleTemplateDefaults(data, usedVars)
+	}
+	return append([]dirBundleFile{
+		{name: "tasks/main.yml", data: []byte(b.String()), mode: 0644},
+		{name: "defaults/main.yml", data: []byte(defaults), mode: 0644},
+		{name: "meta/main.yml", data: []byte(ansibleMeta), mode: 0644},
+	}, files...)
+}
+
+// ansibleTemplateDefaults 返回模板使用的档案和变量的默认值，只包含模板中引用的变量
+func ansibleTemplateDefaults(data models.TemplateData, usedVars map[string]bool) string {
+	var b strings.Builder
+	b.WriteString("\n# 模板中 {{ .Profile }} 和 {{ .Vars.<名称> }} 的值，默认为导出时本机的活动档案和变量\n")
+	fmt.Fprintf(&b, "linux_configs_profile: %s\n", yamlString(data.Profile))
+	if len(usedVars) == 0 {
+		b.WriteString("linux_configs_vars: {}\n")
+		return b.String()
+	}
+	names := make([]string, 0, len(usedVars))
+	for name := range usedVars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	b.WriteString("linux_configs_vars:\n")
+	for _, name := range names {
+		value, ok := data.Vars[name]
+		if !ok {
+			fmt.Fprintf(&b, "  # %s: 导出时未定义，需要在 inventory 中设置\n", name)
+			continue
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", name, yamlString(value))
+	}
+	return b.String()
+}
+
+// jinjaFields 模板字段对应的 Jinja 表达式，OS 与 SystemService 一样取 lsb_release -d 的描述
+var jinjaFields = map[string]string{
+	"Hostname": "ansible_facts['hostname']",
+	"OS":       "(ansible_facts['lsb'] | default({}))['description'] | default('Linux')",
+	"Kernel":   "ansible_facts['kernel']",
+	"User":     "ansible_facts['user_id']",
+	"HomeDir":  "linux_configs_home",
+	"Shell":    "ansible_facts['user_shell']",
+	"Profile":  "linux_configs_profile",
+}
+
+// jinjaTemplate 将模板文件的源文件转换为 Jinja 模板，只支持文本和直接引用字段的 {{ .Hostname }}、{{ .Vars.email }}，
+// 引用的变量名记录到 usedVars；不是模板文件或使用了条件、函数等其他语法时返回 false
+func jinjaTemplate(item exportItem, usedVars map[string]bool) (string, bool) {
+	if !item.file.Template || item.file.LinkTarget != "" {
+		return "", false
+	}
+	tmpl, err := template.New(item.file.ID).Funcs(templateFuncs).Parse(string(item.content))
+	if err != nil {
+		return "", false
+	}
+	if tmpl.Tree == nil {
+		return "", true
+	}
+
+	var b strings.Builder
+	vars := make(map[string]bool)
+	for _, node := range tmpl.Tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			b.WriteString(jinjaText(string(node.Text)))
+		case *parse.ActionNode:
+			expr, ok := jinjaField(node.Pipe, vars)
+			if !ok {
+				return "", false
+			}
+			b.WriteString("{{ " + expr + " }}")
+		default:
+			return "", false
+		}
+	}
+	for name := range vars {
+		usedVars[name] = true
+	}
+	return b.String(), true
+}
+
+// jinjaField 返回只引用一个字段的管道对应的 Jinja 表达式
+func jinjaField(pipe *parse.PipeNode, vars map[string]bool) (string, bool) {
+	if len(pipe.Decl) > 0 || len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	field, ok := pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok {
+		return "", false
+	}
+	switch {
+	case len(field.Ident) == 1 && jinjaFields[field.Ident[0]] != "":
+		return jinjaFields[field.Ident[0]], true
+	case len(field.Ident) == 2 && field.Ident[0] == "Vars":
+		vars[field.Ident[1]] = true
+		return "linux_configs_vars['" + field.Ident[1] + "']", true
+	}
+	return "", false
+}
+
+// jinjaText 转义文本中可能开始 Jinja 语法的 {：后面是 {、%、# 或位于末尾（可能与后面的表达式相连）时
+// 写为 {{ '{' }}，其余内容原样保留
+func jinjaText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '{' && (i+1 == len(s) || strings.IndexByte("{%#", s[i+1]) >= 0) {
+			b.WriteString("{{ '{' }}")
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// yamlString 将字符串写为 YAML 双引号字符串，含有 Jinja 语法时标记为 !unsafe，Ansible 不会对其求值
+func yamlString(s string) string {
+	if strings.Contains(s, "{{") || strings.Contains(s, "{%") || strings.Contains(s, "{#") {
+		return "!unsafe " + yamlQuote(s)
+	}
+	return yamlQuote(s)
+}
+
+// yamlQuote 将字符串写为 YAML 双引号字符串，JSON 字符串是合法的 YAML 双引号字符串
+func yamlQuote(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// nixBundleRoot home-manager 模块导出包的根目录
+const nixBundleRoot = "linux-configs-home-manager"
+
+// nixMaxTextSize 超过该大小的文件不内联到模块中，而是作为 files/ 下的文件引用
+const nixMaxTextSize = 64 << 10
+
+// homeManagerModule 生成 home-manager 模块：文本文件内联为 home.file.<path>.text，
+// 二进制或较大的文件放在 files/ 下用 .source 引用，符号链接用 mkOutOfStoreSymlink 指向原来的目标；
+// 仅所有者可读写的文件只留下注释，不把内容放入 Nix store
+func homeManagerModule(items []exportItem, manifest *models.ExportManifest) []dirBundleFile {
+	var b strings.Builder
+	b.WriteString("# Linux 配置管理器导出的 home-manager 模块\n")
+	fmt.Fprintf(&b, "# 导出时间: %s\n", manifest.ExportedAt)
+	fmt.Fprintf(&b, "# 在 home.nix 中导入: imports = [ ./%s ];\n", nixBundleRoot)
+	if len(manifest.Skipped) > 0 {
+		fmt.Fprintf(&b, "# 未导出（文件不存在或无法读取）: %s\n", scriptComment(strings.Join(manifest.Skipped, ", ")))
+	}
+	b.WriteString("{ config, ... }:\n\n{\n  home.file = {\n")
+
+	var files []dirBundleFile
+	for i, item := range items {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "    # %s: %s\n", scriptComment(item.file.ID), scriptComment(item.file.Path))
+		if !strings.HasPrefix(item.file.Path, "~/") {
+			b.WriteString("    # 不在主目录中，home.file 无法管理该文件\n")
+			continue
+		}
+		homePath := strings.TrimPrefix(item.file.Path, "~/")
+		mode := item.mode.Perm()
+		if item.file.LinkTarget == "" && mode&0077 == 0 {
+			// 内联或引用的内容都会复制到全局可读的 Nix store，仅所有者可读写的文件不导出内容
+			fmt.Fprintf(&b, "    # 原始权限 %04o，未导出：home.file 会把内容放入全局可读的 Nix store，请改用 sops-nix 或 agenix 等方案管理\n", mode)
+			continue
+		}
+
+		fmt.Fprintf(&b, "    %s = {\n", nixString(homePath))
+		if item.file.LinkTarget != "" {
+			fmt.Fprintf(&b, "      source = config.lib.file.mkOutOfStoreSymlink %s;\n", nixLinkTarget(homePath, item.file.LinkTarget))
+			b.WriteString("    };\n")
+			continue
+		}
+
+		if mode&0022 != 0 {
+			fmt.Fprintf(&b, "      # 原始权限 %04o：Nix store 中的文件只读，无法保留写权限\n", mode)
+		}
+		content := item.installContent()
+		if nixInlineText(content) {
+			fmt.Fprintf(&b, "      text = %s;\n", nixString(string(content)))
+		} else {
+			name := "files/" + homePath
+			files = append(files, dirBundleFile{name: name, data: content, mode: mode})
+			fmt.Fprintf(&b, "      source = ./files + %s;\n", nixString("/"+homePath))
+		}
+		if mode&0111 != 0 {
+			b.WriteString("      executable = true;\n")
+		}
+		b.WriteString("    };\n")
+	}
+	b.WriteString("  };\n}\n")
+
+	return append([]dirBundleFile{{name: "default.nix", data: []byte(b.String()), mode: 0644}}, files...)
+}
+
+// nixInlineText 判断文件能否作为字符串内联到模块中：合法的 UTF-8、不含 NUL 且不太大
+func nixInlineText(content []byte) bool {
+	return len(content) <= nixMaxTextSize && utf8.Valid(content) && !strings.ContainsRune(string(content), 0)
+}
+
+// nixString 将字符串写为 Nix 双引号字符串
+func nixString(s string) string {
+	return `"` + nixEscape(s) + `"`
+}
+
+// nixEscape 转义 Nix 双引号字符串中的 \、"、${ 和回车，换行原样保留
+func nixEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`, "\r", `\r`).Replace(s)
+}
+
+// nixLinkTarget 返回符号链接目标的 Nix 表达式，主目录中的目标相对于 config.home.homeDirectory
+func nixLinkTarget(homePath, linkTarget string) string {
+	target := collapseHome(linkTarget)
+	if !path.IsAbs(linkTarget) {
+		target = "~/" + path.Join(path.Dir(homePath), linkTarget)
+	}
+	if strings.HasPrefix(target, "~/") {
+		return `"${config.home.homeDirectory}/` + nixEscape(strings.TrimPrefix(target, "~/")) + `"`
+	}
+	return nixString(target)
+}
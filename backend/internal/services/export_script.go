@@ -0,0 +1,155 @@
+package services
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// scriptLineWidth 安装脚本中 base64 内容每行的字符数，openssl base64 -d 要求每行不超过 76 个字符
+const scriptLineWidth = 76
+
+// scriptHeredocEnd 嵌入文件内容的 here-document 结束标记，base64 字符集中没有下划线，不会与内容冲突
+const scriptHeredocEnd = "LCM_EOF"
+
+// scriptPrelude 安装脚本的公共部分：解码、备份、安装文件和符号链接的函数
+const scriptPrelude = `set -eu
+
+umask 022
+BACKUP_DIR="${LCM_BACKUP_DIR:-$HOME/.local/share/linux-config-manager/script-backups/$(date +%Y%m%d-%H%M%S)}"
+installed=0
+unchanged=0
+backed_up=0
+
+# decode 解码标准输入中的 base64 内容
+decode() {
+	if command -v base64 >/dev/null 2>&1; then
+		base64 -d
+	else
+		openssl base64 -d
+	fi
+}
+
+# backup 将已存在的目标移动到备份目录，保持相对于主目录的路径
+backup() {
+	if [ ! -e "$1" ] && [ ! -L "$1" ]; then
+		return 0
+	fi
+	if [ -d "$1" ] && [ ! -L "$1" ]; then
+		echo "错误: $1 是目录，无法替换" >&2
+		exit 1
+	fi
+	case "$1" in
+	"$HOME"/*) dest="$BACKUP_DIR/${1#"$HOME"/}" ;;
+	*) dest="$BACKUP_DIR/root$1" ;;
+	esac
+	mkdir -p "$(dirname "$dest")"
+	mv -f "$1" "$dest"
+	backed_up=$((backed_up + 1))
+	echo "备份 $1 -> $dest"
+}
+
+# install_file 从标准输入读取文件内容，内容相同时只恢复权限，否则备份后替换
+# 参数：目标路径 权限 修改时间（touch -t 格式，UTC）
+install_file() {
+	mkdir -p "$(dirname "$1")"
+	tmp="$1.lcm-tmp.$$"
+	decode >"$tmp"
+	if [ -f "$1" ] && [ ! -L "$1" ] && cmp -s "$tmp" "$1"; then
+		rm -f "$tmp"
+		chmod "$2" "$1"
+		unchanged=$((unchanged + 1))
+		echo "相同 $1"
+		return 0
+	fi
+	chmod "$2" "$tmp"
+	TZ=UTC0 touch -t "$3" "$tmp"
+	backup "$1"
+	mv -f "$tmp" "$1"
+	installed=$((installed + 1))
+	echo "安装 $1"
+}
+
+# install_link 创建符号链接，链接内容相同时不做处理
+# 参数：目标路径 链接内容
+install_link() {
+	if [ -L "$1" ] && [ "$(readlink "$1")" = "$2" ]; then
+		unchanged=$((unchanged + 1))
+		echo "相同 $1 -> $2"
+		return 0
+	fi
+	mkdir -p "$(dirname "$1")"
+	backup "$1"
+	ln -s "$2" "$1"
+	installed=$((installed + 1))
+	echo "安装 $1 -> $2"
+}
+
+`
+
+// writeScript 将文件写入自包含的 POSIX sh 安装脚本：文件内容以 base64 嵌入，
+// 运行时备份已存在的目标，恢复权限、修改时间和符号链接，最后输出汇总
+func writeScript(w io.Writer, items []exportItem, manifest *models.ExportManifest) error {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Linux 配置管理器导出的安装脚本，在目标机器上运行: sh linux-configs-install.sh\n")
+	b.WriteString("# 已存在的文件先备份到 $LCM_BACKUP_DIR（默认为 ~/.local/share/linux-config-manager/script-backups/<时间>）\n")
+	fmt.Fprintf(&b, "# 导出时间: %s\n", manifest.ExportedAt)
+	fmt.Fprintf(&b, "# 文件数量: %d\n", manifest.TotalFiles)
+	if len(manifest.Skipped) > 0 {
+		fmt.Fprintf(&b, "# 未导出（文件不存在或无法读取）: %s\n", scriptComment(strings.Join(manifest.Skipped, ", ")))
+	}
+	b.WriteString("\n")
+	b.WriteString(scriptPrelude)
+
+	for _, item := range items {
+		target := scriptPath(item.file.Path)
+		fmt.Fprintf(&b, "# %s: %s\n", scriptComment(item.file.ID), scriptComment(item.file.Path))
+		if item.file.LinkTarget != "" {
+			fmt.Fprintf(&b, "install_link %s %s\n\n", target, shellQuote(item.file.LinkTarget))
+			continue
+		}
+
+		fmt.Fprintf(&b, "install_file %s %04o %s <<'%s'\n", target, item.mode.Perm(), item.modTime.UTC().Format("200601021504.05"), scriptHeredocEnd)
+		encoded := base64.StdEncoding.EncodeToString(item.installContent())
+		for len(encoded) > scriptLineWidth {
+			b.WriteString(encoded[:scriptLineWidth])
+			b.WriteString("\n")
+			encoded = encoded[scriptLineWidth:]
+		}
+		if encoded != "" {
+			b.WriteString(encoded)
+			b.WriteString("\n")
+		}
+		b.WriteString(scriptHeredocEnd + "\n\n")
+	}
+
+	b.WriteString(`echo "完成：安装 $installed 个，内容相同 $unchanged 个，备份 $backed_up 个"` + "\n")
+	b.WriteString(`if [ "$backed_up" -gt 0 ]; then` + "\n")
+	b.WriteString(`	echo "备份目录: $BACKUP_DIR"` + "\n")
+	b.WriteString("fi\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// scriptPath 将登记的路径转换为 shell 中的路径表达式，~/ 开头的路径相对于运行时的 $HOME
+func scriptPath(filePath string) string {
+	if strings.HasPrefix(filePath, "~/") {
+		return `"$HOME"/` + shellQuote(strings.TrimPrefix(filePath, "~/"))
+	}
+	return shellQuote(filePath)
+}
+
+// shellQuote 用单引号引用字符串，内容中的单引号先结束引用再转义
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// scriptComment 去掉写入脚本注释的内容中的换行
+func scriptComment(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
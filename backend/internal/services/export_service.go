@@ -0,0 +1,502 @@
+package services
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// 导出压缩包的格式
+const (
+	ExportFormatZip     = "zip"     // 只包含文件内容，符号链接导出为目标文件的内容
+	ExportFormatTarGz   = "tar.gz"  // 保留权限、修改时间和符号链接
+	ExportFormatScript  = "script"  // 自包含的 POSIX sh 安装脚本，用于没有运行本服务的机器
+	ExportFormatNix     = "nix"     // home-manager 模块目录，打包为 tar.gz
+	ExportFormatAnsible = "ansible" // Ansible 角色目录，打包为 tar.gz
+)
+
+// ExportService 将登记的配置文件打包导出
+type ExportService struct {
+	configService  *ConfigService
+	signingService *SigningService
+}
+
+// NewExportService 创建新的导出服务实例
+func NewExportService(configService *ConfigService, signingService *SigningService) *ExportService {
+	return &ExportService{
+		configService:  configService,
+		signingService: signingService,
+	}
+}
+
+// exportItem 导出时收集的单个文件
+type exportItem struct {
+	file     models.ManifestFile
+	content  []byte
+	mode     fs.FileMode
+	modTime  time.Time
+	rendered []byte // 模板文件在本机渲染后写入真实路径的内容，其他文件为 nil
+}
+
+// installContent 返回安装到目标机器的内容：模板文件为渲染结果，其他文件为原内容
+func (item exportItem) installContent() []byte {
+	if item.file.Template {
+		return item.rendered
+	}
+	return item.content
+}
+
+// ExportOptions 控制导出的格式、加密和导出哪些文件
+type ExportOptions struct {
+	Format     string                 // 导出格式（ExportFormatZip 等），为空时使用 zip
+	Passphrase string                 // 非空时用该口令加密整个压缩包
+	Selection  models.ExportSelection // 为空时导出所有文件
+}
+
+// Export 按指定格式将选中的可读取配置文件和配置清单写入 w
+func (s *ExportService) Export(w io.Writer, opts ExportOptions) error {
+	format := opts.Format
+	if format == "" {
+		format = ExportFormatZip
+	}
+	switch format {
+	case ExportFormatZip, ExportFormatTarGz:
+	case ExportFormatScript, ExportFormatNix, ExportFormatAnsible:
+		if opts.Passphrase != "" {
+			return invalidf("%s 格式不支持加密", format)
+		}
+	default:
+		return invalidf("不支持的导出格式: %s（可选 %s、%s、%s、%s、%s）", format, ExportFormatZip, ExportFormatTarGz, ExportFormatScript, ExportFormatNix, ExportFormatAnsible)
+	}
+
+	// 安装脚本、home-manager 模块和 Ansible 角色安装模板文件的渲染结果，压缩包只需要模板源文件
+	installed := format == ExportFormatScript || format == ExportFormatNix || format == ExportFormatAnsible
+	items, manifest, err := s.collect(opts.Selection, installed)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case ExportFormatScript:
+		return writeScript(w, items, manifest)
+	case ExportFormatNix:
+		return writeDirBundle(w, nixBundleRoot, homeManagerModule(items, manifest))
+	case ExportFormatAnsible:
+		// 模板文件转换为 Jinja 模板，档案和变量的默认值取自本机
+		data, err := s.configService.templates.MachineData()
+		if err != nil {
+			return err
+		}
+		return writeDirBundle(w, ansibleRoleName, ansibleRole(items, manifest, data))
+	}
+
+	// 加密时清单中也记录密钥派生参数，解密后可以确认导出包的加密方式
+	var encryption *models.EncryptionInfo
+	if opts.Passphrase != "" {
+		if encryption, err = newEncryptionInfo(format); err != nil {
+			return err
+		}
+		manifest.Encryption = encryption
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// 签名覆盖配置清单的原始字节，清单中的 sha256 再覆盖每个条目的内容
+	signature, err := s.signingService.Sign(manifestData)
+	if err != nil {
+		return err
+	}
+	signatureData, err := json.MarshalIndent(signature, "", "  ")
+	if err != nil {
+		return err
+	}
+	bundleFiles := []bundleFile{{name: manifestName, data: manifestData}, {name: manifestSignatureName, data: signatureData}}
+
+	if encryption == nil {
+		return writeArchive(w, format, items, bundleFiles)
+	}
+	var plaintext bytes.Buffer
+	if err := writeArchive(&plaintext, format, items, bundleFiles); err != nil {
+		return err
+	}
+	return sealBundle(w, plaintext.Bytes(), opts.Passphrase, *encryption)
+}
+
+// bundleFile 压缩包根目录下的配置清单及其签名
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// dirBundleFile 目录形式的导出包中的单个文件
+type dirBundleFile struct {
+	name string // 相对于导出包根目录的路径
+	data []byte
+	mode fs.FileMode
+}
+
+// writeArchive 按格式写入压缩包
+func writeArchive(w io.Writer, format string, items []exportItem, bundleFiles []bundleFile) error {
+	if format == ExportFormatTarGz {
+		return writeTarGz(w, items, bundleFiles)
+	}
+	return writeZip(w, items, bundleFiles)
+}
+
+// collect 读取选中配置文件的内容和元数据，并生成只包含这些文件的配置清单
+// withRendered 为 true 时同时读取模板文件在真实路径上的渲染结果，读取失败的模板文件记为跳过
+func (s *ExportService) collect(selection models.ExportSelection, withRendered bool) ([]exportItem, *models.ExportManifest, error) {
+	registered, err := s.configService.registry.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取配置文件失败: %w", err)
+	}
+	categories, err := s.configService.GetCategories()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取分类失败: %w", err)
+	}
+	// 按档案导出时部署档案中包含的分类和文件，档案没有限定时与不筛选相同
+	filter := selection
+	if selection.Profile != "" {
+		profile, err := s.configService.GetProfile(selection.Profile)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter.Categories = append(append([]string{}, selection.Categories...), profile.Categories...)
+		filter.IDs = append(append([]string{}, selection.IDs...), profile.Files...)
+	}
+	selected, err := selectExportFiles(registered, categories, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	// GetFiles 只返回磁盘上存在的文件，并补充修改时间、大小等运行时信息
+	files, err := s.configService.GetFiles()
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取配置文件失败: %w", err)
+	}
+	existing := make(map[string]models.ConfigFile, len(files))
+	for _, file := range files {
+		existing[file.ID] = file
+	}
+
+	categoryNames := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryNames[category.ID] = category.Name
+	}
+
+	items := []exportItem{}
+	skipped := []string{}
+	usedPaths := make(map[string]bool)
+	for _, selectedFile := range selected {
+		// 跳过不存在或无法读取的文件，选择性导出时在清单中记录
+		file, ok := existing[selectedFile.ID]
+		// 模板文件尚未渲染到真实路径时，压缩包仍导出模板源文件，权限和修改时间取自源文件
+		statPath := ""
+		if !ok && selectedFile.Template && !withRendered {
+			file, ok = selectedFile, true
+			statPath = s.configService.templates.SourcePath(file.ID)
+		}
+		if !ok {
+			skipped = append(skipped, selectedFile.ID)
+			continue
+		}
+		fileWithContent, err := s.configService.GetFileByID(file.ID)
+		if err != nil {
+			skipped = append(skipped, file.ID)
+			continue
+		}
+		realPath, err := expandHome(file.Path)
+		if err != nil {
+			skipped = append(skipped, file.ID)
+			continue
+		}
+		if statPath == "" {
+			statPath = realPath
+		}
+		mode, modTime, linkTarget, err := statExportFile(statPath)
+		if err != nil {
+			skipped = append(skipped, file.ID)
+			continue
+		}
+
+		// 构建文件路径：分类名/文件名
+		categoryName, ok := categoryNames[file.Category]
+		if !ok {
+			categoryName = "其他"
+		}
+		fileName := strings.ReplaceAll(file.Name, "/", "_")
+		archivePath := categoryName + "/" + fileName
+		// 同一分类下文件名重复时加上文件ID，保证每个条目都能在清单中唯一对应
+		if usedPaths[archivePath] {
+			archivePath = categoryName + "/" + file.ID + "_" + fileName
+		}
+		usedPaths[archivePath] = true
+
+		// 压缩包保存模板源文件，安装脚本等格式直接安装真实路径上的渲染结果
+		var rendered []byte
+		if file.Template && withRendered {
+			if rendered, err = os.ReadFile(realPath); err != nil {
+				skipped = append(skipped, file.ID)
+				continue
+			}
+		}
+
+		// 清单中记录导出时内容的 ETag，导入时据此判断本地是否有更新的修改
+		file.ETag = fileWithContent.ETag
+		content := []byte(fileWithContent.Content)
+		sum := sha256.Sum256(content)
+		items = append(items, exportItem{
+			file: models.ManifestFile{
+				ConfigFile:  file,
+				ArchivePath: archivePath,
+				Mode:        fmt.Sprintf("%04o", mode.Perm()),
+				ModTime:     &modTime,
+				LinkTarget:  linkTarget,
+				SHA256:      hex.EncodeToString(sum[:]),
+			},
+			content:  content,
+			mode:     mode,
+			modTime:  modTime,
+			rendered: rendered,
+		})
+	}
+
+	if len(items) == 0 && !selection.IsEmpty() {
+		return nil, nil, invalidf("没有符合筛选条件的可导出配置文件")
+	}
+
+	now := time.Now()
+	manifest := &models.ExportManifest{
+		ExportTime: now.Format("2006-01-02 15:04:05"),
+		ExportedAt: now.Format(time.RFC3339),
+		TotalFiles: len(items),
+		Categories: categories,
+		Files:      make([]models.ManifestFile, 0, len(items)),
+	}
+	for _, item := range items {
+		manifest.Files = append(manifest.Files, item.file)
+	}
+	if !selection.IsEmpty() {
+		// 清单只列出实际导出的文件所属的分类
+		used := make(map[string]bool, len(items))
+		for _, item := range items {
+			used[item.file.Category] = true
+		}
+		manifest.Categories = []models.ConfigCategory{}
+		for _, category := range categories {
+			if used[category.ID] {
+				manifest.Categories = append(manifest.Categories, category)
+			}
+		}
+		manifest.Selection = &selection
+		manifest.Skipped = skipped
+	}
+	return items, manifest, nil
+}
+
+// selectExportFiles 按筛选条件选出要导出的文件，条件中引用了不存在的分类或文件ID时返回错误
+func selectExportFiles(files []models.ConfigFile, categories []models.ConfigCategory, selection models.ExportSelection) ([]models.ConfigFile, error) {
+	knownCategories := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		knownCategories[category.ID] = true
+	}
+	knownFiles := make(map[string]bool, len(files))
+	for _, file := range files {
+		knownFiles[file.ID] = true
+	}
+	for _, category := range selection.Categories {
+		if !knownCategories[category] {
+			return nil, invalidf("分类不存在: %s", category)
+		}
+	}
+	for _, id := range append(append([]string{}, selection.IDs...), selection.Exclude...) {
+		if !knownFiles[id] {
+			return nil, invalidf("文件未登记: %s", id)
+		}
+	}
+
+	includeAll := len(selection.Categories) == 0 && len(selection.IDs) == 0 && len(selection.Tags) == 0
+	inCategories := stringSet(selection.Categories)
+	inIDs := stringSet(selection.IDs)
+	inTags := stringSet(selection.Tags)
+	excluded := stringSet(selection.Exclude)
+
+	selected := []models.ConfigFile{}
+	for _, file := range files {
+		if excluded[file.ID] {
+			continue
+		}
+		match := includeAll || inCategories[file.Category] || inIDs[file.ID]
+		for _, tag := range file.Tags {
+			match = match || inTags[tag]
+		}
+		if match {
+			selected = append(selected, file)
+		}
+	}
+	return selected, nil
+}
+
+// stringSet 将字符串切片转换为集合
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// statExportFile 读取文件本身的权限和修改时间，文件是符号链接时返回链接内容和目标文件的权限
+func statExportFile(realPath string) (fs.FileMode, time.Time, string, error) {
+	info, err := os.Lstat(realPath)
+	if err != nil {
+		return 0, time.Time{}, "", err
+	}
+	if info.Mode()&fs.ModeSymlink == 0 {
+		return info.Mode(), info.ModTime(), "", nil
+	}
+
+	link, err := os.Readlink(realPath)
+	if err != nil {
+		return 0, time.Time{}, "", err
+	}
+	// zip 中保存的是链接目标的内容，清单中的权限以目标文件为准
+	mode := info.Mode()
+	if target, err := os.Stat(realPath); err == nil {
+		mode = target.Mode()
+	}
+	return mode, info.ModTime(), link, nil
+}
+
+// writeZip 将文件内容、配置清单及其签名写入 ZIP 压缩包
+func writeZip(w io.Writer, items []exportItem, bundleFiles []bundleFile) error {
+	zipWriter := zip.NewWriter(w)
+	for _, item := range items {
+		fileWriter, err := zipWriter.Create(item.file.ArchivePath)
+		if err != nil {
+			return err
+		}
+		if _, err := fileWriter.Write(item.content); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range bundleFiles {
+		fileWriter, err := zipWriter.Create(file.name)
+		if err != nil {
+			return err
+		}
+		if _, err := fileWriter.Write(file.data); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// writeTarGz 将文件写入 tar.gz 压缩包，保留权限和修改时间，符号链接保存为链接条目
+func writeTarGz(w io.Writer, items []exportItem, bundleFiles []bundleFile) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, item := range items {
+		header := &tar.Header{
+			Name:    item.file.ArchivePath,
+			Mode:    int64(item.mode.Perm()),
+			ModTime: item.modTime,
+			Format:  tar.FormatPAX,
+		}
+		if item.file.LinkTarget != "" {
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = item.file.LinkTarget
+			header.Mode = 0777
+		} else {
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(item.content))
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tarWriter.Write(item.content); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, file := range bundleFiles {
+		header := &tar.Header{
+			Name:     file.name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(file.data)),
+			ModTime:  time.Now(),
+			Format:   tar.FormatPAX,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tarWriter.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// writeDirBundle 将目录形式的导出包写入 tar.gz，所有文件位于 root 目录下
+func writeDirBundle(w io.Writer, root string, files []dirBundleFile) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	now := time.Now()
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		// 依次写入尚未写入的上级目录
+		name := path.Join(root, file.name)
+		var parents []string
+		for dir := path.Dir(name); dir != "." && !dirs[dir]; dir = path.Dir(dir) {
+			parents = append(parents, dir)
+			dirs[dir] = true
+		}
+		for i := len(parents) - 1; i >= 0; i-- {
+			header := &tar.Header{Name: parents[i] + "/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: now, Format: tar.FormatPAX}
+			if err := tarWriter.WriteHeader(header); err != nil {
+				return err
+			}
+		}
+
+		header := &tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     int64(file.mode.Perm()),
+			Size:     int64(len(file.data)),
+			ModTime:  now,
+			Format:   tar.FormatPAX,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tarWriter.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
@@ -0,0 +1,417 @@
+package services
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestExportTarGzRoundTrip(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	sshConfig := filepath.Join(home, ".ssh", "config")
+	writeTestFile(t, sshConfig, "Host example\n  User alice\n")
+	if err := os.Chmod(sshConfig, 0600); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(sshConfig, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(home, "dotfiles", "bashrc"), "echo dotfiles\n")
+	if err := os.Symlink("dotfiles/bashrc", filepath.Join(home, ".bashrc")); err != nil {
+		t.Fatal(err)
+	}
+	// 指向主目录之外的符号链接
+	outside := filepath.Join(t.TempDir(), "profile")
+	writeTestFile(t, outside, "export A=1\n")
+	if err := os.Symlink(outside, filepath.Join(home, ".profile")); err != nil {
+		t.Fatal(err)
+	}
+
+	configService := NewConfigService()
+	var buf bytes.Buffer
+	if err := NewExportService(configService, NewSigningService()).Export(&buf, ExportOptions{Format: ExportFormatTarGz}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+
+	// 删除原文件后导入，应按压缩包恢复权限、修改时间和符号链接
+	os.Remove(sshConfig)
+	os.Remove(filepath.Join(home, ".bashrc"))
+	os.Remove(filepath.Join(home, ".profile"))
+
+	// 本机签名的导出包受信任，指向主目录之外的符号链接只给出警告
+	archive := bytes.NewReader(buf.Bytes())
+	result, err := NewImportService(configService, NewSigningService()).Import(archive, archive.Size(), ImportOptions{CreateMissing: true})
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if result.ImportedFiles != 3 || len(result.Errors) != 0 || len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], outside) {
+		t.Fatalf("导入结果错误: %+v", result)
+	}
+	if revisions, err := configService.GetFileHistory("bashrc"); err != nil || len(revisions) != 1 {
+		t.Errorf("替换为符号链接应记录历史版本: %+v %v", revisions, err)
+	}
+
+	info, err := os.Stat(sshConfig)
+	if err != nil {
+		t.Fatalf("未恢复 ssh 配置: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("权限未恢复: got %v", info.Mode().Perm())
+	}
+	if !info.ModTime().Equal(modTime) {
+		t.Errorf("修改时间未恢复: got %v want %v", info.ModTime(), modTime)
+	}
+	if link, err := os.Readlink(filepath.Join(home, ".bashrc")); err != nil || link != "dotfiles/bashrc" {
+		t.Errorf("符号链接未恢复: %q %v", link, err)
+	}
+
+	if err := NewExportService(configService, NewSigningService()).Export(&buf, ExportOptions{Format: "rar"}); err == nil {
+		t.Errorf("不支持的格式应返回错误")
+	}
+
+	// 其他机器的密钥不受信任，指向主目录之外的符号链接需要预览计划后确认
+	other := testutil.SetupHome(t)
+	otherConfig := NewConfigService()
+	importService := NewImportService(otherConfig, NewSigningService())
+	result, err = importService.Import(archive, archive.Size(), ImportOptions{CreateMissing: true})
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if result.ImportedFiles != 2 || len(result.EntryErrors) != 1 || result.EntryErrors[0].Code != models.ArchiveErrReviewRequired {
+		t.Fatalf("导入结果错误: %+v", result)
+	}
+	if _, err := os.Lstat(filepath.Join(other, ".profile")); !os.IsNotExist(err) {
+		t.Errorf("未确认时不应创建指向主目录之外的符号链接: %v", err)
+	}
+	plan, err := importService.Plan(archive, archive.Size(), ImportOptions{CreateMissing: true})
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	for _, entry := range plan.Entries {
+		if entry.FileID == "profile" && !strings.Contains(entry.Warning, outside) {
+			t.Errorf("计划条目应列出主目录之外的链接目标: %+v", entry)
+		}
+	}
+	if _, err := importService.Apply(plan.ID, models.ApplyImportRequest{}, ""); err != nil {
+		t.Fatalf("执行导入计划失败: %v", err)
+	}
+	if link, err := os.Readlink(filepath.Join(other, ".profile")); err != nil || link != outside {
+		t.Errorf("确认后应创建符号链接: %q %v", link, err)
+	}
+}
+
+func TestExportSelection(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	writeTestFile(t, filepath.Join(home, ".bashrc"), "echo ok\n")
+	writeTestFile(t, filepath.Join(home, ".gitconfig"), "[user]\n\tname = Alice\n")
+	writeTestFile(t, filepath.Join(home, ".vimrc"), "set number\n")
+	writeTestFile(t, filepath.Join(home, ".ssh", "config"), "Host example\n")
+
+	configService := NewConfigService()
+	tags := []string{"share", " share ", ""}
+	if file, err := configService.UpdateFileMeta("sshconfig", models.UpdateFileMetaRequest{Tags: &tags}); err != nil || len(file.Tags) != 1 {
+		t.Fatalf("设置标签失败: %+v %v", file, err)
+	}
+	invalid := []string{"a,b"}
+	if _, err := configService.UpdateFileMeta("sshconfig", models.UpdateFileMetaRequest{Tags: &invalid}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("包含逗号的标签应返回 ErrInvalidInput, got %v", err)
+	}
+
+	exportService := NewExportService(configService, NewSigningService())
+	exportManifest := func(selection models.ExportSelection) (*importManifest, []archiveEntry) {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := exportService.Export(&buf, ExportOptions{Selection: selection}); err != nil {
+			t.Fatalf("导出失败: %v", err)
+		}
+		entries, err := readArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()), defaultArchiveLimits)
+		if err != nil {
+			t.Fatalf("读取导出包失败: %v", err)
+		}
+		return readImportManifest(entries), entries
+	}
+	fileIDs := func(manifest *importManifest) []string {
+		ids := []string{}
+		for _, file := range manifest.Files {
+			ids = append(ids, file.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	// 分类、文件ID和标签取并集，exclude 总是排除
+	manifest, entries := exportManifest(models.ExportSelection{Categories: []string{"git", "editor"}, Tags: []string{"share"}, Exclude: []string{"vimrc"}})
+	if got := fileIDs(manifest); !reflect.DeepEqual(got, []string{"gitconfig", "sshconfig"}) {
+		t.Errorf("导出的文件错误: %v", got)
+	}
+	if manifest.TotalFiles != 2 || len(entries) != 4 || manifest.Selection == nil || len(manifest.Selection.Exclude) != 1 {
+		t.Errorf("清单应只反映导出的文件: %+v, %d 个条目", manifest.ExportManifest, len(entries))
+	}
+	if len(manifest.Categories) != 2 {
+		t.Errorf("清单应只包含用到的分类: %+v", manifest.Categories)
+	}
+
+	// 选中但磁盘上不存在的文件记录在 skipped 中
+	manifest, _ = exportManifest(models.ExportSelection{IDs: []string{"bashrc", "zshrc"}})
+	if got := fileIDs(manifest); !reflect.DeepEqual(got, []string{"bashrc"}) || !reflect.DeepEqual(manifest.Skipped, []string{"zshrc"}) {
+		t.Errorf("导出结果错误: files=%v skipped=%v", got, manifest.Skipped)
+	}
+
+	manifest, _ = exportManifest(models.ExportSelection{})
+	if manifest.TotalFiles != 4 || manifest.Selection != nil || len(manifest.Categories) != len(defaultCategories) {
+		t.Errorf("没有筛选条件时应导出所有文件: %+v", manifest.ExportManifest)
+	}
+
+	for _, selection := range []models.ExportSelection{
+		{Categories: []string{"nope"}},
+		{IDs: []string{"nope"}},
+		{Tags: []string{"nope"}},
+		{Exclude: []string{"bashrc", "gitconfig", "vimrc", "sshconfig"}},
+	} {
+		if err := exportService.Export(io.Discard, ExportOptions{Selection: selection}); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("筛选条件 %+v 应返回 ErrInvalidInput, got %v", selection, err)
+		}
+	}
+}
+
+func TestExportScript(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("没有 sh，跳过安装脚本测试")
+	}
+	home := testutil.SetupHome(t)
+
+	// 内容包含 shell 特殊字符且没有结尾换行，安装后应逐字节一致
+	profile := "export PATH=\"$HOME/bin:$PATH\"\necho 'it''s' `date` \\\nLCM_EOF"
+	writeTestFile(t, filepath.Join(home, ".profile"), profile)
+	sshConfig := filepath.Join(home, ".ssh", "config")
+	writeTestFile(t, sshConfig, "Host example\n")
+	if err := os.Chmod(sshConfig, 0600); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(sshConfig, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(home, "dotfiles", "bashrc"), "echo dotfiles\n")
+	if err := os.Symlink("dotfiles/bashrc", filepath.Join(home, ".bashrc")); err != nil {
+		t.Fatal(err)
+	}
+
+	exportService := NewExportService(NewConfigService(), NewSigningService())
+	if err := exportService.Export(io.Discard, ExportOptions{Format: ExportFormatScript, Passphrase: "secret"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("安装脚本加密应返回 ErrInvalidInput, got %v", err)
+	}
+	var buf bytes.Buffer
+	if err := exportService.Export(&buf, ExportOptions{Format: ExportFormatScript}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	script := filepath.Join(t.TempDir(), "install.sh")
+	if err := os.WriteFile(script, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 在新的主目录中运行，已存在的 ~/.profile 应先备份
+	target := t.TempDir()
+	writeTestFile(t, filepath.Join(target, ".profile"), "# old\n")
+	run := func() string {
+		t.Helper()
+		cmd := exec.Command(sh, script)
+		cmd.Env = append(os.Environ(), "HOME="+target, "LCM_BACKUP_DIR="+filepath.Join(target, "backup"))
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("运行安装脚本失败: %v\n%s", err, output)
+		}
+		return string(output)
+	}
+	output := run()
+	if !strings.Contains(output, "安装 3 个，内容相同 0 个，备份 1 个") {
+		t.Errorf("安装脚本汇总错误:\n%s", output)
+	}
+
+	if content, err := os.ReadFile(filepath.Join(target, ".profile")); err != nil || string(content) != profile {
+		t.Errorf("~/.profile 内容错误: %q, %v", content, err)
+	}
+	if content, err := os.ReadFile(filepath.Join(target, "backup", ".profile")); err != nil || string(content) != "# old\n" {
+		t.Errorf("~/.profile 的备份错误: %q, %v", content, err)
+	}
+	info, err := os.Stat(filepath.Join(target, ".ssh", "config"))
+	if err != nil || info.Mode().Perm() != 0600 || !info.ModTime().Equal(modTime) {
+		t.Errorf("~/.ssh/config 的权限或修改时间错误: %v, %v", info, err)
+	}
+	if link, err := os.Readlink(filepath.Join(target, ".bashrc")); err != nil || link != "dotfiles/bashrc" {
+		t.Errorf("~/.bashrc 应为符号链接: %q, %v", link, err)
+	}
+
+	// 再次运行时内容相同的文件不再备份
+	if output := run(); !strings.Contains(output, "安装 0 个，内容相同 3 个，备份 0 个") {
+		t.Errorf("重复运行的汇总错误:\n%s", output)
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "更新 testdata 中的 golden 文件")
+
+func TestExportHomeManagerModule(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	item := func(id, filePath string, mode fs.FileMode, content, linkTarget string) exportItem {
+		return exportItem{
+			file:    models.ManifestFile{ConfigFile: models.ConfigFile{ID: id, Path: filePath}, LinkTarget: linkTarget},
+			content: []byte(content),
+			mode:    mode,
+			modTime: modTime,
+		}
+	}
+	items := []exportItem{
+		item("bashrc", "~/.bashrc", 0644, "export PS1=\"\\u@\\h ${PWD} \"\r\nalias ll='ls -l'\n", ""),
+		item("sshconfig", "~/.ssh/config", 0600, "Host example\n  User alice\n", ""),
+		item("hello", "~/.local/bin/hello", 0755, "#!/bin/sh\necho hello\n", ""),
+		item("font", "~/.local/share/fonts/icon.bin", 0664, "\x00\x01\xff", ""),
+		item("vimrc", "~/.vimrc", 0644, "set nu\n", "dotfiles/vimrc"),
+		item("nvim", "~/.config/nvim/init.lua", 0644, "", filepath.Join(home, "dotfiles", "init.lua")),
+		item("hosts", "/etc/hosts", 0644, "127.0.0.1 localhost\n", ""),
+	}
+	manifest := &models.ExportManifest{ExportedAt: "2024-05-01T12:00:00Z", Skipped: []string{"zshrc"}}
+
+	files := homeManagerModule(items, manifest)
+	if len(files) != 2 || files[0].name != "default.nix" || files[1].name != "files/.local/share/fonts/icon.bin" || files[1].mode != 0664 {
+		t.Fatalf("导出包中的文件错误: %+v", files)
+	}
+	golden := filepath.Join("testdata", "home-manager.nix.golden")
+	if *updateGolden {
+		if err := os.WriteFile(golden, files[0].data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := UnifiedDiff("golden", "生成的模块", string(want), string(files[0].data)); string(want) != string(files[0].data) {
+		t.Errorf("生成的模块与 %s 不一致（go test -run TestExportHomeManagerModule -update 更新）:\n%s", golden, diff)
+	}
+	if nix, err := exec.LookPath("nix-instantiate"); err == nil {
+		if output, err := exec.Command(nix, "--parse", golden).CombinedOutput(); err != nil {
+			t.Errorf("生成的模块无法解析: %v\n%s", err, output)
+		}
+	}
+
+	// 通过导出接口生成的导出包是 tar.gz 目录
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	writeTestFile(t, filepath.Join(home, ".bashrc"), "echo ok\n")
+	var buf bytes.Buffer
+	if err := NewExportService(NewConfigService(), NewSigningService()).Export(&buf, ExportOptions{Format: ExportFormatNix}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	gzipReader, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	if !reflect.DeepEqual(names, []string{nixBundleRoot + "/", nixBundleRoot + "/default.nix"}) {
+		t.Errorf("导出包的目录结构错误: %v", names)
+	}
+}
+
+func TestExportAnsibleRole(t *testing.T) {
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	item := func(id, category, filePath string, mode fs.FileMode, content, linkTarget string) exportItem {
+		return exportItem{
+			file:    models.ManifestFile{ConfigFile: models.ConfigFile{ID: id, Category: category, Path: filePath}, LinkTarget: linkTarget},
+			content: []byte(content),
+			mode:    mode,
+			modTime: modTime,
+		}
+	}
+	items := []exportItem{
+		item("bashrc", "shell", "~/.bashrc", 0644, "echo {{ not a template }}\n", ""),
+		item("sshconfig", "ssh", "~/.ssh/config", 0600, "Host example\n", ""),
+		item("hello", "app", "~/.local/bin/hello", 0755, "#!/bin/sh\necho hello\n", ""),
+		item("vimrc", "editor", "~/.vimrc", 0644, "set nu\n", "dotfiles/vimrc"),
+		item("hosts", "app", "/etc/hosts", 0644, "127.0.0.1 localhost\n", ""),
+		item("odd", "app", "~/{{ odd }}.conf", 0644, "odd\n", ""),
+	}
+	// 只引用字段的模板转换为 Jinja 模板，使用条件的模板安装渲染结果
+	gitconfig := item("gitconfig", "git", "~/.gitconfig", 0644, "[user]\n\tname = {{ .User }}\n\temail = {{ .Vars.email }}\n# {% raw %} ${HOME} {\n", "")
+	gitconfig.file.Template = true
+	profile := item("profile", "shell", "~/.profile", 0644, "{{ if eq .Hostname \"work\" }}export WORK=1{{ end }}\n", "")
+	profile.file.Template, profile.rendered = true, []byte("\n")
+	items = append(items, gitconfig, profile)
+	manifest := &models.ExportManifest{ExportedAt: "2024-05-01T12:00:00Z", Skipped: []string{"zshrc"}}
+	data := models.TemplateData{Profile: "laptop", Vars: map[string]string{"email": "{{ alice }}@example.com", "unused": "x"}}
+
+	files := ansibleRole(items, manifest, data)
+	var names []string
+	for _, file := range files {
+		names = append(names, fmt.Sprintf("%s %04o", file.name, file.mode))
+	}
+	want := []string{
+		"tasks/main.yml 0644", "defaults/main.yml 0644", "meta/main.yml 0644",
+		"files/.bashrc 0644", "files/.ssh/config 0600", "files/.local/bin/hello 0755", "files/root/etc/hosts 0644",
+		"files/{{ odd }}.conf 0644", "templates/.gitconfig.j2 0644", "files/.profile 0644",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("角色中的文件错误: %v", names)
+	}
+	if string(files[3].data) != "echo {{ not a template }}\n" {
+		t.Errorf("files/ 中的文件应保持原样: %q", files[3].data)
+	}
+	wantTemplate := "[user]\n\tname = {{ ansible_facts['user_id'] }}\n\temail = {{ linux_configs_vars['email'] }}\n# {{ '{' }}% raw %} ${HOME} {\n"
+	if string(files[8].data) != wantTemplate {
+		t.Errorf("转换的 Jinja 模板错误: %q", files[8].data)
+	}
+	if string(files[9].data) != "\n" {
+		t.Errorf("无法转换的模板应安装渲染结果: %q", files[9].data)
+	}
+	if defaults := string(files[1].data); !strings.HasSuffix(defaults, "linux_configs_profile: \"laptop\"\nlinux_configs_vars:\n  email: !unsafe \"{{ alice }}@example.com\"\n") {
+		t.Errorf("模板变量的默认值错误:\n%s", defaults)
+	}
+
+	golden := filepath.Join("testdata", "ansible-tasks.yml.golden")
+	if *updateGolden {
+		if err := os.WriteFile(golden, files[0].data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(expected) != string(files[0].data) {
+		t.Errorf("生成的任务与 %s 不一致（go test -run TestExportAnsibleRole -update 更新）:\n%s",
+			golden, UnifiedDiff("golden", "生成的任务", string(expected), string(files[0].data)))
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// copyFileAttrs 将原文件的属主和扩展属性复制到新写入的临时文件
+func copyFileAttrs(original os.FileInfo, originalPath string, tmp *os.File) error {
+	if stat, ok := original.Sys().(*syscall.Stat_t); ok {
+		// 属主已一致时无需 chown，避免非 root 用户触发 EPERM
+		var current syscall.Stat_t
+		if err := syscall.Fstat(int(tmp.Fd()), &current); err == nil &&
+			(current.Uid != stat.Uid || current.Gid != stat.Gid) {
+			if err := tmp.Chown(int(stat.Uid), int(stat.Gid)); err != nil {
+				return fmt.Errorf("无法保留文件属主 %d:%d: %w", stat.Uid, stat.Gid, err)
+			}
+		}
+	}
+
+	return copyXattrs(originalPath, tmp.Name())
+}
+
+// copyXattrs 复制扩展属性；文件系统不支持或无权限的命名空间会被跳过
+func copyXattrs(src, dst string) error {
+	size, err := syscall.Listxattr(src, nil)
+	if err != nil || size == 0 {
+		return nil
+	}
+
+	buf := make([]byte, size)
+	size, err = syscall.Listxattr(src, buf)
+	if err != nil {
+		return nil
+	}
+
+	for _, name := range splitXattrNames(buf[:size]) {
+		valueSize, err := syscall.Getxattr(src, name, nil)
+		if err != nil {
+			continue
+		}
+		value := make([]byte, valueSize)
+		if valueSize > 0 {
+			if valueSize, err = syscall.Getxattr(src, name, value); err != nil {
+				continue
+			}
+		}
+		if err := syscall.Setxattr(dst, name, value[:valueSize], 0); err != nil {
+			if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EACCES) {
+				continue
+			}
+			return fmt.Errorf("无法保留扩展属性 %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// splitXattrNames 拆分 listxattr 返回的以 NUL 分隔的属性名列表
+func splitXattrNames(buf []byte) []string {
+	var names []string
+	start := 0
+	for i, b := range buf {
+		if b == 0 {
+			if i > start {
+				names = append(names, string(buf[start:i]))
+			}
+			start = i + 1
+		}
+	}
+	return names
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package services
+
+import "os"
+
+// copyFileAttrs 非 Linux 平台不复制属主和扩展属性
+func copyFileAttrs(original os.FileInfo, originalPath string, tmp *os.File) error {
+	return nil
+}
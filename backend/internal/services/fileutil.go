@@ -0,0 +1,149 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// 符号链接写入策略
+const (
+	SymlinkFollow  = "follow"  // 写入链接指向的目标文件，链接本身保持不变（默认）
+	SymlinkReplace = "replace" // 用普通文件替换链接本身
+)
+
+// defaultFileMode 新建文件时使用的权限
+const defaultFileMode fs.FileMode = 0644
+
+// atomicWriteFile 原子地写入配置文件：在目标所在目录创建临时文件，写入并 fsync 后重命名覆盖，
+// 同时保留原文件的权限、属主和扩展属性。path 是符号链接时按 symlinkPolicy 处理
+func atomicWriteFile(path string, data []byte, symlinkPolicy string) (*models.WriteResult, error) {
+	if symlinkPolicy == "" {
+		symlinkPolicy = SymlinkFollow
+	}
+	if symlinkPolicy != SymlinkFollow && symlinkPolicy != SymlinkReplace {
+		return nil, invalidf("无效的符号链接策略: %s（可选 %s、%s）", symlinkPolicy, SymlinkFollow, SymlinkReplace)
+	}
+
+	result := &models.WriteResult{Path: path, WrittenPath: path}
+
+	target := path
+	if linfo, err := os.Lstat(path); err == nil && linfo.Mode()&fs.ModeSymlink != 0 {
+		result.IsSymlink = true
+		result.SymlinkPolicy = symlinkPolicy
+
+		resolved, err := resolveSymlink(path)
+		if err != nil {
+			return nil, err
+		}
+		result.LinkTarget = resolved
+		if symlinkPolicy == SymlinkFollow {
+			target = resolved
+			result.WrittenPath = resolved
+		}
+	}
+
+	// 权限和属性以实际内容所在的文件为准（替换链接时沿用链接目标的权限）
+	mode := defaultFileMode
+	var original os.FileInfo
+	attrSource := ""
+	if info, err := os.Stat(target); err == nil {
+		if !info.Mode().IsRegular() {
+			return nil, invalidf("不是普通文件: %s", target)
+		}
+		mode = info.Mode().Perm() | (info.Mode() & (fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky))
+		original = info
+		attrSource = target
+	} else if result.LinkTarget != "" {
+		if info, err := os.Stat(result.LinkTarget); err == nil {
+			mode = info.Mode().Perm()
+		}
+	}
+
+	dir := filepath.Dir(target)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(target)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("无法在 %s 中创建临时文件: %w", dir, err)
+	}
+	tmpPath := tmp.Name()
+	committed := false
+	defer func() {
+		if !committed {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("无法写入临时文件: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("无法设置文件权限: %w", err)
+	}
+	if original != nil {
+		if err := copyFileAttrs(original, attrSource, tmp); err != nil {
+			tmp.Close()
+			return nil, err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("无法同步临时文件: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("无法关闭临时文件: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, target); err != nil {
+		return nil, fmt.Errorf("无法写入文件 %s: %w", target, err)
+	}
+	committed = true
+	syncDir(dir)
+
+	result.Mode = fmt.Sprintf("%04o", mode.Perm())
+	return result, nil
+}
+
+// atomicSymlink 原子地将 path 替换为指向 target 的符号链接：先在同一目录创建临时链接，再重命名覆盖
+func atomicSymlink(path, target string) error {
+	dir := filepath.Dir(path)
+	tmpPath := filepath.Join(dir, fmt.Sprintf(".%s.tmp-link-%d", filepath.Base(path), time.Now().UnixNano()))
+	if err := os.Symlink(target, tmpPath); err != nil {
+		return fmt.Errorf("无法在 %s 中创建符号链接: %w", dir, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("无法替换 %s: %w", path, err)
+	}
+	syncDir(dir)
+	return nil
+}
+
+// resolveSymlink 解析符号链接的最终目标，目标不存在时按链接内容推算路径
+func resolveSymlink(path string) (string, error) {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved, nil
+	}
+
+	link, err := os.Readlink(path)
+	if err != nil {
+		return "", fmt.Errorf("无法读取符号链接 %s: %w", path, err)
+	}
+	if !filepath.IsAbs(link) {
+		link = filepath.Join(filepath.Dir(path), link)
+	}
+	return filepath.Clean(link), nil
+}
+
+// syncDir 同步目录项，保证重命名在断电后仍然生效
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestAtomicWriteFilePreservesMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	if err := os.WriteFile(path, []byte("Host *\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// 扩展属性在部分文件系统上不可用，设置失败时只检查权限
+	xattrSupported := syscall.Setxattr(path, "user.lcm-test", []byte("keep"), 0) == nil
+
+	result, err := atomicWriteFile(path, []byte("Host example\n"), "")
+	if err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if result.Mode != "0600" {
+		t.Errorf("权限应保持为 0600, got %s", result.Mode)
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("磁盘上的权限被修改: %v", info.Mode().Perm())
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "Host example\n" {
+		t.Errorf("内容错误: %q", content)
+	}
+
+	if xattrSupported {
+		value := make([]byte, 16)
+		n, err := syscall.Getxattr(path, "user.lcm-test", value)
+		if err != nil || string(value[:n]) != "keep" {
+			t.Errorf("扩展属性未保留: %v %q", err, value[:n])
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("不应残留临时文件: %d 个条目", len(entries))
+	}
+}
+
+func TestAtomicWriteFileSymlinkPolicy(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "bashrc")
+	link := filepath.Join(dir, ".bashrc")
+	writeTestFile(t, target, "old\n")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := atomicWriteFile(link, []byte("follow\n"), SymlinkFollow)
+	if err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if !result.IsSymlink || result.WrittenPath != target {
+		t.Errorf("follow 策略应写入链接目标: %+v", result)
+	}
+	if info, _ := os.Lstat(link); info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("follow 策略不应替换链接")
+	}
+	if content, _ := os.ReadFile(target); string(content) != "follow\n" {
+		t.Errorf("目标内容错误: %q", content)
+	}
+
+	if _, err := atomicWriteFile(link, []byte("replace\n"), SymlinkReplace); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	if info, _ := os.Lstat(link); info.Mode()&os.ModeSymlink != 0 {
+		t.Errorf("replace 策略应将链接替换为普通文件")
+	}
+	if content, _ := os.ReadFile(target); string(content) != "follow\n" {
+		t.Errorf("replace 策略不应修改原目标: %q", content)
+	}
+
+	if _, err := atomicWriteFile(link, []byte("x"), "bogus"); err == nil {
+		t.Errorf("未知策略应返回错误")
+	}
+}
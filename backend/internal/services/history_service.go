@@ -0,0 +1,229 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// revPattern 允许的版本号格式（完整或缩写的提交哈希）
+var revPattern = regexp.MustCompile(`^[0-9a-f]{4,40}$`)
+
+// HistoryService 将每个受管理文件的修改提交到服务自有的 git 仓库中
+// 仓库中每个文件保存为 files/<文件ID>-<路径摘要>，同一ID登记到其他路径时开始新的历史
+type HistoryService struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewHistoryService 创建使用指定目录作为 git 仓库的历史服务实例
+func NewHistoryService(dir string) *HistoryService {
+	return &HistoryService{dir: dir}
+}
+
+// Record 提交文件的新内容，内容未变化时不产生新提交，返回当前版本号
+func (h *HistoryService) Record(fileID, path, content, author, message string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.init(); err != nil {
+		return "", err
+	}
+	return h.commit(h.relPath(fileID, path), content, author, message)
+}
+
+// RecordBaseline 文件还没有任何历史时，把当前内容记录为初始版本
+func (h *HistoryService) RecordBaseline(fileID, path, content string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.init(); err != nil {
+		return err
+	}
+
+	relPath := h.relPath(fileID, path)
+	out, err := h.git("log", "-1", "--format=%H", "--", relPath)
+	if err == nil && strings.TrimSpace(out) != "" {
+		return nil
+	}
+
+	_, err = h.commit(relPath, content, currentUser(), fmt.Sprintf("记录 %s（%s）的初始版本", fileID, path))
+	return err
+}
+
+// Log 返回文件的历史版本列表，最新的在前
+func (h *HistoryService) Log(fileID, path string) ([]models.Revision, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	revisions := []models.Revision{}
+	if !h.initialized() {
+		return revisions, nil
+	}
+
+	out, err := h.git("log", "--format=%H%x1f%h%x1f%an%x1f%aI%x1f%s", "--", h.relPath(fileID, path))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if revision, ok := parseRevisionLine(line); ok {
+			revisions = append(revisions, revision)
+		}
+	}
+	return revisions, nil
+}
+
+// Show 返回文件在指定版本的内容
+func (h *HistoryService) Show(fileID, path, rev string) (*models.RevisionContent, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !revPattern.MatchString(rev) {
+		return nil, invalidf("无效的版本号: %s", rev)
+	}
+	if !h.initialized() {
+		return nil, notFoundf("版本不存在: %s", rev)
+	}
+
+	out, err := h.git("log", "-1", "--format=%H%x1f%h%x1f%an%x1f%aI%x1f%s", rev, "--")
+	if err != nil {
+		return nil, notFoundf("版本不存在: %s", rev)
+	}
+	revision, ok := parseRevisionLine(strings.TrimSpace(out))
+	if !ok {
+		return nil, notFoundf("版本不存在: %s", rev)
+	}
+
+	content, err := h.git("show", revision.Rev+":"+h.relPath(fileID, path))
+	if err != nil {
+		return nil, notFoundf("文件 %s 在版本 %s 中不存在", fileID, rev)
+	}
+
+	return &models.RevisionContent{Revision: revision, Content: content}, nil
+}
+
+// commit 写入仓库中的 relPath 并提交，调用方需持有锁
+func (h *HistoryService) commit(relPath, content, author, message string) (string, error) {
+	fullPath := filepath.Join(h.dir, relPath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0700); err != nil {
+		return "", fmt.Errorf("无法创建历史目录: %w", err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0600); err != nil {
+		return "", fmt.Errorf("无法写入历史文件: %w", err)
+	}
+
+	if _, err := h.git("add", "--", relPath); err != nil {
+		return "", err
+	}
+
+	// 暂存区没有变化时不产生空提交
+	if _, err := h.git("diff", "--cached", "--quiet", "--", relPath); err == nil {
+		out, err := h.git("rev-parse", "--verify", "-q", "HEAD")
+		if err != nil {
+			return "", nil
+		}
+		return strings.TrimSpace(out), nil
+	}
+
+	author = strings.Map(func(r rune) rune {
+		if r == '<' || r == '>' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(author))
+	if author == "" {
+		author = currentUser()
+	}
+	if _, err := h.git("commit", "-q", "-m", message, "--author", fmt.Sprintf("%s <%s@localhost>", author, strings.ReplaceAll(author, " ", ".")), "--", relPath); err != nil {
+		return "", err
+	}
+
+	out, err := h.git("rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// init 首次使用时初始化 git 仓库，调用方需持有锁
+func (h *HistoryService) init() error {
+	if h.initialized() {
+		return nil
+	}
+	if err := os.MkdirAll(h.dir, 0700); err != nil {
+		return fmt.Errorf("无法创建历史仓库目录 %s: %w", h.dir, err)
+	}
+	if _, err := h.git("-c", "init.defaultBranch=main", "init", "-q"); err != nil {
+		return err
+	}
+	return nil
+}
+
+// initialized 判断历史仓库是否已初始化
+func (h *HistoryService) initialized() bool {
+	_, err := os.Stat(filepath.Join(h.dir, ".git"))
+	return err == nil
+}
+
+// relPath 返回文件在历史仓库中的相对路径，路径摘要区分同一ID先后登记的不同文件
+func (h *HistoryService) relPath(fileID, path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return "files/" + fileID + "-" + hex.EncodeToString(sum[:6])
+}
+
+// git 在历史仓库中执行 git 命令，忽略用户的全局和系统配置
+func (h *HistoryService) git(args ...string) (string, error) {
+	base := []string{
+		"-C", h.dir,
+		"-c", "user.name=linux-config-manager",
+		"-c", "user.email=linux-config-manager@localhost",
+		"-c", "commit.gpgsign=false",
+		"-c", "core.autocrlf=false",
+	}
+	cmd := exec.Command("git", append(base, args...)...)
+	cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s 执行失败: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// parseRevisionLine 解析 git log 的单行输出
+func parseRevisionLine(line string) (models.Revision, bool) {
+	parts := strings.Split(line, "\x1f")
+	if len(parts) != 5 {
+		return models.Revision{}, false
+	}
+
+	date, _ := time.Parse(time.RFC3339, parts[3])
+	return models.Revision{
+		Rev:      parts[0],
+		ShortRev: parts[1],
+		Author:   parts[2],
+		Date:     date,
+		Message:  parts[4],
+	}, true
+}
+
+// currentUser 返回运行服务的系统用户名
+func currentUser() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "unknown"
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestUpdateFileRecordsHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("未安装 git")
+	}
+
+	home := testutil.SetupHome(t)
+
+	bashrc := filepath.Join(home, ".bashrc")
+	writeTestFile(t, bashrc, "original\n")
+
+	service := NewConfigService()
+	if _, err := service.UpdateFile("bashrc", "first edit\n", UpdateOptions{Author: "alice"}); err != nil {
+		t.Fatalf("更新文件失败: %v", err)
+	}
+	if _, err := service.UpdateFile("bashrc", "second edit\n", UpdateOptions{Author: "bob"}); err != nil {
+		t.Fatalf("更新文件失败: %v", err)
+	}
+
+	revisions, err := service.GetFileHistory("bashrc")
+	if err != nil {
+		t.Fatalf("获取历史失败: %v", err)
+	}
+	// 初始版本 + 两次修改
+	if len(revisions) != 3 {
+		t.Fatalf("历史版本数量错误: got %d want 3", len(revisions))
+	}
+	if revisions[0].Author != "bob" || revisions[1].Author != "alice" {
+		t.Errorf("修改者记录错误: %s, %s", revisions[0].Author, revisions[1].Author)
+	}
+
+	original := revisions[2]
+	revision, err := service.GetFileRevision("bashrc", original.ShortRev)
+	if err != nil || revision.Content != "original\n" {
+		t.Fatalf("读取初始版本失败: %v %q", err, revision)
+	}
+
+	if _, err := service.RestoreFileRevision("bashrc", original.Rev, "carol"); err != nil {
+		t.Fatalf("恢复版本失败: %v", err)
+	}
+	content, _ := os.ReadFile(bashrc)
+	if string(content) != "original\n" {
+		t.Errorf("恢复后的内容错误: %q", content)
+	}
+
+	revisions, _ = service.GetFileHistory("bashrc")
+	if len(revisions) != 4 || revisions[0].Author != "carol" {
+		t.Errorf("恢复操作应记录为新版本: %+v", revisions)
+	}
+
+	// 修改登记路径后不继承原路径的历史
+	writeTestFile(t, filepath.Join(home, ".bashrc.local"), "local\n")
+	newPath := "~/.bashrc.local"
+	if _, err := service.UpdateFileMeta("bashrc", models.UpdateFileMetaRequest{Path: &newPath}); err != nil {
+		t.Fatalf("修改登记路径失败: %v", err)
+	}
+	if revisions, _ = service.GetFileHistory("bashrc"); len(revisions) != 0 {
+		t.Errorf("新路径不应有历史版本: %+v", revisions)
+	}
+	if _, err := service.GetFileRevision("bashrc", original.Rev); !errors.Is(err, ErrNotFound) {
+		t.Errorf("新路径不应读取到原路径的版本, got %v", err)
+	}
+	if _, err := service.UpdateFile("bashrc", "local edit\n", UpdateOptions{}); err != nil {
+		t.Fatalf("更新文件失败: %v", err)
+	}
+	if revisions, _ = service.GetFileHistory("bashrc"); len(revisions) != 2 {
+		t.Errorf("新路径的历史版本数量错误: %+v", revisions)
+	}
+}
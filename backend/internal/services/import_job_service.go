@@ -0,0 +1,453 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// importJobTTL 导入任务在最后一次更新之后保留的时间，过期的任务和临时文件会被清理
+const importJobTTL = time.Hour
+
+// defaultMaxUploadMB 上传压缩包的默认大小限制（MiB），可通过环境变量 IMPORT_MAX_UPLOAD_MB 修改
+const defaultMaxUploadMB = 256
+
+// 同时保存临时文件的任务（上传中、已上传和处理中）的数量上限，以及临时文件总大小相对上传限制的倍数
+const (
+	maxActiveImportJobs = 8
+	spoolSizeFactor     = 4
+)
+
+// ImportJobService 管理导入任务：上传的压缩包以流的方式写入临时文件，不在内存中缓存，
+// 再在后台生成导入计划或导入，调用方通过任务ID查询进度和结果
+// 临时文件保存在 <数据目录>/uploads 中，权限为 0600
+type ImportJobService struct {
+	importService *ImportService
+	spoolDir      string
+	maxSize       int64
+	maxSpoolSize  int64 // 所有任务的临时文件预留大小之和的上限
+
+	mu    sync.Mutex
+	jobs  map[string]*importJob
+	swept bool
+}
+
+// NewImportJobService 创建新的导入任务服务实例
+func NewImportJobService(importService *ImportService) *ImportJobService {
+	return &ImportJobService{
+		importService: importService,
+		spoolDir:      filepath.Join(dataDir(), "uploads"),
+		maxSize:       maxUploadSize(),
+		maxSpoolSize:  maxUploadSize() * spoolSizeFactor,
+		jobs:          make(map[string]*importJob),
+	}
+}
+
+// importJob 内存中保存的导入任务
+type importJob struct {
+	job     models.ImportJob
+	path    string        // 临时文件路径，处理完成或任务删除后移除
+	spooled int64         // 临时文件预留的字节数，声明了大小时为声明的大小，一次性上传时为上传限制，移除后为 0
+	writing bool          // 正在写入分块，同一任务的分块不能并发写入
+	err     error         // 处理失败时的原始错误
+	done    chan struct{} // 处理结束时关闭
+}
+
+// maxUploadSize 读取环境变量 IMPORT_MAX_UPLOAD_MB，未设置或无效时使用默认值
+func maxUploadSize() int64 {
+	mb, err := strconv.ParseInt(os.Getenv("IMPORT_MAX_UPLOAD_MB"), 10, 64)
+	if err != nil || mb <= 0 {
+		mb = defaultMaxUploadMB
+	}
+	return mb << 20
+}
+
+// MaxUploadSize 返回上传压缩包的大小限制（字节）
+func (s *ImportJobService) MaxUploadSize() int64 {
+	return s.maxSize
+}
+
+// Upload 将一次性上传的压缩包写入临时文件，并创建已上传完整的任务
+func (s *ImportJobService) Upload(r io.Reader, fileName string) (*models.ImportJob, error) {
+	job, err := s.newJob(fileName, 0)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	job.writing = true
+	s.mu.Unlock()
+
+	n, err := spoolTo(job.path, 0, r, s.maxSize)
+	if err == nil && n > s.maxSize {
+		err = tooLargef("压缩包超过上传限制 %d 字节", s.maxSize)
+	}
+	if err != nil {
+		s.remove(job.job.ID)
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.writing = false
+	job.spooled = n
+	job.job.Size = n
+	job.job.Received = n
+	job.job.Status = models.ImportJobUploaded
+	job.touch()
+	result := job.job
+	return &result, nil
+}
+
+// Create 创建分块上传的任务，之后通过 WriteChunk 依次写入各个分块
+func (s *ImportJobService) Create(req models.CreateImportJobRequest) (*models.ImportJob, error) {
+	if req.Size <= 0 {
+		return nil, invalidf("压缩包大小必须大于 0")
+	}
+	if req.Size > s.maxSize {
+		return nil, tooLargef("压缩包大小 %d 字节超过上传限制 %d 字节", req.Size, s.maxSize)
+	}
+
+	job, err := s.newJob(req.FileName, req.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := job.job
+	return &result, nil
+}
+
+// WriteChunk 从 offset 处写入一个分块，offset 必须等于已接收的字节数
+// 上传中断时已写入的部分会保留，调用方查询任务的 received 后从该位置继续上传
+func (s *ImportJobService) WriteChunk(jobID string, offset int64, r io.Reader) (*models.ImportJob, error) {
+	s.mu.Lock()
+	job, err := s.lookup(jobID)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if job.job.Status != models.ImportJobUploading {
+		s.mu.Unlock()
+		return nil, conflictf("导入任务 %s 已上传完整，不能继续写入", jobID)
+	}
+	if job.writing {
+		s.mu.Unlock()
+		return nil, conflictf("导入任务 %s 正在接收其他分块", jobID)
+	}
+	if offset != job.job.Received {
+		s.mu.Unlock()
+		return nil, conflictf("分块偏移量 %d 与已接收的字节数 %d 不一致", offset, job.job.Received)
+	}
+	job.writing = true
+	remaining := job.job.Size - offset
+	s.mu.Unlock()
+
+	n, err := spoolTo(job.path, offset, r, remaining)
+	if err == nil && n > remaining {
+		// 超出声明大小的分块整体丢弃
+		n = 0
+		err = os.Truncate(job.path, offset)
+		if err == nil {
+			err = invalidf("分块超出声明的压缩包大小 %d 字节", job.job.Size)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.writing = false
+	job.job.Received = offset + n
+	if job.job.Received == job.job.Size {
+		job.job.Status = models.ImportJobUploaded
+	}
+	job.touch()
+	if err != nil {
+		return nil, err
+	}
+	result := job.job
+	return &result, nil
+}
+
+// Start 开始在后台处理已上传完整的压缩包，按 req.Mode 生成导入计划或直接导入
+// author 记录到直接导入时的历史版本中
+func (s *ImportJobService) Start(jobID string, req models.StartImportJobRequest, author string) (*models.ImportJob, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = models.ImportJobModePlan
+	}
+	if mode != models.ImportJobModePlan && mode != models.ImportJobModeImport {
+		return nil, invalidf("无效的导入模式: %s（可选 %s、%s）", mode, models.ImportJobModePlan, models.ImportJobModeImport)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.lookup(jobID)
+	if err != nil {
+		return nil, err
+	}
+	switch job.job.Status {
+	case models.ImportJobUploaded:
+	case models.ImportJobUploading:
+		return nil, conflictf("压缩包尚未上传完整: 已接收 %d / %d 字节", job.job.Received, job.job.Size)
+	default:
+		return nil, conflictf("导入任务 %s 已经开始处理", jobID)
+	}
+
+	job.job.Status = models.ImportJobProcessing
+	job.job.Mode = mode
+	job.job.Stage = "等待处理"
+	job.touch()
+
+	opts := ImportOptions{
+		Author:         author,
+		Force:          req.Force,
+		SkipValidation: req.SkipValidation,
+		CreateMissing:  req.CreateMissing,
+		Passphrase:     req.Passphrase,
+		Progress: func(stage string, done, total int) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			job.job.Stage = stage
+			job.job.Processed = done
+			job.job.Total = total
+			job.touch()
+		},
+	}
+	go s.run(job, mode, opts)
+
+	result := job.job
+	return &result, nil
+}
+
+// run 在后台生成导入计划或导入，结束后移除临时文件
+func (s *ImportJobService) run(job *importJob, mode string, opts ImportOptions) {
+	var plan *models.ImportPlan
+	var result *models.ImportResult
+	file, err := os.Open(job.path)
+	if err == nil {
+		if mode == models.ImportJobModePlan {
+			plan, err = s.importService.Plan(file, job.job.Size, opts)
+		} else {
+			result, err = s.importService.Import(file, job.job.Size, opts)
+		}
+		file.Close()
+	}
+	os.Remove(job.path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.spooled = 0
+	job.err = err
+	job.job.Plan = plan
+	job.job.Result = result
+	if err != nil {
+		job.job.Status = models.ImportJobFailed
+		job.job.Error = err.Error()
+		var archiveErr *ArchiveError
+		if errors.As(err, &archiveErr) {
+			detail := archiveErr.Detail
+			job.job.ErrorDetail = &detail
+		}
+	} else {
+		job.job.Status = models.ImportJobCompleted
+		job.job.Stage = "完成"
+	}
+	job.touch()
+	close(job.done)
+}
+
+// Wait 等待任务处理结束，返回任务的最终状态和处理失败时的原始错误
+func (s *ImportJobService) Wait(jobID string) (*models.ImportJob, error) {
+	s.mu.Lock()
+	job, err := s.lookup(jobID)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	<-job.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := job.job
+	return &result, job.err
+}
+
+// Get 返回导入任务的当前状态
+func (s *ImportJobService) Get(jobID string) (*models.ImportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.lookup(jobID)
+	if err != nil {
+		return nil, err
+	}
+	result := job.job
+	return &result, nil
+}
+
+// Delete 取消尚未开始处理的任务或删除已结束的任务，并移除临时文件
+func (s *ImportJobService) Delete(jobID string) error {
+	s.mu.Lock()
+	job, err := s.lookup(jobID)
+	if err == nil && (job.writing || job.job.Status == models.ImportJobProcessing) {
+		err = conflictf("导入任务 %s 正在处理，不能删除", jobID)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.remove(jobID)
+	return nil
+}
+
+// newJob 创建任务及其空的临时文件
+func (s *ImportJobService) newJob(fileName string, size int64) (*importJob, error) {
+	if err := checkImportFileName(fileName); err != nil {
+		return nil, err
+	}
+	id, err := newPlanID()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(s.spoolDir, 0700); err != nil {
+		return nil, fmt.Errorf("无法创建上传目录: %w", err)
+	}
+
+	// 一次性上传的大小未知，先按上传限制预留
+	spooled := size
+	if spooled == 0 {
+		spooled = s.maxSize
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 限制同时占用上传目录的任务数和临时文件的总大小，避免并发上传耗尽磁盘
+	s.expire()
+	active, total := 0, int64(0)
+	for _, existing := range s.jobs {
+		if existing.spooled > 0 {
+			active++
+			total += existing.spooled
+		}
+	}
+	if active >= maxActiveImportJobs {
+		return nil, conflictf("同时进行的导入任务已达上限 %d，请等待其他任务结束或删除不需要的任务", maxActiveImportJobs)
+	}
+	if total+spooled > s.maxSpoolSize {
+		return nil, tooLargef("上传目录中的临时文件已预留 %d 字节，再上传 %d 字节将超过上限 %d 字节", total, spooled, s.maxSpoolSize)
+	}
+
+	// 任务只保存在内存中，服务重启前遗留的临时文件不再有对应的任务
+	if !s.swept {
+		s.swept = true
+		if stale, err := filepath.Glob(filepath.Join(s.spoolDir, "import-*.part")); err == nil {
+			for _, path := range stale {
+				os.Remove(path)
+			}
+		}
+	}
+
+	path := filepath.Join(s.spoolDir, "import-"+id+".part")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("无法创建上传临时文件: %w", err)
+	}
+	file.Close()
+
+	now := time.Now()
+	job := &importJob{
+		job: models.ImportJob{
+			ID:        id,
+			Status:    models.ImportJobUploading,
+			FileName:  filepath.Base(fileName),
+			Size:      size,
+			CreatedAt: now,
+		},
+		path:    path,
+		spooled: spooled,
+		done:    make(chan struct{}),
+	}
+	job.touch()
+	s.jobs[id] = job
+	return job, nil
+}
+
+// lookup 查找任务并清理过期的任务，调用方需持有锁
+func (s *ImportJobService) lookup(jobID string) (*importJob, error) {
+	s.expire()
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil, notFoundf("导入任务不存在或已过期: %s", jobID)
+	}
+	return job, nil
+}
+
+// expire 清理过期的任务及其临时文件，调用方需持有锁
+func (s *ImportJobService) expire() {
+	now := time.Now()
+	for id, job := range s.jobs {
+		if now.After(job.job.ExpiresAt) && !job.writing && job.job.Status != models.ImportJobProcessing {
+			os.Remove(job.path)
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// remove 删除任务及其临时文件
+func (s *ImportJobService) remove(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job, ok := s.jobs[jobID]; ok {
+		os.Remove(job.path)
+		delete(s.jobs, jobID)
+	}
+}
+
+// touch 更新任务的修改时间和过期时间，调用方需持有锁
+func (j *importJob) touch() {
+	j.job.UpdatedAt = time.Now()
+	j.job.ExpiresAt = j.job.UpdatedAt.Add(importJobTTL)
+}
+
+// spoolTo 从 offset 处将 r 写入文件，最多读取 limit+1 字节，返回值大于 limit 表示内容超出限制
+// 读取中途出错时返回已写入的字节数和错误，已写入的部分保留在文件中
+func spoolTo(path string, offset int64, r io.Reader, limit int64) (int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("无法打开上传临时文件: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return 0, err
+	}
+
+	n, err := io.Copy(file, io.LimitReader(r, limit+1))
+	if err != nil {
+		err = fmt.Errorf("接收上传内容失败: %w", err)
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = closeErr
+	}
+	return n, err
+}
+
+// checkImportFileName 检查上传文件的扩展名，压缩包的实际格式按内容识别
+func checkImportFileName(fileName string) error {
+	name := strings.TrimSuffix(strings.ToLower(fileName), ".enc")
+	if !strings.HasSuffix(name, ".zip") && !strings.HasSuffix(name, ".tar.gz") && !strings.HasSuffix(name, ".tgz") {
+		return invalidf("只支持 ZIP 或 tar.gz 格式的配置文件")
+	}
+	return nil
+}
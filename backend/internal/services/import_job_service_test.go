@@ -0,0 +1,158 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestImportJobChunkedUpload(t *testing.T) {
+	home := testutil.SetupHome(t)
+	t.Setenv("IMPORT_MAX_UPLOAD_MB", "1")
+
+	writeTestFile(t, filepath.Join(home, ".bashrc"), "echo ok\n")
+	configService := NewConfigService()
+	signingService := NewSigningService()
+	var buf bytes.Buffer
+	if err := NewExportService(configService, signingService).Export(&buf, ExportOptions{Format: ExportFormatTarGz}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	bundle := buf.Bytes()
+
+	jobs := NewImportJobService(NewImportService(configService, signingService))
+	if jobs.MaxUploadSize() != 1<<20 {
+		t.Errorf("上传限制错误: %d", jobs.MaxUploadSize())
+	}
+	if _, err := jobs.Create(models.CreateImportJobRequest{FileName: "configs.tar.gz", Size: 2 << 20}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("超过上传限制应返回 ErrTooLarge, got %v", err)
+	}
+	if _, err := jobs.Create(models.CreateImportJobRequest{FileName: "configs.txt", Size: 10}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("不支持的扩展名应返回 ErrInvalidInput, got %v", err)
+	}
+
+	job, err := jobs.Create(models.CreateImportJobRequest{FileName: "configs.tar.gz", Size: int64(len(bundle))})
+	if err != nil {
+		t.Fatalf("创建导入任务失败: %v", err)
+	}
+
+	// 上传中断时保留已写入的部分，从 received 处继续
+	half := len(bundle) / 2
+	interrupted := io.MultiReader(bytes.NewReader(bundle[:half]), iotest.ErrReader(errors.New("连接中断")))
+	if _, err := jobs.WriteChunk(job.ID, 0, interrupted); err == nil {
+		t.Fatal("上传中断时应返回错误")
+	}
+	job, _ = jobs.Get(job.ID)
+	if job.Received != int64(half) || job.Status != models.ImportJobUploading {
+		t.Fatalf("中断后的任务状态错误: %+v", job)
+	}
+	if _, err := jobs.WriteChunk(job.ID, 0, bytes.NewReader(bundle)); !errors.Is(err, ErrConflict) {
+		t.Errorf("偏移量不一致应返回 ErrConflict, got %v", err)
+	}
+	if _, err := jobs.Start(job.ID, models.StartImportJobRequest{}, ""); !errors.Is(err, ErrConflict) {
+		t.Errorf("未上传完整时开始处理应返回 ErrConflict, got %v", err)
+	}
+	oversized := append(append([]byte(nil), bundle[half:]...), 'x')
+	if _, err := jobs.WriteChunk(job.ID, int64(half), bytes.NewReader(oversized)); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("超出声明大小的分块应返回 ErrInvalidInput, got %v", err)
+	}
+	job, err = jobs.WriteChunk(job.ID, int64(half), bytes.NewReader(bundle[half:]))
+	if err != nil {
+		t.Fatalf("写入分块失败: %v", err)
+	}
+	if job.Status != models.ImportJobUploaded || job.Received != job.Size {
+		t.Fatalf("上传完整后的任务状态错误: %+v", job)
+	}
+
+	if _, err := jobs.Start(job.ID, models.StartImportJobRequest{}, ""); err != nil {
+		t.Fatalf("开始处理失败: %v", err)
+	}
+	job, err = jobs.Wait(job.ID)
+	if err != nil {
+		t.Fatalf("处理导入任务失败: %v", err)
+	}
+	if job.Status != models.ImportJobCompleted || job.Plan == nil || len(job.Plan.Entries) != 1 || job.Processed != job.Total {
+		t.Errorf("导入任务结果错误: %+v", job)
+	}
+	if _, err := jobs.Start(job.ID, models.StartImportJobRequest{}, ""); !errors.Is(err, ErrConflict) {
+		t.Errorf("重复开始处理应返回 ErrConflict, got %v", err)
+	}
+
+	// 一次性上传超过限制时不保留任务和临时文件
+	if _, err := jobs.Upload(strings.NewReader(strings.Repeat("x", 1<<20+1)), "big.zip"); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("超过上传限制应返回 ErrTooLarge, got %v", err)
+	}
+
+	job, err = jobs.Upload(strings.NewReader("not an archive"), "broken.zip")
+	if err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	if _, err := jobs.Start(job.ID, models.StartImportJobRequest{Mode: models.ImportJobModeImport}, ""); err != nil {
+		t.Fatalf("开始处理失败: %v", err)
+	}
+	job, err = jobs.Wait(job.ID)
+	var archiveErr *ArchiveError
+	if !errors.As(err, &archiveErr) || job.Status != models.ImportJobFailed || job.ErrorDetail == nil {
+		t.Errorf("无效的压缩包应处理失败: %+v, %v", job, err)
+	}
+	if err := jobs.Delete(job.ID); err != nil {
+		t.Errorf("删除导入任务失败: %v", err)
+	}
+	if _, err := jobs.Get(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后应返回 ErrNotFound, got %v", err)
+	}
+
+	// 处理完成、删除或被拒绝的任务都不应留下临时文件
+	spooled, _ := os.ReadDir(jobs.spoolDir)
+	if len(spooled) != 0 {
+		t.Errorf("临时文件未清理: %v", spooled)
+	}
+}
+
+func TestImportJobLimits(t *testing.T) {
+	testutil.SetupHome(t)
+	t.Setenv("IMPORT_MAX_UPLOAD_MB", "1")
+	jobs := NewImportJobService(NewImportService(NewConfigService(), NewSigningService()))
+
+	// 临时文件的预留总大小不超过上传限制的 4 倍
+	var ids []string
+	for i := 0; i < spoolSizeFactor; i++ {
+		job, err := jobs.Create(models.CreateImportJobRequest{FileName: "configs.zip", Size: 1 << 20})
+		if err != nil {
+			t.Fatalf("创建导入任务失败: %v", err)
+		}
+		ids = append(ids, job.ID)
+	}
+	if _, err := jobs.Create(models.CreateImportJobRequest{FileName: "configs.zip", Size: 1}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("超过临时文件总大小上限应返回 ErrTooLarge, got %v", err)
+	}
+	for _, id := range ids {
+		if err := jobs.Delete(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 同时进行的任务数有上限
+	for i := 0; i < maxActiveImportJobs; i++ {
+		if _, err := jobs.Create(models.CreateImportJobRequest{FileName: "configs.zip", Size: 1}); err != nil {
+			t.Fatalf("创建导入任务失败: %v", err)
+		}
+	}
+	if _, err := jobs.Create(models.CreateImportJobRequest{FileName: "configs.zip", Size: 1}); !errors.Is(err, ErrConflict) {
+		t.Errorf("超过任务数上限应返回 ErrConflict, got %v", err)
+	}
+
+	// 压缩包的安全限制随上传限制放宽
+	t.Setenv("IMPORT_MAX_UPLOAD_MB", "1024")
+	limits := NewImportService(nil, nil).limits
+	if limits.maxTotalSize != 1<<30 || limits.maxEntrySize != 64<<20 {
+		t.Errorf("压缩包限制应随上传限制放宽: %+v", limits)
+	}
+}
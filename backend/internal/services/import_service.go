@@ -0,0 +1,882 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// importPlanTTL 导入计划在内存中保留的时间
+const importPlanTTL = 30 * time.Minute
+
+// 同时保留在内存中的导入计划的数量和内容总大小上限，超出时丢弃最早的计划
+const (
+	maxStoredImportPlans     = 16
+	maxStoredImportPlanBytes = 256 << 20
+)
+
+// manifestName 导出压缩包中配置清单的文件名
+const manifestName = "配置清单.json"
+
+// ImportService 处理配置压缩包的导入：先生成导入计划，再执行计划中选定的条目
+type ImportService struct {
+	configService  *ConfigService
+	signingService *SigningService
+	limits         archiveLimits
+	spoolDir       string // 解密加密导出包时存放临时文件的目录
+
+	mu    sync.Mutex
+	plans map[string]*importPlan
+}
+
+// NewImportService 创建新的导入服务实例
+func NewImportService(configService *ConfigService, signingService *SigningService) *ImportService {
+	return &ImportService{
+		configService:  configService,
+		signingService: signingService,
+		limits:         archiveLimitsFor(maxUploadSize()),
+		spoolDir:       filepath.Join(dataDir(), "uploads"),
+		plans:          make(map[string]*importPlan),
+	}
+}
+
+// importPlan 内存中保存的导入计划，items 与 plan.Entries 一一对应
+type importPlan struct {
+	plan  models.ImportPlan
+	items []importItem
+	size  int64 // 条目内容和差异占用的字节数，保存时计算
+}
+
+// importItem 导入计划条目执行时需要的数据
+type importItem struct {
+	content  []byte
+	etag     string                      // 生成计划时本地文件的 ETag，执行时据此判断文件是否又被修改
+	register *models.RegisterFileRequest // 非空时执行前需要先登记该文件
+	meta     entryMeta                   // 写入后恢复的权限、修改时间，或要创建的符号链接
+	review   string                      // 非空时条目只能在用户确认导入计划后执行，直接导入时跳过并给出该原因
+}
+
+// Plan 解析压缩包并生成导入计划，不修改任何文件
+// 只使用 opts 中的 CreateMissing 和 Passphrase，Author、Force 和 SkipValidation 在执行计划时指定
+func (s *ImportService) Plan(r io.ReaderAt, size int64, opts ImportOptions) (*models.ImportPlan, error) {
+	plan, err := s.buildPlan(r, size, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.storePlan(plan), nil
+}
+
+// storePlan 保存导入计划供之后执行，同时清理过期的计划
+// 计划数量或内容总大小超出上限时丢弃最早生成的计划，新保存的计划始终保留
+func (s *ImportService) storePlan(plan *importPlan) *models.ImportPlan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan.size = 0
+	for i, item := range plan.items {
+		plan.size += int64(len(item.content) + len(plan.plan.Entries[i].Diff))
+	}
+
+	now := time.Now()
+	var total int64
+	for id, existing := range s.plans {
+		if now.After(existing.plan.ExpiresAt) {
+			delete(s.plans, id)
+			continue
+		}
+		total += existing.size
+	}
+	for len(s.plans) > 0 && (len(s.plans) >= maxStoredImportPlans || total+plan.size > maxStoredImportPlanBytes) {
+		oldest := ""
+		for id, existing := range s.plans {
+			if oldest == "" || existing.plan.ExpiresAt.Before(s.plans[oldest].plan.ExpiresAt) {
+				oldest = id
+			}
+		}
+		total -= s.plans[oldest].size
+		delete(s.plans, oldest)
+	}
+	s.plans[plan.plan.ID] = plan
+
+	result := plan.plan
+	return &result
+}
+
+// GetPlan 返回尚未执行且未过期的导入计划
+func (s *ImportService) GetPlan(planID string) (*models.ImportPlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan, ok := s.plans[planID]
+	if !ok || time.Now().After(plan.plan.ExpiresAt) {
+		return nil, notFoundf("导入计划不存在或已过期: %s", planID)
+	}
+	result := plan.plan
+	return &result, nil
+}
+
+// Apply 执行导入计划中选定的条目，每个计划只能执行一次
+func (s *ImportService) Apply(planID string, req models.ApplyImportRequest, author string) (*models.ImportResult, error) {
+	s.mu.Lock()
+	plan, ok := s.plans[planID]
+	if !ok || time.Now().After(plan.plan.ExpiresAt) {
+		s.mu.Unlock()
+		return nil, notFoundf("导入计划不存在或已过期: %s", planID)
+	}
+
+	known := make(map[string]bool, len(plan.plan.Entries))
+	for _, entry := range plan.plan.Entries {
+		known[entry.Entry] = true
+	}
+	for _, name := range req.Entries {
+		if !known[name] {
+			s.mu.Unlock()
+			return nil, invalidf("导入计划中没有条目: %s", name)
+		}
+	}
+	delete(s.plans, planID)
+	s.mu.Unlock()
+
+	return s.apply(plan, req.Entries, true, ImportOptions{Author: author, Force: req.Force, SkipValidation: req.SkipValidation}), nil
+}
+
+// ImportOptions 控制直接导入的行为
+type ImportOptions struct {
+	Author         string // 记录到历史版本中的修改者，为空时使用系统用户
+	Force          bool   // 为 true 时覆盖导出之后在本地修改过的文件
+	SkipValidation bool   // 为 true 时导入内容未通过格式校验也写入
+	CreateMissing  bool   // 为 true 时新建本地不存在的文件（包括上级目录），并登记清单中未登记的文件
+	Passphrase     string // 解密加密导出包的口令
+	Progress       ImportProgress
+}
+
+// ImportProgress 报告导入进度：stage 为当前阶段的描述，done/total 为该阶段已处理和总共的条目数（未知时为 0）
+type ImportProgress func(stage string, done, total int)
+
+// report 在设置了进度回调时报告进度
+func (p ImportProgress) report(stage string, done, total int) {
+	if p != nil {
+		p(stage, done, total)
+	}
+}
+
+// Import 生成导入计划并立即执行全部条目
+// 本地文件在导出之后被修改过时记为冲突并跳过，除非 opts.Force 为 true；需要用户确认的条目同样跳过
+func (s *ImportService) Import(r io.ReaderAt, size int64, opts ImportOptions) (*models.ImportResult, error) {
+	plan, err := s.buildPlan(r, size, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.apply(plan, nil, false, opts), nil
+}
+
+// buildPlan 将压缩包中的每个条目匹配到配置文件，并决定处理方式
+func (s *ImportService) buildPlan(r io.ReaderAt, size int64, opts ImportOptions) (*importPlan, error) {
+	// 加密的导出包边读边解密到临时文件，解密后的压缩包同样按安全限制读取
+	if isEncryptedBundle(r) {
+		opts.Progress.report("解密导出包", 0, 0)
+		plaintext, err := s.decryptToSpool(io.NewSectionReader(r, 0, size), opts.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			plaintext.Close()
+			os.Remove(plaintext.Name())
+		}()
+		info, err := plaintext.Stat()
+		if err != nil {
+			return nil, err
+		}
+		r, size = plaintext, info.Size()
+	}
+
+	opts.Progress.report("读取压缩包", 0, 0)
+	archiveEntries, err := readArchive(r, size, s.limits)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := readImportManifest(archiveEntries)
+	trust, err := s.signingService.Trust()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.verifySignature(archiveEntries, trust.Policy)
+	if err != nil {
+		return nil, err
+	}
+	// 签名本身有效时才能用清单中的哈希校验条目；require 策略下签名无效的导出包已被拒绝
+	signedPolicy := ""
+	if manifest != nil && (signature.Status == models.SignatureValid || signature.Status == models.SignatureUntrusted) {
+		signedPolicy = trust.Policy
+	}
+
+	trusted := signature.Status == models.SignatureValid
+	plan, err := s.planEntries(archiveEntries, manifest, signedPolicy, trusted, opts)
+	if err != nil {
+		return nil, err
+	}
+	plan.plan.Signature = signature
+	return plan, nil
+}
+
+// decryptToSpool 将加密的导出包解密到上传目录中的临时文件（权限 0600），不在内存中缓存整个压缩包
+// 解密后的大小按压缩包的安全限制检查，调用方负责关闭并删除返回的文件
+func (s *ImportService) decryptToSpool(r io.Reader, passphrase string) (*os.File, error) {
+	if err := os.MkdirAll(s.spoolDir, 0700); err != nil {
+		return nil, fmt.Errorf("无法创建上传目录: %w", err)
+	}
+	file, err := os.CreateTemp(s.spoolDir, "import-*.part")
+	if err != nil {
+		return nil, fmt.Errorf("无法创建解密临时文件: %w", err)
+	}
+	if _, err := openBundle(r, file, passphrase, s.limits.maxArchiveSize()); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+// planEntries 将条目逐个匹配到配置文件并决定处理方式
+// signedPolicy 非空时按已签名的清单校验每个条目，并按该签名策略处理不一致的条目；
+// trusted 为 false 时导出包没有受信任的签名，按清单登记新文件的条目需要用户确认导入计划后才能执行
+func (s *ImportService) planEntries(archiveEntries []archiveEntry, manifest *importManifest, signedPolicy string, trusted bool, opts ImportOptions) (*importPlan, error) {
+	registered, err := s.configService.registry.List()
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.configService.registry.Categories()
+	if err != nil {
+		return nil, err
+	}
+	categoryIDs := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		categoryIDs[category.ID] = true
+	}
+
+	id, err := newPlanID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	plan := &importPlan{plan: models.ImportPlan{
+		ID:        id,
+		CreatedAt: now,
+		ExpiresAt: now.Add(importPlanTTL),
+		Entries:   []models.ImportPlanEntry{},
+	}}
+
+	for i, archiveEntry := range archiveEntries {
+		opts.Progress.report("生成导入计划", i+1, len(archiveEntries))
+		// 跳过根目录下的配置清单及其签名，子目录中的同名文件按普通条目处理
+		if name := path.Clean(archiveEntry.name); name == manifestName || name == manifestSignatureName {
+			continue
+		}
+
+		// 条目与已签名的清单不一致时，require 策略下拒绝该条目，warn 策略下给出警告
+		signatureWarning := ""
+		if signedPolicy != "" && archiveEntry.rejected == nil {
+			if code, message := checkSignedEntry(manifest, archiveEntry); code != "" {
+				if signedPolicy == models.SignaturePolicyRequire {
+					archiveEntry.reject(code, "%s", message)
+				} else {
+					signatureWarning = message
+				}
+			}
+		}
+
+		if archiveEntry.rejected != nil {
+			plan.plan.Entries = append(plan.plan.Entries, models.ImportPlanEntry{
+				Entry:     archiveEntry.name,
+				Size:      archiveEntry.size,
+				Action:    models.ImportActionSkip,
+				Reason:    archiveEntry.rejected.Message,
+				ErrorCode: archiveEntry.rejected.Code,
+			})
+			plan.items = append(plan.items, importItem{})
+			continue
+		}
+
+		// ZIP 条目没有元数据，按配置清单中记录的权限和修改时间恢复
+		if manifest != nil && archiveEntry.meta == (entryMeta{}) {
+			if manifestFile := manifest.lookup(archiveEntry.name); manifestFile != nil {
+				archiveEntry.meta = manifestMeta(manifestFile)
+			}
+		}
+
+		target := resolveImportTarget(manifest, registered, archiveEntry.name)
+		if target.register != nil && !categoryIDs[target.register.Category] {
+			target = importTarget{reason: fmt.Sprintf("文件 %s 的分类 %s 不存在，请先创建该分类", target.file.ID, target.register.Category)}
+		}
+		if target.file != nil && target.file.Template {
+			target.source = s.configService.templates.SourcePath(target.file.ID)
+		}
+		entry, item := planEntry(manifest, target, archiveEntry, opts.CreateMissing)
+		addWarning(&entry, signatureWarning)
+		untrusted := !trusted || signatureWarning != ""
+		// 清单可以登记任意路径，只有受信任的签名者导出且内容与签名一致时才允许直接登记
+		if item.register != nil && entry.Action != models.ImportActionSkip && untrusted {
+			item.review = "导出包没有受信任的签名，按配置清单登记新文件需要先预览导入计划并确认执行"
+		}
+		// 符号链接可以指向任意位置，指向主目录之外时给出警告，没有受信任的签名时同样需要用户确认
+		if archiveEntry.meta.linkTarget != "" && entry.Path != "" {
+			if resolved, outside := symlinkOutsideHome(entry.Path, archiveEntry.meta.linkTarget); outside {
+				addWarning(&entry, fmt.Sprintf("符号链接指向主目录之外的 %s", resolved))
+				if entry.Action != models.ImportActionSkip && untrusted {
+					item.review = "导出包没有受信任的签名，创建指向主目录之外的符号链接需要先预览导入计划并确认执行"
+				}
+			}
+		}
+		plan.plan.Entries = append(plan.plan.Entries, entry)
+		plan.items = append(plan.items, item)
+	}
+	return plan, nil
+}
+
+// verifySignature 校验配置清单的签名，签名策略为 require 且签名不是 valid 时拒绝整个导出包
+func (s *ImportService) verifySignature(entries []archiveEntry, policy string) (models.SignatureStatus, error) {
+	var manifestData, signatureData []byte
+	if entry := findArchiveEntry(entries, manifestName); entry != nil {
+		manifestData = entry.content
+	}
+	if entry := findArchiveEntry(entries, manifestSignatureName); entry != nil {
+		signatureData = entry.content
+	}
+	signature := s.signingService.Verify(manifestData, signatureData)
+	if policy != models.SignaturePolicyRequire {
+		return signature, nil
+	}
+	switch signature.Status {
+	case models.SignatureUnsigned:
+		return signature, archiveErrorf(models.ArchiveErrUnsigned, "签名策略要求导出包必须签名: %s", signature.Message)
+	case models.SignatureInvalid:
+		return signature, archiveErrorf(models.ArchiveErrSignature, "%s", signature.Message)
+	case models.SignatureUntrusted:
+		return signature, archiveErrorf(models.ArchiveErrUntrusted, "%s", signature.Message)
+	}
+	return signature, nil
+}
+
+// checkSignedEntry 按已签名清单中记录的 SHA-256 或符号链接内容校验条目，一致时返回空错误码
+func checkSignedEntry(manifest *importManifest, entry archiveEntry) (string, string) {
+	manifestFile := manifest.lookup(entry.name)
+	if manifestFile == nil {
+		return models.ArchiveErrUnsignedEntry, "条目不在已签名的配置清单中"
+	}
+	if entry.meta.linkTarget != "" {
+		if entry.meta.linkTarget != manifestFile.LinkTarget {
+			return models.ArchiveErrHashMismatch, "符号链接与已签名清单中记录的不一致"
+		}
+		return "", ""
+	}
+	if manifestFile.SHA256 == "" {
+		return models.ArchiveErrUnsignedEntry, "已签名的配置清单中没有该条目的 SHA-256"
+	}
+	sum := sha256.Sum256(entry.content)
+	if hex.EncodeToString(sum[:]) != manifestFile.SHA256 {
+		return models.ArchiveErrHashMismatch, "条目内容与已签名清单中的 SHA-256 不一致"
+	}
+	return "", ""
+}
+
+// planEntry 读取单个条目并根据目标文件的当前状态决定处理方式
+func planEntry(manifest *importManifest, target importTarget, archiveEntry archiveEntry, createMissing bool) (models.ImportPlanEntry, importItem) {
+	entry := models.ImportPlanEntry{
+		Entry:   archiveEntry.name,
+		Size:    archiveEntry.size,
+		Action:  models.ImportActionSkip,
+		Warning: target.warning,
+	}
+	item := importItem{content: archiveEntry.content, meta: archiveEntry.meta, register: target.register}
+	if archiveEntry.meta.mode != 0 {
+		entry.Mode = fmt.Sprintf("%04o", archiveEntry.meta.mode)
+	}
+	entry.LinkTarget = archiveEntry.meta.linkTarget
+
+	if target.file == nil {
+		entry.Reason = target.reason
+		return entry, item
+	}
+	entry.FileID = target.file.ID
+	entry.Path = target.file.Path
+	entry.Register = target.register != nil
+
+	realPath, err := expandHome(target.file.Path)
+	if err != nil {
+		entry.Reason = err.Error()
+		return entry, item
+	}
+	if target.source != "" {
+		realPath = target.source
+	}
+
+	// 符号链接条目按链接内容比较，差异中显示为“符号链接 -> 目标”
+	incoming := string(archiveEntry.content)
+	if archiveEntry.meta.linkTarget != "" {
+		incoming = describeSymlink(archiveEntry.meta.linkTarget)
+	}
+
+	info, err := os.Lstat(realPath)
+	if os.IsNotExist(err) {
+		if !createMissing {
+			entry.Reason = "本地文件不存在（可通过 createMissing 新建）"
+			return entry, item
+		}
+		entry.Action = models.ImportActionCreate
+		entry.Diff = UnifiedDiff("/dev/null", "导入的内容 "+archiveEntry.name, "", incoming)
+		return entry, item
+	}
+	if err != nil {
+		entry.Reason = fmt.Sprintf("无法读取本地文件: %v", err)
+		return entry, item
+	}
+	if entry.Register && !createMissing && (manifest == nil || !manifest.adopt) {
+		entry.Reason = "文件未登记（可通过 createMissing 按配置清单登记）"
+		return entry, item
+	}
+	lastModified := info.ModTime()
+	entry.LastModified = &lastModified
+
+	var current string
+	if info.Mode()&fs.ModeSymlink != 0 && archiveEntry.meta.linkTarget != "" {
+		link, err := os.Readlink(realPath)
+		if err != nil {
+			entry.Reason = fmt.Sprintf("无法读取本地符号链接: %v", err)
+			return entry, item
+		}
+		current = describeSymlink(link)
+	} else {
+		content, err := os.ReadFile(realPath)
+		if err != nil {
+			entry.Reason = fmt.Sprintf("无法读取本地文件: %v", err)
+			return entry, item
+		}
+		current = string(content)
+		item.etag = contentETag(content)
+	}
+
+	if current == incoming {
+		if entry.Register {
+			entry.Action = models.ImportActionRegister
+		}
+		entry.Reason = "内容与本地文件相同"
+		return entry, item
+	}
+
+	entry.Diff = UnifiedDiff("本地文件 "+target.file.Path, "导入的内容 "+archiveEntry.name, current, incoming)
+	if localEditConflict(manifest, target.file.ID, item.etag, lastModified) {
+		entry.Action = models.ImportActionConflict
+		entry.Reason = "本地文件在导出之后被修改过"
+		return entry, item
+	}
+	entry.Action = models.ImportActionOverwrite
+	return entry, item
+}
+
+// apply 执行计划中选定的条目，selected 为空时执行全部条目；使用 opts 中的 Author、Force、SkipValidation 和 Progress
+// reviewed 为 false 时用户没有预览过计划，跳过需要确认的条目；覆盖已有文件前自动创建备份，备份失败时不写入该文件
+func (s *ImportService) apply(plan *importPlan, selected []string, reviewed bool, opts ImportOptions) *models.ImportResult {
+	chosen := make(map[string]bool, len(selected))
+	for _, name := range selected {
+		chosen[name] = true
+	}
+
+	result := &models.ImportResult{
+		Errors:      []string{},
+		EntryErrors: []models.ImportEntryError{},
+		Warnings:    []string{},
+		Signature:   plan.plan.Signature,
+		Conflicts:   []models.ImportConflict{},
+		Backups:     []models.Backup{},
+	}
+
+	if plan.plan.Signature.Status != models.SignatureValid && plan.plan.Signature.Message != "" {
+		result.Warnings = append(result.Warnings, plan.plan.Signature.Message)
+	}
+
+	for i, entry := range plan.plan.Entries {
+		opts.Progress.report("导入文件", i+1, len(plan.plan.Entries))
+		if len(chosen) > 0 && !chosen[entry.Entry] {
+			continue
+		}
+		item := plan.items[i]
+		if entry.Warning != "" {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("%s: %s", entry.Entry, entry.Warning))
+		}
+
+		switch entry.Action {
+		case models.ImportActionSkip:
+			if entry.ErrorCode != "" {
+				result.EntryErrors = append(result.EntryErrors, models.ImportEntryError{
+					Entry:   entry.Entry,
+					Code:    entry.ErrorCode,
+					Message: entry.Reason,
+				})
+			}
+			if entry.FileID == "" {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", entry.Entry, entry.Reason))
+			}
+			result.SkippedFiles++
+			continue
+		case models.ImportActionConflict:
+			if !opts.Force {
+				result.Conflicts = append(result.Conflicts, models.ImportConflict{
+					FileID:       entry.FileID,
+					Path:         entry.Path,
+					LastModified: *entry.LastModified,
+					Diff:         entry.Diff,
+				})
+				result.SkippedFiles++
+				continue
+			}
+		}
+
+		if item.review != "" && !reviewed {
+			result.EntryErrors = append(result.EntryErrors, models.ImportEntryError{
+				Entry:   entry.Entry,
+				Code:    models.ArchiveErrReviewRequired,
+				Message: item.review,
+			})
+			result.SkippedFiles++
+			continue
+		}
+
+		if err := s.applyEntry(entry, item, opts, result); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("导入 %s 到 %s 失败: %v", entry.Entry, entry.FileID, err))
+			result.SkippedFiles++
+			continue
+		}
+		result.ImportedFiles++
+	}
+
+	result.Message = fmt.Sprintf("导入完成：成功 %d 个文件，跳过 %d 个文件", result.ImportedFiles, result.SkippedFiles)
+	return result
+}
+
+// applyEntry 备份并写入单个条目，需要时先按配置清单登记文件
+func (s *ImportService) applyEntry(entry models.ImportPlanEntry, item importItem, opts ImportOptions, result *models.ImportResult) (err error) {
+	if item.register != nil {
+		if _, err := s.configService.RegisterFile(*item.register); err != nil {
+			return fmt.Errorf("登记文件失败: %w", err)
+		}
+		// 写入失败时撤销登记，保持登记表与执行前一致
+		defer func() {
+			if err != nil {
+				s.configService.UnregisterFile(item.register.ID)
+			}
+		}()
+	}
+
+	// 内容与本地文件相同时只登记，不修改文件
+	if entry.Action == models.ImportActionRegister {
+		return nil
+	}
+
+	_, realPath, err := s.configService.resolveFile(entry.FileID)
+	if err != nil {
+		return err
+	}
+
+	// 写入前再次校验 ETag，防止生成计划之后文件又被修改；强制导入时不校验
+	ifMatch := item.etag
+	if entry.Action == models.ImportActionCreate {
+		if _, err := os.Lstat(realPath); err == nil {
+			return conflictf("文件在生成导入计划之后被创建: %s", realPath)
+		}
+		if err := os.MkdirAll(filepath.Dir(realPath), 0700); err != nil {
+			return fmt.Errorf("无法创建上级目录: %w", err)
+		}
+		ifMatch = ""
+	} else if info, err := os.Lstat(realPath); err != nil || info.Mode()&fs.ModeSymlink == 0 || item.meta.linkTarget == "" {
+		// 用符号链接替换符号链接时只改变指向，没有需要备份的内容
+		backup, err := s.configService.BackupFile(entry.FileID, BackupReasonImport)
+		if err != nil {
+			return fmt.Errorf("导入前备份失败: %w", err)
+		}
+		result.Backups = append(result.Backups, *backup.Backup)
+	}
+	if opts.Force {
+		ifMatch = ""
+	}
+
+	update := UpdateOptions{
+		Author:  opts.Author,
+		Reason:  fmt.Sprintf("从 %s 导入 %s", entry.Entry, entry.FileID),
+		IfMatch: ifMatch,
+		Force:   opts.SkipValidation,
+	}
+	if item.meta.linkTarget != "" {
+		return s.configService.ReplaceWithSymlink(entry.FileID, item.meta.linkTarget, update)
+	}
+
+	written, err := s.configService.UpdateFile(entry.FileID, string(item.content), update)
+	if err != nil {
+		return err
+	}
+	return restoreFileMeta(written.WrittenPath, item.meta)
+}
+
+// addWarning 在计划条目已有的警告之后追加一条警告
+func addWarning(entry *models.ImportPlanEntry, warning string) {
+	if warning == "" {
+		return
+	}
+	if entry.Warning != "" {
+		entry.Warning += "；"
+	}
+	entry.Warning += warning
+}
+
+// symlinkOutsideHome 返回登记路径为 filePath 的符号链接指向的绝对路径，以及该路径是否在主目录之外
+// 相对的链接内容按链接所在的目录解析；只按路径判断，不解析路径中间的其他符号链接
+func symlinkOutsideHome(filePath, linkTarget string) (string, bool) {
+	resolved := linkTarget
+	if !filepath.IsAbs(resolved) {
+		linkPath, err := expandHome(filePath)
+		if err != nil {
+			return linkTarget, true
+		}
+		resolved = filepath.Join(filepath.Dir(linkPath), resolved)
+	}
+	resolved = filepath.Clean(resolved)
+	return resolved, !strings.HasPrefix(collapseHome(resolved), "~/")
+}
+
+// describeSymlink 返回符号链接在差异中和历史版本中的文本表示
+func describeSymlink(target string) string {
+	return "符号链接 -> " + target + "\n"
+}
+
+// manifestMeta 从配置清单中读取文件导出时的权限和修改时间
+// ZIP 中保存的是符号链接目标的内容，因此不恢复链接本身
+func manifestMeta(manifestFile *models.ManifestFile) entryMeta {
+	var meta entryMeta
+	if mode, err := strconv.ParseUint(manifestFile.Mode, 8, 32); err == nil {
+		meta.mode = fs.FileMode(mode).Perm()
+	}
+	if manifestFile.ModTime != nil {
+		meta.modTime = *manifestFile.ModTime
+	}
+	return meta
+}
+
+// restoreFileMeta 恢复写入后文件的权限和修改时间，未记录的项保持不变
+func restoreFileMeta(path string, meta entryMeta) error {
+	if meta.mode != 0 {
+		if err := os.Chmod(path, meta.mode); err != nil {
+			return fmt.Errorf("无法恢复文件权限: %w", err)
+		}
+	}
+	if !meta.modTime.IsZero() {
+		if err := os.Chtimes(path, meta.modTime, meta.modTime); err != nil {
+			return fmt.Errorf("无法恢复修改时间: %w", err)
+		}
+	}
+	return nil
+}
+
+// importTarget 压缩包条目匹配到的目标文件
+type importTarget struct {
+	file     *models.ConfigFile
+	register *models.RegisterFileRequest // 非空时目标尚未登记，执行前需按配置清单登记
+	warning  string
+	reason   string // 没有匹配到目标时的原因
+	source   string // 目标是模板文件时模板源文件的路径，导入的内容与源文件比较
+}
+
+// resolveImportTarget 为压缩包条目查找目标文件：优先按配置清单中的文件ID和原始路径精确匹配，
+// 清单中没有该条目时才按文件名匹配唯一的已登记文件，并给出警告
+func resolveImportTarget(manifest *importManifest, registered []models.ConfigFile, entryName string) importTarget {
+	if manifest != nil {
+		if manifestFile := manifest.lookup(entryName); manifestFile != nil {
+			return resolveManifestTarget(manifestFile, registered)
+		}
+	}
+
+	base := path.Base(entryName)
+	var matches []string
+	var target importTarget
+	for i, f := range registered {
+		if f.Name == base || path.Base(f.Path) == base {
+			matches = append(matches, f.ID)
+			target.file = &registered[i]
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return importTarget{reason: "配置清单中没有该条目，也没有文件名相同的已登记文件"}
+	case 1:
+		target.warning = fmt.Sprintf("配置清单中没有该条目，按文件名匹配到 %s，请确认", matches[0])
+		return target
+	default:
+		return importTarget{reason: fmt.Sprintf("配置清单中没有该条目，文件名同时匹配 %s，无法确定目标", strings.Join(matches, "、"))}
+	}
+}
+
+// resolveManifestTarget 按清单中的文件ID查找已登记文件，其次按原始路径查找，都没有时计划按清单登记
+func resolveManifestTarget(manifestFile *models.ManifestFile, registered []models.ConfigFile) importTarget {
+	filePath, err := normalizeRegistryPath(manifestFile.Path)
+	if err != nil {
+		return importTarget{reason: fmt.Sprintf("配置清单中的路径无效: %v", err)}
+	}
+
+	for i, f := range registered {
+		if f.ID != manifestFile.ID {
+			continue
+		}
+		target := importTarget{file: &registered[i]}
+		if f.Path != filePath {
+			target.warning = fmt.Sprintf("配置清单中的路径 %s 与登记的路径 %s 不同，将写入登记的路径", filePath, f.Path)
+		}
+		return target
+	}
+	for i, f := range registered {
+		if f.Path == filePath {
+			return importTarget{
+				file:    &registered[i],
+				warning: fmt.Sprintf("配置清单中的文件ID %s 与登记的 %s 不同，按路径匹配", manifestFile.ID, f.ID),
+			}
+		}
+	}
+
+	// 清单中的路径可能来自不受信任的导出包，主目录之外的文件需要用户特别留意
+	warning := ""
+	if !strings.HasPrefix(filePath, "~/") {
+		warning = fmt.Sprintf("将按配置清单登记主目录之外的文件 %s，请确认", filePath)
+	}
+	file := models.ConfigFile{
+		ID:          manifestFile.ID,
+		Name:        manifestFile.Name,
+		Path:        filePath,
+		Category:    manifestFile.Category,
+		Description: manifestFile.Description,
+		Format:      manifestFile.Format,
+		Template:    manifestFile.Template,
+	}
+	return importTarget{
+		file:    &file,
+		warning: warning,
+		register: &models.RegisterFileRequest{
+			ID:          file.ID,
+			Name:        file.Name,
+			Path:        file.Path,
+			Category:    file.Category,
+			Description: file.Description,
+			Format:      file.Format,
+			Template:    file.Template,
+		},
+	}
+}
+
+// newPlanID 生成随机的导入计划ID
+func newPlanID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("无法生成导入计划ID: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// importManifest 导入时从配置清单中读取的信息
+type importManifest struct {
+	models.ExportManifest
+	adopt bool // 从其他 dotfile 管理工具导入时为 true，登记本地已存在的文件不需要 createMissing
+}
+
+// lookup 返回配置清单中与压缩包条目对应的文件，没有时返回 nil
+func (m *importManifest) lookup(entryName string) *models.ManifestFile {
+	for i := range m.Files {
+		if m.Files[i].ArchivePath == entryName {
+			return &m.Files[i]
+		}
+	}
+
+	// 旧版清单没有 archivePath，按旧版的导出规则“分类名称/文件名”还原条目路径
+	categoryNames := make(map[string]string, len(m.Categories))
+	for _, category := range m.Categories {
+		categoryNames[category.ID] = category.Name
+	}
+	for i, f := range m.Files {
+		if f.ArchivePath != "" {
+			continue
+		}
+		categoryName, ok := categoryNames[f.Category]
+		if !ok {
+			categoryName = "其他"
+		}
+		if categoryName+"/"+strings.ReplaceAll(f.Name, "/", "_") == entryName {
+			return &m.Files[i]
+		}
+	}
+	return nil
+}
+
+// exportedTime 返回压缩包的导出时间，旧版清单只有本地时间格式的 exportTime
+func (m *importManifest) exportedTime() (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, m.ExportedAt); err == nil {
+		return t, true
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", m.ExportTime, time.Local); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// readImportManifest 读取压缩包中的配置清单，不存在、被拒绝或无法解析时返回 nil
+func readImportManifest(entries []archiveEntry) *importManifest {
+	entry := findArchiveEntry(entries, manifestName)
+	if entry == nil {
+		return nil
+	}
+
+	var manifest importManifest
+	if err := json.Unmarshal(entry.content, &manifest); err != nil {
+		return nil
+	}
+	return &manifest
+}
+
+// findArchiveEntry 返回压缩包根目录下名为 name 的条目，该条目被拒绝或不存在时返回 nil
+func findArchiveEntry(entries []archiveEntry, name string) *archiveEntry {
+	for i := range entries {
+		if path.Clean(entries[i].name) != name {
+			continue
+		}
+		if entries[i].rejected != nil {
+			return nil
+		}
+		return &entries[i]
+	}
+	return nil
+}
+
+// localEditConflict 判断本地文件是否在导出之后被修改过
+// 清单中记录的导出 ETag 与当前一致时不算修改；旧版清单没有 ETag 时比较修改时间与导出时间
+func localEditConflict(manifest *importManifest, fileID, currentETag string, lastModified time.Time) bool {
+	if manifest == nil {
+		return false
+	}
+
+	for _, f := range manifest.Files {
+		if f.ID == fileID && f.ETag != "" && f.ETag == currentETag {
+			return false
+		}
+	}
+
+	exportedAt, ok := manifest.exportedTime()
+	return ok && lastModified.After(exportedAt)
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestImportPlanAndApply(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	writeTestFile(t, filepath.Join(home, ".bashrc"), "echo old\n")
+	writeTestFile(t, filepath.Join(home, ".gitconfig"), "[user]\n\tname = Alice\n")
+	writeTestFile(t, filepath.Join(home, ".vimrc"), "set number\n")
+
+	// 清单的导出时间早于本地文件的修改时间：.bashrc 的 ETag 与导出时相同，
+	// .vimrc 的 ETag 与导出时不同，应判定为冲突
+	manifest, _ := json.Marshal(models.ExportManifest{
+		ExportedAt: "2000-01-01T00:00:00Z",
+		Files: []models.ManifestFile{
+			{ConfigFile: models.ConfigFile{ID: "bashrc", Path: "~/.bashrc", ETag: contentETag([]byte("echo old\n"))}, ArchivePath: "Shell 配置/.bashrc"},
+			{ConfigFile: models.ConfigFile{ID: "gitconfig", Path: "~/.gitconfig"}, ArchivePath: "Git 配置/.gitconfig"},
+			{ConfigFile: models.ConfigFile{ID: "vimrc", Path: "~/.vimrc", ETag: `"stale"`}, ArchivePath: "编辑器配置/.vimrc"},
+		},
+	})
+	archive := buildTestZip(t, [][2]string{
+		{"Shell 配置/.bashrc", "echo new\n"},
+		{"Git 配置/.gitconfig", "[user]\n\tname = Alice\n"},
+		{"编辑器配置/.vimrc", "set nonumber\n"},
+		{"其他/unknown.conf", "x\n"},
+		{manifestName, string(manifest)},
+	})
+
+	configService := NewConfigService()
+	importService := NewImportService(configService, NewSigningService())
+
+	plan, err := importService.Plan(archive, archive.Size(), ImportOptions{})
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	actions := map[string]string{}
+	for _, entry := range plan.Entries {
+		actions[entry.Entry] = entry.Action
+	}
+	want := map[string]string{
+		"Shell 配置/.bashrc":  models.ImportActionOverwrite,
+		"Git 配置/.gitconfig": models.ImportActionSkip,
+		"编辑器配置/.vimrc":      models.ImportActionConflict,
+		"其他/unknown.conf":   models.ImportActionSkip,
+	}
+	for entry, action := range want {
+		if actions[entry] != action {
+			t.Errorf("%s 的处理方式错误: got %q want %q", entry, actions[entry], action)
+		}
+	}
+	if content, _ := os.ReadFile(filepath.Join(home, ".bashrc")); string(content) != "echo old\n" {
+		t.Fatalf("生成计划不应修改文件")
+	}
+
+	// 只执行 .bashrc 和冲突的 .vimrc，不强制时 .vimrc 应被跳过
+	result, err := importService.Apply(plan.ID, models.ApplyImportRequest{
+		Entries: []string{"Shell 配置/.bashrc", "编辑器配置/.vimrc"},
+	}, "tester")
+	if err != nil {
+		t.Fatalf("执行导入计划失败: %v", err)
+	}
+	if result.ImportedFiles != 1 || len(result.Conflicts) != 1 || len(result.Backups) != 1 {
+		t.Fatalf("导入结果错误: %+v", result)
+	}
+	if result.Backups[0].FileID != "bashrc" || result.Backups[0].Reason != BackupReasonImport {
+		t.Errorf("应在覆盖前自动备份: %+v", result.Backups[0])
+	}
+	if content, _ := os.ReadFile(filepath.Join(home, ".bashrc")); string(content) != "echo new\n" {
+		t.Errorf(".bashrc 未被导入, got %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(home, ".vimrc")); string(content) != "set number\n" {
+		t.Errorf("冲突的文件不应被覆盖, got %q", content)
+	}
+
+	// 计划只能执行一次
+	if _, err := importService.Apply(plan.ID, models.ApplyImportRequest{}, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("重复执行计划应返回 ErrNotFound, got %v", err)
+	}
+}
+
+func TestImportManifestMatching(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	writeTestFile(t, filepath.Join(home, ".zshrc"), "echo old\n")
+
+	// tmux 只在清单中登记，本地不存在；.zshrc 不在清单中，只能按文件名匹配
+	manifest, _ := json.Marshal(models.ExportManifest{
+		Files: []models.ManifestFile{
+			{ConfigFile: models.ConfigFile{ID: "tmux", Name: "tmux.conf", Path: "~/.config/tmux/tmux.conf", Category: "app"}, ArchivePath: "应用配置/tmux.conf"},
+			{ConfigFile: models.ConfigFile{ID: "hosts", Name: "hosts", Path: "/etc/hosts", Category: "system"}, ArchivePath: "系统配置/hosts"},
+		},
+	})
+	archive := buildTestZip(t, [][2]string{
+		{"应用配置/tmux.conf", "set -g mouse on\n"},
+		{"杂项/.zshrc", "echo new\n"},
+		{"系统配置/hosts", "127.0.0.1 localhost\n"},
+		{"杂项/" + manifestName, "{}"},
+		{manifestName, string(manifest)},
+	})
+
+	configService := NewConfigService()
+	importService := NewImportService(configService, NewSigningService())
+
+	plan, err := importService.Plan(archive, archive.Size(), ImportOptions{})
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	entries := map[string]models.ImportPlanEntry{}
+	for _, entry := range plan.Entries {
+		entries[entry.Entry] = entry
+	}
+	if tmux := entries["应用配置/tmux.conf"]; tmux.FileID != "tmux" || tmux.Action != models.ImportActionSkip || !tmux.Register {
+		t.Errorf("未指定 createMissing 时应跳过不存在的文件: %+v", tmux)
+	}
+	if zshrc := entries["杂项/.zshrc"]; zshrc.FileID != "zshrc" || zshrc.Action != models.ImportActionOverwrite || zshrc.Warning == "" {
+		t.Errorf("按文件名匹配时应给出警告: %+v", zshrc)
+	}
+
+	// 只有根目录下的配置清单才是清单，子目录中的同名文件按普通条目处理
+	if nested, ok := entries["杂项/"+manifestName]; !ok || nested.Action != models.ImportActionSkip {
+		t.Errorf("子目录中的配置清单应作为普通条目: %+v", nested)
+	}
+	if hosts := entries["系统配置/hosts"]; !hosts.Register || !strings.Contains(hosts.Warning, "/etc/hosts") {
+		t.Errorf("登记主目录之外的文件时应给出警告: %+v", hosts)
+	}
+
+	// 清单没有签名，直接导入时不按清单登记新文件
+	result, err := importService.Import(archive, archive.Size(), ImportOptions{CreateMissing: true})
+	if err != nil {
+		t.Fatalf("导入失败: %v", err)
+	}
+	if result.ImportedFiles != 1 || len(result.EntryErrors) != 2 || result.EntryErrors[0].Code != models.ArchiveErrReviewRequired {
+		t.Fatalf("导入结果错误: %+v", result)
+	}
+	if _, err := configService.GetFileByID("tmux"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("未确认导入计划时不应登记新文件: %v", err)
+	}
+
+	// 预览计划并确认执行后才登记
+	plan, err = importService.Plan(archive, archive.Size(), ImportOptions{CreateMissing: true})
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	result, err = importService.Apply(plan.ID, models.ApplyImportRequest{Entries: []string{"应用配置/tmux.conf"}}, "")
+	if err != nil {
+		t.Fatalf("执行导入计划失败: %v", err)
+	}
+	// 手工生成的清单没有签名，结果中有一条未签名的警告
+	if result.ImportedFiles != 1 || len(result.Warnings) != 1 || len(result.Errors) != 0 || len(result.EntryErrors) != 0 {
+		t.Fatalf("导入结果错误: %+v", result)
+	}
+	if content, _ := os.ReadFile(filepath.Join(home, ".config", "tmux", "tmux.conf")); string(content) != "set -g mouse on\n" {
+		t.Errorf("应新建文件及上级目录, got %q", content)
+	}
+	if _, err := configService.GetFileByID("tmux"); err != nil {
+		t.Errorf("应按配置清单登记新文件: %v", err)
+	}
+}
+
+// buildTestZip 按给定顺序生成包含指定条目的压缩包
+func TestImportSkipValidation(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	gitconfig := filepath.Join(home, ".gitconfig")
+	writeTestFile(t, gitconfig, "[user]\n\tname = Alice\n")
+	manifest, _ := json.Marshal(models.ExportManifest{
+		Files: []models.ManifestFile{{ConfigFile: models.ConfigFile{ID: "gitconfig", Path: "~/.gitconfig"}, ArchivePath: ".gitconfig"}},
+	})
+	archive := buildTestZip(t, [][2]string{{".gitconfig", "[user\n"}, {manifestName, string(manifest)}})
+	importService := NewImportService(NewConfigService(), NewSigningService())
+
+	// 未通过格式校验的条目记为错误，skipValidation 时照常写入
+	for _, skip := range []bool{false, true} {
+		plan, err := importService.Plan(archive, archive.Size(), ImportOptions{})
+		if err != nil {
+			t.Fatalf("生成导入计划失败: %v", err)
+		}
+		result, err := importService.Apply(plan.ID, models.ApplyImportRequest{Force: true, SkipValidation: skip}, "")
+		if err != nil {
+			t.Fatalf("执行导入计划失败: %v", err)
+		}
+		if imported := result.ImportedFiles == 1; imported != skip {
+			t.Errorf("skipValidation=%v 时导入结果错误: %+v", skip, result)
+		}
+	}
+	if content, _ := os.ReadFile(gitconfig); string(content) != "[user\n" {
+		t.Errorf("skipValidation 时应写入导入内容, got %q", content)
+	}
+}
+
+func TestStorePlanLimits(t *testing.T) {
+	service := NewImportService(nil, nil)
+	newPlan := func(id string, size int, age time.Duration) *importPlan {
+		return &importPlan{
+			plan:  models.ImportPlan{ID: id, Entries: []models.ImportPlanEntry{{}}, ExpiresAt: time.Now().Add(importPlanTTL - age)},
+			items: []importItem{{content: make([]byte, size)}},
+		}
+	}
+
+	// 数量超出上限时丢弃最早的计划
+	for i := 0; i < maxStoredImportPlans+1; i++ {
+		service.storePlan(newPlan(fmt.Sprintf("plan-%d", i), 1, time.Duration(maxStoredImportPlans-i)*time.Second))
+	}
+	if len(service.plans) != maxStoredImportPlans {
+		t.Errorf("保留的计划数量错误: %d", len(service.plans))
+	}
+	if _, err := service.GetPlan("plan-0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("最早的计划应被丢弃, got %v", err)
+	}
+
+	// 内容总大小超出上限时只保留新计划
+	service.storePlan(newPlan("large", maxStoredImportPlanBytes, 0))
+	if _, err := service.GetPlan("large"); err != nil || len(service.plans) != 1 {
+		t.Errorf("超出总大小时应丢弃其他计划: %v, %d", err, len(service.plans))
+	}
+}
+
+func buildTestZip(t testing.TB, entries [][2]string) *bytes.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		w, err := zipWriter.Create(entry[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
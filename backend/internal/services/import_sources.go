@@ -0,0 +1,620 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// sourceFile 从其他 dotfile 管理工具读取的单个文件
+type sourceFile struct {
+	entry  string // 文件在源目录或仓库中的相对路径，作为导入计划的条目名称
+	target string // 目标文件的绝对路径
+	entryMeta
+	content []byte
+	skip    string // 非空时不导入该文件的原因
+}
+
+// PlanSource 读取 chezmoi、stow 或 yadm 的本地目录，将其中的文件映射为配置文件并生成导入计划
+// 计划与导入压缩包的计划相同，通过 Apply 执行；未登记的文件在执行时按计划登记
+func (s *ImportService) PlanSource(source models.ImportSource) (*models.ImportPlan, error) {
+	root, err := sourceRoot(source)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []sourceFile
+	switch source.Type {
+	case models.ImportSourceChezmoi:
+		files, err = readChezmoiSource(root, s.limits)
+	case models.ImportSourceStow:
+		target := filepath.Dir(root)
+		if source.Target != "" {
+			if target, err = expandHome(source.Target); err != nil {
+				return nil, err
+			}
+			if !filepath.IsAbs(target) {
+				return nil, invalidf("stow 目标目录必须是绝对路径或以 ~ 开头: %s", source.Target)
+			}
+		}
+		files, err = readStowSource(root, target, source.Dotfiles, s.limits)
+	case models.ImportSourceYadm:
+		files, err = readGitSource(root, s.limits)
+	default:
+		return nil, invalidf("不支持的导入来源: %s（可选 %s、%s、%s）", source.Type, models.ImportSourceChezmoi, models.ImportSourceStow, models.ImportSourceYadm)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries, manifest, err := s.sourceEntries(source.Type, files)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := s.planEntries(entries, manifest, "", false, ImportOptions{CreateMissing: source.CreateMissing})
+	if err != nil {
+		return nil, err
+	}
+	plan.plan.Signature = models.SignatureStatus{Status: models.SignatureUnsigned}
+	source.Path = collapseHome(root)
+	plan.plan.Source = &source
+	return s.storePlan(plan), nil
+}
+
+// sourceRoot 返回来源目录的绝对路径，未指定时使用各工具的默认位置
+func sourceRoot(source models.ImportSource) (string, error) {
+	dir := source.Path
+	if dir == "" {
+		switch source.Type {
+		case models.ImportSourceChezmoi:
+			dir = filepath.Join(xdgDataHome(), "chezmoi")
+		case models.ImportSourceYadm:
+			dir = filepath.Join(xdgDataHome(), "yadm", "repo.git")
+		default:
+			return "", invalidf("必须指定 %s 目录", source.Type)
+		}
+	}
+	dir, err := expandHome(dir)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(dir) {
+		return "", invalidf("路径必须是绝对路径或以 ~ 开头: %s", source.Path)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", invalidf("无法读取目录 %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return "", invalidf("不是目录: %s", dir)
+	}
+	return filepath.Clean(dir), nil
+}
+
+// sourceEntries 将来源文件转换为导入条目，并生成记录目标路径、文件ID和分类的配置清单
+// 目标路径已登记时使用登记的文件ID，否则按已知应用的签名表或文件名生成不冲突的ID
+func (s *ImportService) sourceEntries(sourceType string, files []sourceFile) ([]archiveEntry, *importManifest, error) {
+	registered, err := s.configService.registry.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	categories, err := s.configService.registry.Categories()
+	if err != nil {
+		return nil, nil, err
+	}
+	categoryIDs := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		categoryIDs[category.ID] = true
+	}
+	registeredIDs := make(map[string]string, len(registered))
+	usedIDs := make(map[string]bool, len(registered))
+	for _, file := range registered {
+		registeredIDs[file.Path] = file.ID
+		usedIDs[file.ID] = true
+	}
+
+	manifest := &importManifest{ExportManifest: models.ExportManifest{Categories: categories}, adopt: true}
+	entries := make([]archiveEntry, 0, len(files))
+	targets := make(map[string]string, len(files))
+	for _, file := range files {
+		entry := archiveEntry{name: file.entry, size: int64(len(file.content)), content: file.content, meta: file.entryMeta}
+		filePath := collapseHome(file.target)
+		if file.skip != "" {
+			entry.content = nil
+			entry.reject(models.ArchiveErrUnsupported, "%s", file.skip)
+			entries = append(entries, entry)
+			continue
+		}
+		if other, ok := targets[filePath]; ok {
+			entry.content = nil
+			entry.reject(models.ArchiveErrDuplicateName, "目标路径 %s 与 %s 相同", filePath, other)
+			entries = append(entries, entry)
+			continue
+		}
+		targets[filePath] = file.entry
+		entries = append(entries, entry)
+
+		id, ok := registeredIDs[filePath]
+		category, description := sourceCategory(filePath), fmt.Sprintf("从 %s 导入", sourceType)
+		if sig := lookupSignature(filePath); sig != nil {
+			category, description = sig.Category, sig.Description
+			if !ok && !usedIDs[sig.ID] {
+				id, ok = sig.ID, true
+			}
+		}
+		if !ok {
+			id = uniqueFileID(slugifyID(strings.TrimPrefix(filePath, "~/")), usedIDs)
+		}
+		usedIDs[id] = true
+		if !categoryIDs[category] {
+			category = "app"
+		}
+
+		manifest.Files = append(manifest.Files, models.ManifestFile{
+			ConfigFile: models.ConfigFile{
+				ID:          id,
+				Name:        path.Base(filepath.ToSlash(filePath)),
+				Path:        filePath,
+				Category:    category,
+				Description: description,
+			},
+			ArchivePath: file.entry,
+		})
+	}
+	manifest.TotalFiles = len(manifest.Files)
+	return entries, manifest, nil
+}
+
+// lookupSignature 按目标路径在已知应用的签名表中查找
+func lookupSignature(filePath string) *appSignature {
+	for i, sig := range knownSignatures {
+		sigPath := sig.Path
+		if !strings.HasPrefix(sigPath, "~/") {
+			sigPath = collapseHome(filepath.Join(xdgConfigHome(), sig.Path))
+		}
+		if sigPath == filePath {
+			return &knownSignatures[i]
+		}
+	}
+	return nil
+}
+
+// sourceCategory 签名表中没有的文件按路径推测分类
+func sourceCategory(filePath string) string {
+	name := strings.ToLower(filePath)
+	switch {
+	case strings.Contains(name, "/.ssh/"):
+		return "ssh"
+	case strings.Contains(name, "git"):
+		return "git"
+	case strings.Contains(name, "vim") || strings.Contains(name, "emacs") || strings.Contains(name, "helix") || strings.Contains(name, "editorconfig"):
+		return "editor"
+	case strings.Contains(name, "bash") || strings.Contains(name, "zsh") || strings.Contains(name, "fish") ||
+		strings.HasSuffix(name, "/.profile") || strings.HasSuffix(name, "/.inputrc"):
+		return "shell"
+	}
+	return "app"
+}
+
+// uniqueFileID 在ID已被使用时追加序号
+func uniqueFileID(id string, used map[string]bool) string {
+	if len(id) > 56 {
+		id = strings.TrimRight(id[len(id)-56:], ".-_")
+		id = strings.TrimLeft(id, ".-_")
+	}
+	if id == "" {
+		id = "file"
+	}
+	candidate := id
+	for i := 2; used[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d", id, i)
+	}
+	return candidate
+}
+
+// sourceReader 按安全限制读取来源目录中的文件
+type sourceReader struct {
+	limits archiveLimits
+	total  int64
+	files  []sourceFile
+}
+
+// add 读取普通文件的内容或符号链接的内容，超过大小限制的文件被跳过
+func (r *sourceReader) add(file sourceFile, realPath string, d fs.DirEntry) error {
+	if len(r.files) >= r.limits.maxEntries {
+		return archiveErrorf(models.ArchiveErrTooManyEntries, "文件数量超过上限 %d", r.limits.maxEntries)
+	}
+	defer func() { r.files = append(r.files, file) }()
+	if file.skip != "" {
+		return nil
+	}
+
+	if d.Type()&fs.ModeSymlink != 0 {
+		link, err := os.Readlink(realPath)
+		if err != nil {
+			file.skip = fmt.Sprintf("无法读取符号链接: %v", err)
+			return nil
+		}
+		file.linkTarget = link
+		file.mode = 0
+		return nil
+	}
+	if !d.Type().IsRegular() {
+		file.skip = fmt.Sprintf("不支持的文件类型: %s", d.Type())
+		return nil
+	}
+
+	info, err := d.Info()
+	if err != nil {
+		file.skip = fmt.Sprintf("无法读取文件: %v", err)
+		return nil
+	}
+	if info.Size() > r.limits.maxEntrySize {
+		file.skip = fmt.Sprintf("文件 %d 字节，超过上限 %d 字节", info.Size(), r.limits.maxEntrySize)
+		return nil
+	}
+	if r.total+info.Size() > r.limits.maxTotalSize {
+		return archiveErrorf(models.ArchiveErrTotalTooLarge, "文件总大小超过上限 %d 字节", r.limits.maxTotalSize)
+	}
+	content, err := os.ReadFile(realPath)
+	if err != nil {
+		file.skip = fmt.Sprintf("无法读取文件: %v", err)
+		return nil
+	}
+	r.total += int64(len(content))
+	file.content = content
+	file.modTime = info.ModTime()
+	if file.mode == 0 {
+		file.mode = info.Mode().Perm()
+	}
+	return nil
+}
+
+// readChezmoiSource 读取 chezmoi 源目录：按文件名前缀还原目标文件名和权限，
+// 以 . 开头的文件（.chezmoi* 等）被忽略，模板、脚本和加密文件不导入
+func readChezmoiSource(root string, limits archiveLimits) ([]sourceFile, error) {
+	// .chezmoiroot 指定源状态所在的子目录，不能指向源目录之外
+	if content, err := os.ReadFile(filepath.Join(root, ".chezmoiroot")); err == nil {
+		if sub := strings.TrimSpace(string(content)); sub != "" {
+			cleaned := filepath.Clean(filepath.FromSlash(sub))
+			if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+				return nil, invalidf(".chezmoiroot 必须是源目录中的相对路径: %s", sub)
+			}
+			root = filepath.Join(root, cleaned)
+		}
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("无法获取用户主目录: %w", err)
+	}
+
+	reader := &sourceReader{limits: limits}
+	err = filepath.WalkDir(root, func(realPath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if realPath == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if _, skip := chezmoiDirName(d.Name()); skip != "" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, realPath)
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		for i := range parts[:len(parts)-1] {
+			parts[i], _ = chezmoiDirName(parts[i])
+		}
+		name, attrs := parseChezmoiFileName(parts[len(parts)-1])
+		parts[len(parts)-1] = name
+
+		file := sourceFile{entry: filepath.ToSlash(rel), target: filepath.Join(homeDir, filepath.FromSlash(strings.Join(parts, "/")))}
+		file.skip = attrs.skip
+		file.mode = attrs.mode
+		if err := reader.add(file, realPath, d); err != nil {
+			return err
+		}
+		// symlink_ 文件的内容是链接目标
+		if attrs.symlink {
+			added := &reader.files[len(reader.files)-1]
+			if added.skip == "" {
+				added.linkTarget = strings.TrimSpace(string(added.content))
+				added.content = nil
+				added.mode = 0
+				if added.linkTarget == "" {
+					added.skip = "符号链接的目标为空"
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, sourceWalkError(err)
+	}
+	return reader.files, nil
+}
+
+// chezmoiAttributes 从 chezmoi 文件名前缀和后缀解析出的属性
+type chezmoiAttributes struct {
+	mode    fs.FileMode
+	symlink bool
+	skip    string
+}
+
+// parseChezmoiFileName 去掉 chezmoi 文件名的属性前缀和后缀，返回目标文件名
+func parseChezmoiFileName(name string) (string, chezmoiAttributes) {
+	attrs := chezmoiAttributes{mode: 0644}
+	if strings.HasPrefix(name, "run_") {
+		attrs.skip = "chezmoi 脚本不会导入"
+		return name, attrs
+	}
+
+	private, readonly := false, false
+	for matched := true; matched; {
+		matched = false
+		for _, prefix := range []string{"create_", "modify_", "remove_", "encrypted_", "private_", "readonly_", "empty_", "executable_", "symlink_", "once_", "onchange_"} {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			name, matched = strings.TrimPrefix(name, prefix), true
+			switch prefix {
+			case "modify_", "remove_":
+				attrs.skip = fmt.Sprintf("chezmoi 的 %s 文件不会导入", strings.TrimSuffix(prefix, "_"))
+			case "encrypted_":
+				attrs.skip = "加密的文件需要先用 chezmoi 解密"
+			case "private_":
+				private = true
+			case "readonly_":
+				readonly = true
+			case "executable_":
+				attrs.mode = 0755
+			case "symlink_":
+				attrs.symlink = true
+			}
+			break
+		}
+	}
+	if private {
+		attrs.mode &^= 0077
+	}
+	if readonly {
+		attrs.mode &^= 0222
+	}
+
+	if strings.HasPrefix(name, "literal_") {
+		name = strings.TrimPrefix(name, "literal_")
+	} else if strings.HasPrefix(name, "dot_") {
+		name = "." + strings.TrimPrefix(name, "dot_")
+	}
+	switch {
+	case strings.HasSuffix(name, ".tmpl"):
+		if attrs.skip == "" {
+			attrs.skip = "chezmoi 模板需要先用 chezmoi 渲染"
+		}
+	case strings.HasSuffix(name, ".literal"):
+		name = strings.TrimSuffix(name, ".literal")
+	}
+	return name, attrs
+}
+
+// chezmoiDirName 去掉 chezmoi 目录名的属性前缀，remove_ 和 external_ 目录不导入
+func chezmoiDirName(name string) (string, string) {
+	for matched := true; matched; {
+		matched = false
+		for _, prefix := range []string{"exact_", "private_", "readonly_", "remove_", "external_"} {
+			if strings.HasPrefix(name, prefix) {
+				name, matched = strings.TrimPrefix(name, prefix), true
+				if prefix == "remove_" || prefix == "external_" {
+					return name, "chezmoi 的 " + strings.TrimSuffix(prefix, "_") + " 目录不会导入"
+				}
+				break
+			}
+		}
+	}
+	if strings.HasPrefix(name, "literal_") {
+		return strings.TrimPrefix(name, "literal_"), ""
+	}
+	if strings.HasPrefix(name, "dot_") {
+		return "." + strings.TrimPrefix(name, "dot_"), ""
+	}
+	return name, ""
+}
+
+// stowIgnored stow 默认忽略的文件名，^ 开头的只在软件包顶层忽略
+var stowIgnored = []string{".git", ".gitignore", ".gitmodules", ".hg", ".svn", "CVS", "RCS", "_darcs", ".stow-local-ignore", "^README", "^LICENSE", "^COPYING"}
+
+// isStowIgnored 判断文件是否按 stow 的默认规则忽略
+func isStowIgnored(name string, topLevel bool) bool {
+	if strings.HasSuffix(name, "~") || (strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#")) {
+		return true
+	}
+	for _, pattern := range stowIgnored {
+		if strings.HasPrefix(pattern, "^") {
+			if topLevel && strings.HasPrefix(name, pattern[1:]) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// readStowSource 读取 stow 目录：每个子目录是一个软件包，包内的路径相对于 target
+// dotfiles 为 true 时按 stow --dotfiles 将路径中的 dot- 前缀还原为 .
+func readStowSource(root, target string, dotfiles bool, limits archiveLimits) ([]sourceFile, error) {
+	packages, err := os.ReadDir(root)
+	if err != nil {
+		return nil, invalidf("无法读取 stow 目录: %v", err)
+	}
+
+	reader := &sourceReader{limits: limits}
+	for _, pkg := range packages {
+		if !pkg.IsDir() || strings.HasPrefix(pkg.Name(), ".") {
+			continue
+		}
+		pkgRoot := filepath.Join(root, pkg.Name())
+		err := filepath.WalkDir(pkgRoot, func(realPath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if realPath == pkgRoot {
+				return nil
+			}
+			rel, _ := filepath.Rel(pkgRoot, realPath)
+			if isStowIgnored(d.Name(), !strings.ContainsRune(rel, filepath.Separator)) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if dotfiles {
+				for i, part := range parts {
+					if strings.HasPrefix(part, "dot-") {
+						parts[i] = "." + strings.TrimPrefix(part, "dot-")
+					}
+				}
+			}
+			file := sourceFile{
+				entry:  pkg.Name() + "/" + filepath.ToSlash(rel),
+				target: filepath.Join(target, filepath.FromSlash(strings.Join(parts, "/"))),
+			}
+			return reader.add(file, realPath, d)
+		})
+		if err != nil {
+			return nil, sourceWalkError(err)
+		}
+	}
+	return reader.files, nil
+}
+
+// gitFilterOverrides 返回禁用仓库配置中所有 filter 驱动的 -c 参数
+// git archive 会对设置了 filter 属性的文件执行驱动的 smudge 或 process 命令，导入的仓库不可信，不能运行这些命令；
+// 这些文件（如 git-lfs、git-crypt 管理的文件）按仓库中保存的内容导入
+func gitFilterOverrides(gitDir string, env []string) ([]string, error) {
+	cmd := exec.Command("git", "--git-dir="+gitDir, "config", "-z", "--get-regexp", `^filter\.`)
+	cmd.Env = env
+	output, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		// 没有任何 filter 配置
+		return nil, nil
+	}
+	if err != nil {
+		return nil, invalidf("无法读取 git 仓库 %s 的配置: %v", gitDir, err)
+	}
+
+	var args []string
+	seen := make(map[string]bool)
+	for _, record := range strings.Split(string(output), "\x00") {
+		key, _, _ := strings.Cut(record, "\n")
+		name := strings.TrimPrefix(key, "filter.")
+		dot := strings.LastIndex(name, ".")
+		if dot <= 0 || seen[name[:dot]] {
+			continue
+		}
+		name = name[:dot]
+		seen[name] = true
+		// -c 在第一个 = 处分隔键和值，名称含有 = 的驱动无法覆盖
+		if strings.Contains(name, "=") {
+			return nil, invalidf("git 仓库 %s 配置了名称含有 = 的 filter 驱动，无法安全读取", gitDir)
+		}
+		args = append(args, "-c", "filter."+name+".smudge=", "-c", "filter."+name+".process=", "-c", "filter."+name+".required=false")
+	}
+	return args, nil
+}
+
+// readGitSource 读取 yadm 仓库或以主目录为工作区的 git 仓库中 HEAD 的文件
+// 通过 git archive 导出未压缩的 tar 流，与导入压缩包使用相同的安全限制；yadm 自身的配置和备用文件（##条件）不导入
+func readGitSource(root string, limits archiveLimits) ([]sourceFile, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("无法获取用户主目录: %w", err)
+	}
+
+	gitDir := root
+	if info, err := os.Stat(filepath.Join(root, ".git")); err == nil && info.IsDir() {
+		gitDir = filepath.Join(root, ".git")
+	}
+	env := append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "GIT_CONFIG_GLOBAL="+os.DevNull, "GIT_TERMINAL_PROMPT=0")
+	overrides, err := gitFilterOverrides(gitDir, env)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"--git-dir=" + gitDir, "-c", "tar.umask=0022"}, overrides...)
+	cmd := exec.Command("git", append(args, "archive", "--format=tar", "HEAD")...)
+	cmd.Env = env
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("无法执行 git: %w", err)
+	}
+
+	entries, readErr := readTarArchive(stdout, limits)
+	if readErr != nil {
+		// 超过限制时不再读取剩余输出，直接结束 git；git 自身出错时输出不是完整的 tar 流，报告 git 的错误
+		cmd.Process.Kill()
+		if err := cmd.Wait(); err != nil && stderr.Len() > 0 {
+			return nil, invalidf("无法读取 git 仓库 %s: %s", root, strings.TrimSpace(stderr.String()))
+		}
+		return nil, readErr
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, invalidf("无法读取 git 仓库 %s: %s", root, strings.TrimSpace(stderr.String()))
+	}
+
+	files := make([]sourceFile, 0, len(entries))
+	for _, entry := range entries {
+		file := sourceFile{
+			entry:     entry.name,
+			target:    filepath.Join(homeDir, filepath.FromSlash(entry.name)),
+			entryMeta: entry.meta,
+			content:   entry.content,
+		}
+		switch {
+		case entry.rejected != nil:
+			file.skip = entry.rejected.Message
+		case strings.HasPrefix(entry.name, ".config/yadm/") || strings.HasPrefix(entry.name, ".local/share/yadm/"):
+			file.skip = "yadm 自身的配置不会导入"
+		case strings.Contains(path.Base(entry.name), "##"):
+			file.skip = "yadm 备用文件（##条件）需要由 yadm 选择"
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// sourceWalkError 将遍历目录时的错误转换为业务错误
+func sourceWalkError(err error) error {
+	var archiveErr *ArchiveError
+	if errors.As(err, &archiveErr) {
+		return err
+	}
+	return invalidf("无法读取目录: %v", err)
+}
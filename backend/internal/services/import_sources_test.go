@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestImportFromSources(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	configService := NewConfigService()
+	importService := NewImportService(configService, NewSigningService())
+	planEntries := func(plan *models.ImportPlan) map[string]models.ImportPlanEntry {
+		entries := make(map[string]models.ImportPlanEntry, len(plan.Entries))
+		for _, entry := range plan.Entries {
+			entries[entry.Entry] = entry
+		}
+		return entries
+	}
+
+	// chezmoi：按前缀还原文件名和权限，模板和脚本不导入
+	source := filepath.Join(home, ".local", "share", "chezmoi")
+	writeTestFile(t, filepath.Join(home, ".bash_aliases"), "alias ll='ls -l'\n")
+	writeTestFile(t, filepath.Join(source, "dot_bash_aliases"), "alias ll='ls -l'\n")
+	writeTestFile(t, filepath.Join(source, "private_dot_ssh", "private_config"), "Host *\n")
+	writeTestFile(t, filepath.Join(source, "dot_local", "bin", "executable_hello"), "#!/bin/sh\necho hello\n")
+	writeTestFile(t, filepath.Join(source, "symlink_dot_vimrc"), ".config/nvim/init.vim\n")
+	writeTestFile(t, filepath.Join(source, "dot_gitconfig.tmpl"), "[user]\n\tname = {{ .name }}\n")
+	writeTestFile(t, filepath.Join(source, "run_once_install.sh"), "#!/bin/sh\n")
+	writeTestFile(t, filepath.Join(source, ".chezmoiignore"), "README.md\n")
+
+	plan, err := importService.PlanSource(models.ImportSource{Type: models.ImportSourceChezmoi, CreateMissing: true})
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	if plan.Source == nil || plan.Source.Path != "~/.local/share/chezmoi" || plan.Signature.Status != models.SignatureUnsigned {
+		t.Errorf("导入计划的来源错误: %+v, %+v", plan.Source, plan.Signature)
+	}
+	entries := planEntries(plan)
+	if len(entries) != 6 {
+		t.Fatalf("条目数量错误: %+v", plan.Entries)
+	}
+	if entry := entries["dot_bash_aliases"]; entry.Action != models.ImportActionRegister || entry.FileID != "bash_aliases" || !entry.Register {
+		t.Errorf("内容相同的未登记文件应只登记: %+v", entry)
+	}
+	if entry := entries["private_dot_ssh/private_config"]; entry.Action != models.ImportActionCreate || entry.Path != "~/.ssh/config" || entry.Mode != "0600" {
+		t.Errorf("private_ 前缀映射错误: %+v", entry)
+	}
+	if entry := entries["dot_local/bin/executable_hello"]; entry.Path != "~/.local/bin/hello" || entry.Mode != "0755" {
+		t.Errorf("executable_ 前缀映射错误: %+v", entry)
+	}
+	if entry := entries["symlink_dot_vimrc"]; entry.Path != "~/.vimrc" || entry.LinkTarget != ".config/nvim/init.vim" {
+		t.Errorf("symlink_ 前缀映射错误: %+v", entry)
+	}
+	for _, name := range []string{"dot_gitconfig.tmpl", "run_once_install.sh"} {
+		if entry := entries[name]; entry.Action != models.ImportActionSkip || entry.ErrorCode != models.ArchiveErrUnsupported {
+			t.Errorf("%s 不应导入: %+v", name, entry)
+		}
+	}
+
+	result, err := importService.Apply(plan.ID, models.ApplyImportRequest{}, "")
+	if err != nil {
+		t.Fatalf("执行导入计划失败: %v", err)
+	}
+	if result.ImportedFiles != 4 || len(result.Errors) != 2 {
+		t.Errorf("导入结果错误: %+v", result)
+	}
+	if info, err := os.Stat(filepath.Join(home, ".ssh", "config")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("~/.ssh/config 的权限错误: %v, %v", info, err)
+	}
+	if link, err := os.Readlink(filepath.Join(home, ".vimrc")); err != nil || link != ".config/nvim/init.vim" {
+		t.Errorf("~/.vimrc 应为符号链接: %q, %v", link, err)
+	}
+	if file, err := configService.GetFileByID("bash_aliases"); err != nil || file.Category != "shell" {
+		t.Errorf("~/.bash_aliases 应按签名表登记: %+v, %v", file, err)
+	}
+
+	// stow：按 --dotfiles 还原 dot- 前缀，忽略软件包顶层的 README
+	stowDir := filepath.Join(home, "dotfiles")
+	writeTestFile(t, filepath.Join(home, ".zshrc"), "# old\n")
+	writeTestFile(t, filepath.Join(stowDir, "zsh", "dot-zshrc"), "# stow\n")
+	writeTestFile(t, filepath.Join(stowDir, "zsh", "README.md"), "zsh\n")
+	writeTestFile(t, filepath.Join(stowDir, "git", "dot-config", "git", "ignore"), "*.swp\n")
+
+	plan, err = importService.PlanSource(models.ImportSource{Type: models.ImportSourceStow, Path: "~/dotfiles", Dotfiles: true})
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	entries = planEntries(plan)
+	if len(entries) != 2 {
+		t.Fatalf("条目数量错误: %+v", plan.Entries)
+	}
+	if entry := entries["zsh/dot-zshrc"]; entry.Action != models.ImportActionOverwrite || entry.Path != "~/.zshrc" || entry.FileID != "zshrc" {
+		t.Errorf("stow 条目映射错误: %+v", entry)
+	}
+	if entry := entries["git/dot-config/git/ignore"]; entry.Action != models.ImportActionSkip || entry.Path != "~/.config/git/ignore" {
+		t.Errorf("本地不存在的文件未指定 createMissing 时应跳过: %+v", entry)
+	}
+
+	if _, err := importService.PlanSource(models.ImportSource{Type: models.ImportSourceStow}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("未指定 stow 目录应返回 ErrInvalidInput, got %v", err)
+	}
+	if _, err := importService.PlanSource(models.ImportSource{Type: "homesick", Path: "~"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("不支持的来源应返回 ErrInvalidInput, got %v", err)
+	}
+	escape := filepath.Join(home, "escape")
+	writeTestFile(t, filepath.Join(escape, ".chezmoiroot"), "../.ssh\n")
+	if _, err := importService.PlanSource(models.ImportSource{Type: models.ImportSourceChezmoi, Path: escape}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf(".chezmoiroot 指向源目录之外时应返回 ErrInvalidInput, got %v", err)
+	}
+
+	// yadm：读取仓库 HEAD 中的文件，yadm 自身的配置和备用文件不导入
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("没有 git，跳过 yadm 导入测试")
+	}
+	repo := filepath.Join(home, "yadm-work")
+	writeTestFile(t, filepath.Join(repo, ".tmux.conf"), "set -g mouse on\n")
+	writeTestFile(t, filepath.Join(repo, ".config", "yadm", "bootstrap"), "#!/bin/sh\n")
+	writeTestFile(t, filepath.Join(repo, ".gitconfig##os.Linux"), "[core]\n")
+	// 仓库配置的 filter 驱动不会被执行
+	writeTestFile(t, filepath.Join(repo, ".gitattributes"), ".tmux.conf filter=evil\n")
+	marker := filepath.Join(home, "filter-ran")
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+		{"config", "filter.evil.smudge", "touch " + marker + "; cat"},
+		{"config", "filter.evil.required", "true"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v 失败: %v\n%s", args, err, output)
+		}
+	}
+
+	plan, err = importService.PlanSource(models.ImportSource{Type: models.ImportSourceYadm, Path: repo, CreateMissing: true})
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	entries = planEntries(plan)
+	if entry := entries[".tmux.conf"]; entry.Action != models.ImportActionCreate || entry.Path != "~/.tmux.conf" || entry.FileID != "tmux" {
+		t.Errorf("yadm 条目映射错误: %+v", entry)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("读取仓库时不应执行 filter 驱动")
+	}
+	for _, name := range []string{".config/yadm/bootstrap", ".gitconfig##os.Linux"} {
+		if entry := entries[name]; entry.Action != models.ImportActionSkip || entry.ErrorCode != models.ArchiveErrUnsupported {
+			t.Errorf("%s 不应导入: %+v", name, entry)
+		}
+	}
+}
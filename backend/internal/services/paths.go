@@ -0,0 +1,74 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// appDirName 服务自身数据所在的子目录名
+const appDirName = "linux-config-manager"
+
+// configDir 返回服务配置目录（$XDG_CONFIG_HOME/linux-config-manager）
+func configDir() string {
+	return filepath.Join(xdgConfigHome(), appDirName)
+}
+
+// xdgConfigHome 返回 $XDG_CONFIG_HOME，未设置时为 ~/.config
+func xdgConfigHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".config")
+	}
+	return filepath.Join(homeDir, ".config")
+}
+
+// dataDir 返回服务数据目录（$XDG_DATA_HOME/linux-config-manager），用于保存历史版本和备份
+func dataDir() string {
+	return filepath.Join(xdgDataHome(), appDirName)
+}
+
+// xdgDataHome 返回 $XDG_DATA_HOME，未设置时为 ~/.local/share
+func xdgDataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), ".local", "share")
+	}
+	return filepath.Join(homeDir, ".local", "share")
+}
+
+// expandHome 将以 ~ 开头的路径展开为绝对路径
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("无法获取用户主目录: %w", err)
+	}
+
+	return filepath.Join(homeDir, strings.TrimPrefix(path, "~")), nil
+}
+
+// collapseHome 将位于用户主目录下的绝对路径转换为 ~ 形式，便于跨机器迁移
+func collapseHome(path string) string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil || homeDir == "" {
+		return path
+	}
+	if path == homeDir {
+		return "~"
+	}
+	if strings.HasPrefix(path, homeDir+string(filepath.Separator)) {
+		return "~" + strings.TrimPrefix(path, homeDir)
+	}
+	return path
+}
@@ -0,0 +1,401 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// profileData 档案文件的整体结构
+type profileData struct {
+	Profiles []models.MachineProfile `json:"profiles"`
+}
+
+// ProfileService 管理机器配置档案，并按本机信息解析活动档案
+type ProfileService struct {
+	mu            sync.Mutex
+	path          string
+	registry      *FileRegistry
+	systemService *SystemService
+}
+
+// NewProfileService 创建新的档案服务实例，registry 用于校验档案引用的分类和文件
+func NewProfileService(path string, registry *FileRegistry, systemService *SystemService) *ProfileService {
+	return &ProfileService{path: path, registry: registry, systemService: systemService}
+}
+
+// List 获取所有档案，按定义顺序排列
+func (p *ProfileService) List() ([]models.MachineProfile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	return data.Profiles, nil
+}
+
+// Get 根据ID获取档案
+func (p *ProfileService) Get(profileID string) (*models.MachineProfile, error) {
+	profiles, err := p.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		if profile.ID == profileID {
+			return &profile, nil
+		}
+	}
+	return nil, notFoundf("档案未找到: %s", profileID)
+}
+
+// Create 新建档案，未指定ID时根据名称生成
+func (p *ProfileService) Create(profile models.MachineProfile) (*models.MachineProfile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	profile = normalizeProfile(profile)
+	if profile.ID == "" {
+		profile.ID = slugifyID(profile.Name)
+	}
+	if err := p.validate(data, profile, -1); err != nil {
+		return nil, err
+	}
+
+	data.Profiles = append(data.Profiles, profile)
+	if err := writeJSONFile(p.path, data); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Update 整体替换档案内容，档案ID不可修改
+func (p *ProfileService) Update(profileID string, profile models.MachineProfile) (*models.MachineProfile, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	i := profileIndex(data.Profiles, profileID)
+	if i < 0 {
+		return nil, notFoundf("档案未找到: %s", profileID)
+	}
+	profile = normalizeProfile(profile)
+	if profile.ID != "" && profile.ID != profileID {
+		return nil, invalidf("不允许修改档案ID: %s", profileID)
+	}
+	profile.ID = profileID
+	if err := p.validate(data, profile, i); err != nil {
+		return nil, err
+	}
+
+	data.Profiles[i] = profile
+	if err := writeJSONFile(p.path, data); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Delete 删除档案
+func (p *ProfileService) Delete(profileID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := p.load()
+	if err != nil {
+		return err
+	}
+
+	i := profileIndex(data.Profiles, profileID)
+	if i < 0 {
+		return notFoundf("档案未找到: %s", profileID)
+	}
+	data.Profiles = append(data.Profiles[:i], data.Profiles[i+1:]...)
+	return writeJSONFile(p.path, data)
+}
+
+// RemoveFileRefs 从所有档案中移除对已取消登记文件的引用
+func (p *ProfileService) RemoveFileRefs(fileID string) error {
+	return p.rewriteRefs(func(profile *models.MachineProfile) bool {
+		var changed bool
+		profile.Files, changed = replaceRef(profile.Files, fileID, "")
+		return changed
+	})
+}
+
+// ReplaceCategoryRefs 将所有档案对已删除分类的引用改为 reassignTo（文件改归的分类），为空或分类不存在时移除引用
+func (p *ProfileService) ReplaceCategoryRefs(categoryID, reassignTo string) error {
+	if reassignTo != "" {
+		categories, err := p.registry.Categories()
+		if err != nil {
+			return err
+		}
+		known := false
+		for _, category := range categories {
+			known = known || category.ID == reassignTo
+		}
+		if !known {
+			reassignTo = ""
+		}
+	}
+	return p.rewriteRefs(func(profile *models.MachineProfile) bool {
+		var changed bool
+		profile.Categories, changed = replaceRef(profile.Categories, categoryID, reassignTo)
+		return changed
+	})
+}
+
+// rewriteRefs 对每个档案执行 update，有档案被修改时保存
+func (p *ProfileService) rewriteRefs(update func(*models.MachineProfile) bool) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, err := p.load()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range data.Profiles {
+		if update(&data.Profiles[i]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeJSONFile(p.path, data)
+}
+
+// replaceRef 将列表中的 old 替换为 replacement（为空时删除）并去重，返回新列表以及是否有变化
+func replaceRef(refs []string, old, replacement string) ([]string, bool) {
+	changed := false
+	result := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if ref != old {
+			result = append(result, ref)
+			continue
+		}
+		changed = true
+		if replacement != "" {
+			result = append(result, replacement)
+		}
+	}
+	if !changed {
+		return refs, false
+	}
+	return normalizeTags(result), true
+}
+
+// Active 按本机的系统信息解析活动档案
+func (p *ProfileService) Active() (*models.ProfileResolution, error) {
+	info, err := p.systemService.GetSystemInfo()
+	if err != nil {
+		return nil, err
+	}
+	return p.Resolve(*info)
+}
+
+// Resolve 按给定的机器信息解析活动档案：所有非空规则都匹配的档案中，
+// 优先级高的优先，优先级相同时规则多（更具体）的优先，再相同时按定义顺序
+func (p *ProfileService) Resolve(info models.SystemInfo) (*models.ProfileResolution, error) {
+	profiles, err := p.List()
+	if err != nil {
+		return nil, err
+	}
+
+	resolution := &models.ProfileResolution{System: info, Candidates: []models.ProfileCandidate{}}
+	var matched []int
+	for i, profile := range profiles {
+		candidate := matchProfile(profile, info)
+		resolution.Candidates = append(resolution.Candidates, candidate)
+		if candidate.Matched {
+			matched = append(matched, i)
+		}
+	}
+
+	if len(matched) == 0 {
+		resolution.Reason = "没有匹配本机的档案"
+		if len(profiles) == 0 {
+			resolution.Reason = "尚未定义任何档案"
+		}
+		return resolution, nil
+	}
+
+	sort.SliceStable(matched, func(a, b int) bool {
+		pa, pb := profiles[matched[a]], profiles[matched[b]]
+		if pa.Priority != pb.Priority {
+			return pa.Priority > pb.Priority
+		}
+		return len(resolution.Candidates[matched[a]].Rules) > len(resolution.Candidates[matched[b]].Rules)
+	})
+	best := profiles[matched[0]]
+	resolution.Active = &best
+	resolution.Reason = matchReason(best, resolution.Candidates[matched[0]])
+
+	if len(matched) > 1 {
+		runnerUp := profiles[matched[1]]
+		others := make([]string, 0, len(matched)-1)
+		for _, i := range matched[1:] {
+			others = append(others, profiles[i].ID)
+		}
+		var why string
+		switch {
+		case best.Priority != runnerUp.Priority:
+			why = fmt.Sprintf("优先级 %d 最高", best.Priority)
+		case len(resolution.Candidates[matched[0]].Rules) != len(resolution.Candidates[matched[1]].Rules):
+			why = fmt.Sprintf("优先级相同，规则最多（%d 条）", len(resolution.Candidates[matched[0]].Rules))
+		default:
+			why = "优先级和规则数相同，按定义顺序在前"
+		}
+		resolution.Reason += fmt.Sprintf("；另有 %s 也匹配，%s 因%s而生效", strings.Join(others, "、"), best.ID, why)
+	}
+	return resolution, nil
+}
+
+// matchProfile 逐条检查档案的匹配规则，没有规则的档案匹配任何机器
+func matchProfile(profile models.MachineProfile, info models.SystemInfo) models.ProfileCandidate {
+	candidate := models.ProfileCandidate{
+		ProfileID: profile.ID,
+		Priority:  profile.Priority,
+		Matched:   true,
+		Rules:     []models.ProfileRuleResult{},
+	}
+	for _, rule := range []struct{ field, pattern, value string }{
+		{"hostname", profile.Match.Hostname, info.Hostname},
+		{"os", profile.Match.OS, info.OS},
+		{"kernel", profile.Match.Kernel, info.Kernel},
+	} {
+		if rule.pattern == "" {
+			continue
+		}
+		ok, _ := matchGlob(strings.ToLower(rule.pattern), strings.ToLower(rule.value))
+		candidate.Rules = append(candidate.Rules, models.ProfileRuleResult{
+			Field:   rule.field,
+			Pattern: rule.pattern,
+			Value:   rule.value,
+			Matched: ok,
+		})
+		candidate.Matched = candidate.Matched && ok
+	}
+	return candidate
+}
+
+// matchGlob 按 shell 通配符匹配，与 path.Match 不同，* 和 ? 也匹配 /，
+// 例如 debian* 匹配 lsb_release 给出的 "Debian GNU/Linux 12 (bookworm)"
+func matchGlob(pattern, value string) (bool, error) {
+	return path.Match(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(value, "/", "\x00"))
+}
+
+// matchReason 说明档案为什么匹配
+func matchReason(profile models.MachineProfile, candidate models.ProfileCandidate) string {
+	if len(candidate.Rules) == 0 {
+		return fmt.Sprintf("档案 %s 没有匹配规则，适用于任何机器", profile.ID)
+	}
+	parts := make([]string, 0, len(candidate.Rules))
+	for _, rule := range candidate.Rules {
+		parts = append(parts, fmt.Sprintf("%s %q 匹配 %q", rule.Field, rule.Value, rule.Pattern))
+	}
+	return fmt.Sprintf("档案 %s 的规则全部匹配: %s", profile.ID, strings.Join(parts, "，"))
+}
+
+// normalizeProfile 去掉档案字段两端的空白，分类和文件列表去重
+func normalizeProfile(profile models.MachineProfile) models.MachineProfile {
+	profile.ID = strings.TrimSpace(profile.ID)
+	profile.Name = strings.TrimSpace(profile.Name)
+	profile.Match.Hostname = strings.TrimSpace(profile.Match.Hostname)
+	profile.Match.OS = strings.TrimSpace(profile.Match.OS)
+	profile.Match.Kernel = strings.TrimSpace(profile.Match.Kernel)
+	profile.Categories = normalizeTags(profile.Categories)
+	profile.Files = normalizeTags(profile.Files)
+	return profile
+}
+
+// validate 校验档案的ID、名称、匹配规则、变量名以及引用的分类和文件，skip 为正在修改的档案下标
+func (p *ProfileService) validate(data *profileData, profile models.MachineProfile, skip int) error {
+	if !fileIDPattern.MatchString(profile.ID) {
+		return invalidf("无效的档案ID: %q（只允许字母、数字和 . _ -）", profile.ID)
+	}
+	if profile.Name == "" {
+		return invalidf("档案名称不能为空")
+	}
+	for i, existing := range data.Profiles {
+		if i != skip && existing.ID == profile.ID {
+			return alreadyExistsf("档案ID已存在: %s", profile.ID)
+		}
+	}
+
+	for field, pattern := range map[string]string{"hostname": profile.Match.Hostname, "os": profile.Match.OS, "kernel": profile.Match.Kernel} {
+		if _, err := matchGlob(pattern, ""); err != nil {
+			return invalidf("无效的 %s 通配符: %q", field, pattern)
+		}
+	}
+	for name := range profile.Vars {
+		if !templateVarPattern.MatchString(name) {
+			return invalidf("无效的变量名: %q（只允许字母、数字和下划线，且不能以数字开头）", name)
+		}
+	}
+
+	categories, err := p.registry.Categories()
+	if err != nil {
+		return err
+	}
+	knownCategories := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		knownCategories[category.ID] = true
+	}
+	for _, category := range profile.Categories {
+		if !knownCategories[category] {
+			return invalidf("分类不存在: %s", category)
+		}
+	}
+	for _, fileID := range profile.Files {
+		if _, err := p.registry.Get(fileID); err != nil {
+			return invalidf("文件未登记: %s", fileID)
+		}
+	}
+	return nil
+}
+
+// profileIndex 返回指定档案ID的下标，不存在时返回 -1
+func profileIndex(profiles []models.MachineProfile, profileID string) int {
+	for i, profile := range profiles {
+		if profile.ID == profileID {
+			return i
+		}
+	}
+	return -1
+}
+
+// load 读取档案文件，文件不存在时没有档案
+func (p *ProfileService) load() (*profileData, error) {
+	data := &profileData{Profiles: []models.MachineProfile{}}
+	content, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取档案 %s: %w", p.path, err)
+	}
+	if err := json.Unmarshal(content, data); err != nil {
+		return nil, fmt.Errorf("档案格式错误 %s: %w", p.path, err)
+	}
+	if data.Profiles == nil {
+		data.Profiles = []models.MachineProfile{}
+	}
+	return data, nil
+}
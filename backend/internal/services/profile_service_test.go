@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestProfileResolution(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	configService := NewConfigService()
+	for _, profile := range []models.MachineProfile{
+		{ID: "default", Name: "默认"},
+		{ID: "work", Name: "工作", Match: models.ProfileMatch{Hostname: "work-*"}, Vars: map[string]string{"email": "me@work.example"}, Categories: []string{"git"}},
+		{ID: "work-ubuntu", Name: "工作 Ubuntu", Match: models.ProfileMatch{Hostname: "work-*", OS: "ubuntu*"}, Files: []string{"vimrc"}},
+	} {
+		if _, err := configService.CreateProfile(profile); err != nil {
+			t.Fatalf("新建档案 %s 失败: %v", profile.ID, err)
+		}
+	}
+
+	for name, profile := range map[string]models.MachineProfile{
+		"重复ID":  {ID: "work", Name: "重复"},
+		"无效通配符": {ID: "bad", Name: "无效", Match: models.ProfileMatch{Hostname: "[work"}},
+		"未知分类":  {ID: "bad", Name: "无效", Categories: []string{"no-such-category"}},
+		"无效变量名": {ID: "bad", Name: "无效", Vars: map[string]string{"1x": ""}},
+	} {
+		if _, err := configService.CreateProfile(profile); err == nil {
+			t.Errorf("%s: 应拒绝新建档案", name)
+		}
+	}
+
+	// 规则更多的档案比同优先级的档案更具体
+	resolution, err := configService.ResolveProfile(models.SystemInfo{Hostname: "WORK-laptop", OS: "Ubuntu 22.04.3 LTS"})
+	if err != nil {
+		t.Fatalf("解析活动档案失败: %v", err)
+	}
+	if resolution.Active == nil || resolution.Active.ID != "work-ubuntu" {
+		t.Fatalf("活动档案错误: %+v", resolution)
+	}
+	if !strings.Contains(resolution.Reason, `hostname "WORK-laptop" 匹配 "work-*"`) || !strings.Contains(resolution.Reason, "规则最多") {
+		t.Errorf("匹配原因错误: %s", resolution.Reason)
+	}
+	if len(resolution.Candidates) != 3 || !resolution.Candidates[0].Matched || !resolution.Candidates[1].Matched {
+		t.Errorf("候选档案错误: %+v", resolution.Candidates)
+	}
+
+	// 优先级高于规则数
+	priority := models.MachineProfile{Name: "工作", Match: models.ProfileMatch{Hostname: "work-*"}, Priority: 10, Vars: map[string]string{"email": "me@work.example"}, Categories: []string{"git"}}
+	if _, err := configService.UpdateProfile("work", priority); err != nil {
+		t.Fatalf("修改档案失败: %v", err)
+	}
+	resolution, _ = configService.ResolveProfile(models.SystemInfo{Hostname: "work-laptop", OS: "Ubuntu"})
+	if resolution.Active == nil || resolution.Active.ID != "work" || !strings.Contains(resolution.Reason, "优先级 10 最高") {
+		t.Errorf("优先级应决定活动档案: %+v", resolution)
+	}
+
+	// 通配符中的 * 也匹配 /
+	debian := models.MachineProfile{ID: "debian", Name: "Debian", Match: models.ProfileMatch{OS: "debian*"}, Priority: 20}
+	if _, err := configService.CreateProfile(debian); err != nil {
+		t.Fatalf("新建档案失败: %v", err)
+	}
+	resolution, _ = configService.ResolveProfile(models.SystemInfo{Hostname: "server", OS: "Debian GNU/Linux 12 (bookworm)"})
+	if resolution.Active == nil || resolution.Active.ID != "debian" {
+		t.Errorf("debian* 应匹配含有 / 的操作系统描述: %+v", resolution)
+	}
+	if err := configService.DeleteProfile("debian"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他机器只匹配没有规则的默认档案
+	resolution, _ = configService.ResolveProfile(models.SystemInfo{Hostname: "home-desktop"})
+	if resolution.Active == nil || resolution.Active.ID != "default" || resolution.Candidates[1].Rules[0].Matched {
+		t.Errorf("应使用默认档案: %+v", resolution)
+	}
+
+	// 活动档案的变量覆盖全局变量
+	writeTestFile(t, filepath.Join(home, ".gitconfig"), "[user]\n")
+	if _, err := configService.SetTemplateVars(map[string]string{"email": "me@home.example"}); err != nil {
+		t.Fatalf("设置模板变量失败: %v", err)
+	}
+	on := true
+	if _, err := configService.UpdateFileMeta("gitconfig", models.UpdateFileMetaRequest{Template: &on}); err != nil {
+		t.Fatalf("开启模板方式失败: %v", err)
+	}
+	if _, err := configService.UpdateFile("gitconfig", "[user]\n\temail = {{ .Vars.email }}\n", UpdateOptions{}); err != nil {
+		t.Fatalf("保存模板文件失败: %v", err)
+	}
+	for hostname, want := range map[string]string{"work-laptop": "me@work.example", "home-desktop": "me@home.example"} {
+		preview, err := configService.RenderFile("gitconfig", models.TemplateData{Hostname: hostname})
+		if err != nil {
+			t.Fatalf("预览 %s 失败: %v", hostname, err)
+		}
+		if !strings.Contains(preview.Content, want) {
+			t.Errorf("%s 的渲染结果错误: %s", hostname, preview.Content)
+		}
+	}
+	if _, err := configService.RenderFile("gitconfig", models.TemplateData{Profile: "missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("不存在的档案应返回 ErrNotFound, got %v", err)
+	}
+
+	// 按档案导出只包含档案中的分类和文件
+	writeTestFile(t, filepath.Join(home, ".vimrc"), "set number\n")
+	writeTestFile(t, filepath.Join(home, ".bashrc"), "echo hi\n")
+	items, _, err := NewExportService(configService, NewSigningService()).collect(models.ExportSelection{Profile: "work-ubuntu"}, false)
+	if err != nil {
+		t.Fatalf("按档案收集导出文件失败: %v", err)
+	}
+	if len(items) != 1 || items[0].file.ID != "vimrc" {
+		t.Errorf("按档案导出的文件错误: %+v", items)
+	}
+
+	// 取消登记文件或删除分类后，档案中的引用随之更新，档案仍可修改和导出
+	if err := configService.UnregisterFile("vimrc"); err != nil {
+		t.Fatalf("取消登记文件失败: %v", err)
+	}
+	if _, err := configService.CreateCategory(models.ConfigCategory{ID: "work-tools", Name: "工作工具"}); err != nil {
+		t.Fatalf("新建分类失败: %v", err)
+	}
+	if _, err := configService.UpdateProfile("work", models.MachineProfile{Name: "工作", Categories: []string{"git", "work-tools"}}); err != nil {
+		t.Fatalf("修改档案失败: %v", err)
+	}
+	if err := configService.DeleteCategory("work-tools", ""); err != nil {
+		t.Fatalf("删除分类失败: %v", err)
+	}
+	if err := configService.DeleteCategory("git", "shell"); err != nil {
+		t.Fatalf("删除分类失败: %v", err)
+	}
+	if profile, _ := configService.GetProfile("work-ubuntu"); len(profile.Files) != 0 {
+		t.Errorf("档案仍引用已取消登记的文件: %+v", profile)
+	}
+	profile, _ := configService.GetProfile("work")
+	if !reflect.DeepEqual(profile.Categories, []string{"shell"}) {
+		t.Errorf("档案中的分类应改为文件改归的分类: %+v", profile.Categories)
+	}
+	if _, err := configService.UpdateProfile("work", *profile); err != nil {
+		t.Errorf("更新引用后应能修改档案: %v", err)
+	}
+	if _, _, err := NewExportService(configService, NewSigningService()).collect(models.ExportSelection{Profile: "work"}, false); err != nil {
+		t.Errorf("更新引用后应能按档案导出: %v", err)
+	}
+
+	if err := configService.DeleteProfile("work"); err != nil {
+		t.Fatalf("删除档案失败: %v", err)
+	}
+	if _, err := configService.GetProfile("work"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("删除后应返回 ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,576 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// registryVersion 登记表文件格式版本
+const registryVersion = 1
+
+// fileIDPattern 文件ID只允许字母、数字以及 . _ -
+var fileIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// 标签的数量和长度限制，标签不能包含逗号，以便在查询参数中用逗号分隔
+const (
+	maxFileTags  = 32
+	maxTagLength = 64
+)
+
+// registryEntry 登记表中持久化的单个文件条目（不含运行时信息）
+type registryEntry struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Category    string   `json:"category"`
+	Description string   `json:"description"`
+	Format      string   `json:"format,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Template    bool     `json:"template,omitempty"`
+}
+
+// registryData 登记表文件的整体结构
+type registryData struct {
+	Version    int                     `json:"version"`
+	Categories []models.ConfigCategory `json:"categories"`
+	Files      []registryEntry         `json:"files"`
+}
+
+// FileRegistry 管理用户登记的配置文件和分类，持久化为 JSON 文件
+// 文件不存在时以内置的常用配置文件和分类作为初始内容
+type FileRegistry struct {
+	mu     sync.Mutex
+	path   string
+	loaded bool
+	data   registryData
+}
+
+// NewFileRegistry 创建指向指定文件的登记表实例
+func NewFileRegistry(path string) *FileRegistry {
+	return &FileRegistry{path: path}
+}
+
+// Path 返回登记表文件路径
+func (r *FileRegistry) Path() string {
+	return r.path
+}
+
+// List 返回所有已登记的配置文件
+func (r *FileRegistry) List() ([]models.ConfigFile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	files := make([]models.ConfigFile, 0, len(r.data.Files))
+	for _, entry := range r.data.Files {
+		files = append(files, entry.toConfigFile())
+	}
+	return files, nil
+}
+
+// Get 根据ID查找已登记的配置文件
+func (r *FileRegistry) Get(fileID string) (*models.ConfigFile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	if i := r.indexOf(fileID); i >= 0 {
+		file := r.data.Files[i].toConfigFile()
+		return &file, nil
+	}
+	return nil, notFoundf("文件未找到: %s", fileID)
+}
+
+// Add 登记新的配置文件
+func (r *FileRegistry) Add(req models.RegisterFileRequest) (*models.ConfigFile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	entry := registryEntry{
+		ID:          strings.TrimSpace(req.ID),
+		Name:        strings.TrimSpace(req.Name),
+		Path:        req.Path,
+		Category:    strings.TrimSpace(req.Category),
+		Description: req.Description,
+		Format:      strings.TrimSpace(req.Format),
+		Tags:        normalizeTags(req.Tags),
+		Template:    req.Template,
+	}
+
+	path, err := normalizeRegistryPath(entry.Path)
+	if err != nil {
+		return nil, err
+	}
+	entry.Path = path
+
+	if entry.Name == "" {
+		entry.Name = filepath.Base(path)
+	}
+	if entry.ID == "" {
+		entry.ID = slugifyID(entry.Name)
+	}
+	if err := r.validate(entry); err != nil {
+		return nil, err
+	}
+
+	if r.indexOf(entry.ID) >= 0 {
+		return nil, alreadyExistsf("文件ID已存在: %s", entry.ID)
+	}
+	for _, existing := range r.data.Files {
+		if existing.Path == entry.Path {
+			return nil, alreadyExistsf("路径已被登记为 %s: %s", existing.ID, entry.Path)
+		}
+	}
+
+	r.data.Files = append(r.data.Files, entry)
+	if err := r.save(); err != nil {
+		r.data.Files = r.data.Files[:len(r.data.Files)-1]
+		return nil, err
+	}
+
+	file := entry.toConfigFile()
+	return &file, nil
+}
+
+// Update 修改已登记文件的元数据，未提供的字段保持不变
+func (r *FileRegistry) Update(fileID string, req models.UpdateFileMetaRequest) (*models.ConfigFile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	i := r.indexOf(fileID)
+	if i < 0 {
+		return nil, notFoundf("文件未找到: %s", fileID)
+	}
+
+	entry := r.data.Files[i]
+	if req.Name != nil {
+		entry.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Path != nil {
+		path, err := normalizeRegistryPath(*req.Path)
+		if err != nil {
+			return nil, err
+		}
+		for j, existing := range r.data.Files {
+			if j != i && existing.Path == path {
+				return nil, alreadyExistsf("路径已被登记为 %s: %s", existing.ID, path)
+			}
+		}
+		entry.Path = path
+	}
+	if req.Category != nil {
+		entry.Category = strings.TrimSpace(*req.Category)
+	}
+	if req.Description != nil {
+		entry.Description = *req.Description
+	}
+	if req.Format != nil {
+		entry.Format = strings.TrimSpace(*req.Format)
+	}
+	if req.Tags != nil {
+		entry.Tags = normalizeTags(*req.Tags)
+	}
+	if req.Template != nil {
+		entry.Template = *req.Template
+	}
+	if err := r.validate(entry); err != nil {
+		return nil, err
+	}
+
+	previous := r.data.Files[i]
+	r.data.Files[i] = entry
+	if err := r.save(); err != nil {
+		r.data.Files[i] = previous
+		return nil, err
+	}
+
+	file := entry.toConfigFile()
+	return &file, nil
+}
+
+// Remove 取消登记配置文件，不会删除磁盘上的文件
+func (r *FileRegistry) Remove(fileID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return err
+	}
+
+	i := r.indexOf(fileID)
+	if i < 0 {
+		return notFoundf("文件未找到: %s", fileID)
+	}
+
+	previous := r.data.Files
+	r.data.Files = append(append([]registryEntry{}, previous[:i]...), previous[i+1:]...)
+	if err := r.save(); err != nil {
+		r.data.Files = previous
+		return err
+	}
+	return nil
+}
+
+// validate 检查条目字段是否合法
+func (r *FileRegistry) validate(entry registryEntry) error {
+	if !fileIDPattern.MatchString(entry.ID) {
+		return invalidf("无效的文件ID: %q（只允许字母、数字和 . _ -）", entry.ID)
+	}
+	if entry.Name == "" {
+		return invalidf("文件名称不能为空")
+	}
+	if r.categoryIndex(entry.Category) < 0 {
+		return invalidf("分类不存在: %s", entry.Category)
+	}
+	if entry.Format != "" && lookupValidator(entry.Format) == nil {
+		return invalidf("不支持的文件格式: %s（可用格式: %s）", entry.Format, strings.Join(ValidatorFormats(), ", "))
+	}
+	if len(entry.Tags) > maxFileTags {
+		return invalidf("标签过多: 最多 %d 个", maxFileTags)
+	}
+	for _, tag := range entry.Tags {
+		if strings.ContainsAny(tag, ", \t\n") || len(tag) > maxTagLength {
+			return invalidf("无效的标签: %q（不能包含逗号或空白，最长 %d 字节）", tag, maxTagLength)
+		}
+	}
+	return nil
+}
+
+// normalizeTags 去掉标签两端的空白、空标签和重复标签，保持原有顺序
+func normalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// Categories 返回所有分类
+func (r *FileRegistry) Categories() ([]models.ConfigCategory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	return append([]models.ConfigCategory{}, r.data.Categories...), nil
+}
+
+// AddCategory 新建分类，分类ID和名称都必须唯一
+func (r *FileRegistry) AddCategory(category models.ConfigCategory) (*models.ConfigCategory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	category.ID = strings.TrimSpace(category.ID)
+	category.Name = strings.TrimSpace(category.Name)
+	if category.ID == "" {
+		category.ID = slugifyID(category.Name)
+	}
+	if err := r.validateCategory(category, -1); err != nil {
+		return nil, err
+	}
+
+	r.data.Categories = append(r.data.Categories, category)
+	if err := r.save(); err != nil {
+		r.data.Categories = r.data.Categories[:len(r.data.Categories)-1]
+		return nil, err
+	}
+	return &category, nil
+}
+
+// UpdateCategory 修改分类信息，分类ID不可修改
+func (r *FileRegistry) UpdateCategory(categoryID string, category models.ConfigCategory) (*models.ConfigCategory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+
+	i := r.categoryIndex(categoryID)
+	if i < 0 {
+		return nil, notFoundf("分类未找到: %s", categoryID)
+	}
+	if category.ID != "" && category.ID != categoryID {
+		return nil, invalidf("不允许修改分类ID: %s", categoryID)
+	}
+
+	category.ID = categoryID
+	category.Name = strings.TrimSpace(category.Name)
+	if err := r.validateCategory(category, i); err != nil {
+		return nil, err
+	}
+
+	previous := r.data.Categories[i]
+	r.data.Categories[i] = category
+	if err := r.save(); err != nil {
+		r.data.Categories[i] = previous
+		return nil, err
+	}
+	return &category, nil
+}
+
+// RemoveCategory 删除分类
+// 仍有文件引用该分类时，reassignTo 为空则拒绝删除，否则先把这些文件移到 reassignTo 分类
+func (r *FileRegistry) RemoveCategory(categoryID, reassignTo string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.load(); err != nil {
+		return err
+	}
+
+	i := r.categoryIndex(categoryID)
+	if i < 0 {
+		return notFoundf("分类未找到: %s", categoryID)
+	}
+
+	var referencing []string
+	for _, entry := range r.data.Files {
+		if entry.Category == categoryID {
+			referencing = append(referencing, entry.ID)
+		}
+	}
+
+	if len(referencing) > 0 {
+		if reassignTo == "" {
+			return conflictf("分类 %s 仍被以下文件使用: %s", categoryID, strings.Join(referencing, ", "))
+		}
+		if reassignTo == categoryID || r.categoryIndex(reassignTo) < 0 {
+			return invalidf("无效的目标分类: %s", reassignTo)
+		}
+	}
+
+	previousFiles := append([]registryEntry{}, r.data.Files...)
+	previousCategories := r.data.Categories
+
+	for j := range r.data.Files {
+		if r.data.Files[j].Category == categoryID {
+			r.data.Files[j].Category = reassignTo
+		}
+	}
+	r.data.Categories = append(append([]models.ConfigCategory{}, previousCategories[:i]...), previousCategories[i+1:]...)
+
+	if err := r.save(); err != nil {
+		r.data.Files = previousFiles
+		r.data.Categories = previousCategories
+		return err
+	}
+	return nil
+}
+
+// validateCategory 检查分类字段是否合法，skip 为修改时被替换的下标
+func (r *FileRegistry) validateCategory(category models.ConfigCategory, skip int) error {
+	if !fileIDPattern.MatchString(category.ID) {
+		return invalidf("无效的分类ID: %q（只允许字母、数字和 . _ -）", category.ID)
+	}
+	if category.Name == "" {
+		return invalidf("分类名称不能为空")
+	}
+	if strings.ContainsAny(category.Name, `/\`) {
+		return invalidf("分类名称不能包含路径分隔符: %s", category.Name)
+	}
+
+	for i, existing := range r.data.Categories {
+		if i == skip {
+			continue
+		}
+		if existing.ID == category.ID {
+			return alreadyExistsf("分类ID已存在: %s", category.ID)
+		}
+		if existing.Name == category.Name {
+			return alreadyExistsf("分类名称已存在: %s", category.Name)
+		}
+	}
+	return nil
+}
+
+// categoryIndex 返回指定分类ID的下标，不存在时返回 -1
+func (r *FileRegistry) categoryIndex(categoryID string) int {
+	for i, category := range r.data.Categories {
+		if category.ID == categoryID {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexOf 返回指定ID在登记表中的下标，不存在时返回 -1
+func (r *FileRegistry) indexOf(fileID string) int {
+	for i, entry := range r.data.Files {
+		if entry.ID == fileID {
+			return i
+		}
+	}
+	return -1
+}
+
+// load 首次访问时读取登记表文件，文件不存在时使用内置默认列表
+func (r *FileRegistry) load() error {
+	if r.loaded {
+		return nil
+	}
+
+	content, err := os.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		r.data = defaultRegistryData()
+		r.loaded = true
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("无法读取登记表 %s: %w", r.path, err)
+	}
+
+	var data registryData
+	if err := json.Unmarshal(content, &data); err != nil {
+		return fmt.Errorf("登记表格式错误 %s: %w", r.path, err)
+	}
+
+	// 旧版本登记表没有分类字段时补上内置分类
+	if data.Categories == nil {
+		data.Categories = append([]models.ConfigCategory{}, defaultCategories...)
+	}
+
+	r.data = data
+	r.loaded = true
+	return nil
+}
+
+// save 将登记表写回磁盘
+func (r *FileRegistry) save() error {
+	r.data.Version = registryVersion
+	return writeJSONFile(r.path, r.data)
+}
+
+// defaultRegistryData 使用内置的分类和常用配置文件生成初始登记表
+func defaultRegistryData() registryData {
+	data := registryData{
+		Version:    registryVersion,
+		Categories: append([]models.ConfigCategory{}, defaultCategories...),
+	}
+	for _, file := range defaultConfigFiles {
+		data.Files = append(data.Files, registryEntry{
+			ID:          file.ID,
+			Name:        file.Name,
+			Path:        file.Path,
+			Category:    file.Category,
+			Description: file.Description,
+		})
+	}
+	return data
+}
+
+// toConfigFile 将登记表条目转换为配置文件模型
+func (e registryEntry) toConfigFile() models.ConfigFile {
+	return models.ConfigFile{
+		ID:          e.ID,
+		Name:        e.Name,
+		Path:        e.Path,
+		Category:    e.Category,
+		Description: e.Description,
+		Format:      e.Format,
+		Tags:        e.Tags,
+		Template:    e.Template,
+	}
+}
+
+// normalizeRegistryPath 规范化登记路径：必须是绝对路径或 ~ 开头，主目录下的路径统一保存为 ~ 形式
+func normalizeRegistryPath(path string) (string, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return "", invalidf("文件路径不能为空")
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		cleaned := filepath.Clean(path)
+		if cleaned == "~" {
+			return "", invalidf("文件路径不能是主目录本身")
+		}
+		// "~/../x" 清理后不再以 ~/ 开头，会被当作相对路径
+		if !strings.HasPrefix(cleaned, "~/") {
+			return "", invalidf("以 ~ 开头的路径不能跳出主目录: %s", path)
+		}
+		return cleaned, nil
+	}
+
+	if !filepath.IsAbs(path) {
+		return "", invalidf("文件路径必须是绝对路径或以 ~ 开头: %s", path)
+	}
+	return collapseHome(filepath.Clean(path)), nil
+}
+
+// slugifyID 根据文件名生成默认ID，例如 ".tmux.conf" -> "tmux.conf"
+func slugifyID(name string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(name) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+			b.WriteRune(c)
+		default:
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), ".-_")
+}
+
+// writeJSONFile 以临时文件加重命名的方式写入 JSON，避免写到一半的文件
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("无法序列化数据: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("无法创建目录 %s: %w", filepath.Dir(path), err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("无法创建临时文件: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("无法写入 %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("无法写入 %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("无法保存 %s: %w", path, err)
+	}
+	return nil
+}
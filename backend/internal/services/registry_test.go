@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+func TestFileRegistrySeedAndPersist(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	path := filepath.Join(t.TempDir(), "registry.json")
+	registry := NewFileRegistry(path)
+
+	// 未登记过任何文件时应使用内置列表，并且不写入磁盘
+	files, err := registry.List()
+	if err != nil {
+		t.Fatalf("读取登记表失败: %v", err)
+	}
+	if len(files) != len(defaultConfigFiles) {
+		t.Errorf("默认文件数量错误: got %d want %d", len(files), len(defaultConfigFiles))
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("只读访问不应创建登记表文件")
+	}
+
+	file, err := registry.Add(models.RegisterFileRequest{
+		Path:     filepath.Join(home, ".tmux.conf"),
+		Category: "app",
+	})
+	if err != nil {
+		t.Fatalf("登记文件失败: %v", err)
+	}
+	if file.ID != "tmux.conf" || file.Path != "~/.tmux.conf" {
+		t.Errorf("登记结果错误: id=%s path=%s", file.ID, file.Path)
+	}
+
+	if _, err := registry.Add(models.RegisterFileRequest{ID: "other", Path: "~/.tmux.conf", Category: "app"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("重复路径应返回 ErrAlreadyExists, got %v", err)
+	}
+	if _, err := registry.Add(models.RegisterFileRequest{Path: "relative/path", Category: "app"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("相对路径应返回 ErrInvalidInput, got %v", err)
+	}
+	if _, err := registry.Add(models.RegisterFileRequest{Path: "~/../etc/passwd", Category: "app"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("跳出主目录的路径应返回 ErrInvalidInput, got %v", err)
+	}
+
+	// 重新加载后应能读到新登记的文件
+	reloaded := NewFileRegistry(path)
+	if _, err := reloaded.Get("tmux.conf"); err != nil {
+		t.Fatalf("重新加载后未找到登记的文件: %v", err)
+	}
+
+	description := "tmux 终端复用器配置"
+	updated, err := reloaded.Update("tmux.conf", models.UpdateFileMetaRequest{Description: &description})
+	if err != nil {
+		t.Fatalf("更新元数据失败: %v", err)
+	}
+	if updated.Description != description || updated.Path != "~/.tmux.conf" {
+		t.Errorf("更新后的元数据错误: %+v", updated)
+	}
+
+	if err := reloaded.Remove("bashrc"); err != nil {
+		t.Fatalf("取消登记失败: %v", err)
+	}
+	if _, err := NewFileRegistry(path).Get("bashrc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("取消登记后应返回 ErrNotFound, got %v", err)
+	}
+}
+
+func TestFileRegistryCategories(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	registry := NewFileRegistry(filepath.Join(t.TempDir(), "registry.json"))
+
+	if _, err := registry.AddCategory(models.ConfigCategory{ID: "terminal", Name: "终端配置"}); err != nil {
+		t.Fatalf("新建分类失败: %v", err)
+	}
+	if _, err := registry.AddCategory(models.ConfigCategory{ID: "terminal", Name: "另一个终端"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("重复分类ID应返回 ErrAlreadyExists, got %v", err)
+	}
+
+	if _, err := registry.Add(models.RegisterFileRequest{ID: "kitty", Path: "~/.config/kitty/kitty.conf", Category: "terminal"}); err != nil {
+		t.Fatalf("登记文件失败: %v", err)
+	}
+
+	// 仍被引用的分类不能直接删除
+	if err := registry.RemoveCategory("terminal", ""); !errors.Is(err, ErrConflict) {
+		t.Errorf("删除被引用的分类应返回 ErrConflict, got %v", err)
+	}
+	if err := registry.RemoveCategory("terminal", "app"); err != nil {
+		t.Fatalf("迁移后删除分类失败: %v", err)
+	}
+
+	file, err := registry.Get("kitty")
+	if err != nil {
+		t.Fatalf("未找到文件: %v", err)
+	}
+	if file.Category != "app" {
+		t.Errorf("文件应被迁移到 app 分类, got %s", file.Category)
+	}
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// signingAlgorithm 导出包签名使用的算法
+const signingAlgorithm = "ed25519"
+
+// manifestSignatureName 导出压缩包中配置清单签名的文件名
+const manifestSignatureName = "配置清单.sig"
+
+// signingKeyFile 本机签名密钥在磁盘上的结构，私钥只保存在服务配置目录中
+type signingKeyFile struct {
+	models.SigningKey
+	PrivateKey string `json:"privateKey"` // base64 编码的 ed25519 私钥种子
+}
+
+// SigningService 管理本机的导出包签名密钥和受信任的公钥列表
+// 密钥保存在 <配置目录>/signing_key.json，受信任列表和策略保存在 <配置目录>/trusted_keys.json
+type SigningService struct {
+	mu        sync.Mutex
+	keyPath   string
+	trustPath string
+	key       *signingKeyFile
+}
+
+// NewSigningService 创建新的签名服务实例
+func NewSigningService() *SigningService {
+	return &SigningService{
+		keyPath:   filepath.Join(configDir(), "signing_key.json"),
+		trustPath: filepath.Join(configDir(), "trusted_keys.json"),
+	}
+}
+
+// PublicKey 返回本机签名公钥，首次调用时生成密钥对
+func (s *SigningService) PublicKey() (*models.SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.loadKey()
+	if err != nil {
+		return nil, err
+	}
+	result := key.SigningKey
+	return &result, nil
+}
+
+// Sign 用本机私钥对配置清单的原始字节签名
+func (s *SigningService) Sign(manifest []byte) (*models.ManifestSignature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.loadKey()
+	if err != nil {
+		return nil, err
+	}
+	seed, err := base64.StdEncoding.DecodeString(key.PrivateKey)
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("签名密钥格式错误: %s", s.keyPath)
+	}
+
+	signature := ed25519.Sign(ed25519.NewKeyFromSeed(seed), manifest)
+	return &models.ManifestSignature{
+		Algorithm: signingAlgorithm,
+		KeyID:     key.KeyID,
+		PublicKey: key.PublicKey,
+		Signature: base64.StdEncoding.EncodeToString(signature),
+	}, nil
+}
+
+// loadKey 读取本机签名密钥，不存在时生成新的密钥对并保存，调用方需持有锁
+func (s *SigningService) loadKey() (*signingKeyFile, error) {
+	if key, err := s.readKey(); err != nil || key != nil {
+		return key, err
+	}
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("无法生成签名密钥: %w", err)
+	}
+	key := &signingKeyFile{
+		SigningKey: models.SigningKey{
+			KeyID:     signingKeyID(publicKey),
+			Algorithm: signingAlgorithm,
+			PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+			CreatedAt: time.Now(),
+		},
+		PrivateKey: base64.StdEncoding.EncodeToString(privateKey.Seed()),
+	}
+	// writeJSONFile 通过 0600 权限的临时文件写入，私钥不会被其他用户读取
+	if err := writeJSONFile(s.keyPath, key); err != nil {
+		return nil, err
+	}
+	s.key = key
+	return s.key, nil
+}
+
+// readKey 读取本机签名密钥但不生成，密钥文件不存在时返回 nil，调用方需持有锁
+func (s *SigningService) readKey() (*signingKeyFile, error) {
+	if s.key != nil {
+		return s.key, nil
+	}
+
+	content, err := os.ReadFile(s.keyPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取签名密钥: %w", err)
+	}
+	var key signingKeyFile
+	if err := json.Unmarshal(content, &key); err != nil {
+		return nil, fmt.Errorf("签名密钥格式错误: %w", err)
+	}
+	s.key = &key
+	return s.key, nil
+}
+
+// Trust 返回签名策略和受信任的公钥列表
+func (s *SigningService) Trust() (*models.TrustSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadTrust()
+}
+
+// SetPolicy 修改导入时的签名策略
+func (s *SigningService) SetPolicy(policy string) (*models.TrustSettings, error) {
+	if policy != models.SignaturePolicyWarn && policy != models.SignaturePolicyRequire {
+		return nil, invalidf("无效的签名策略: %s（可选 %s、%s）", policy, models.SignaturePolicyWarn, models.SignaturePolicyRequire)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trust, err := s.loadTrust()
+	if err != nil {
+		return nil, err
+	}
+	trust.Policy = policy
+	if err := writeJSONFile(s.trustPath, trust); err != nil {
+		return nil, err
+	}
+	return trust, nil
+}
+
+// AddTrustedKey 添加受信任的签名公钥，名称为空时使用密钥ID
+func (s *SigningService) AddTrustedKey(req models.AddTrustedKeyRequest) (*models.TrustedKey, error) {
+	publicKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(req.PublicKey))
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return nil, invalidf("公钥必须是 base64 编码的 %d 字节 ed25519 公钥", ed25519.PublicKeySize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trust, err := s.loadTrust()
+	if err != nil {
+		return nil, err
+	}
+	key := models.TrustedKey{
+		KeyID:     signingKeyID(publicKey),
+		Name:      strings.TrimSpace(req.Name),
+		PublicKey: base64.StdEncoding.EncodeToString(publicKey),
+		AddedAt:   time.Now(),
+	}
+	if key.Name == "" {
+		key.Name = key.KeyID
+	}
+	for _, existing := range trust.Keys {
+		if existing.KeyID == key.KeyID {
+			return nil, alreadyExistsf("公钥已在受信任列表中: %s", key.KeyID)
+		}
+	}
+
+	trust.Keys = append(trust.Keys, key)
+	if err := writeJSONFile(s.trustPath, trust); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RemoveTrustedKey 从受信任列表中删除公钥
+func (s *SigningService) RemoveTrustedKey(keyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trust, err := s.loadTrust()
+	if err != nil {
+		return err
+	}
+	for i, key := range trust.Keys {
+		if key.KeyID == keyID {
+			trust.Keys = append(trust.Keys[:i:i], trust.Keys[i+1:]...)
+			return writeJSONFile(s.trustPath, trust)
+		}
+	}
+	return notFoundf("受信任的公钥不存在: %s", keyID)
+}
+
+// loadTrust 读取受信任列表，不存在时返回默认的 warn 策略，调用方需持有锁
+func (s *SigningService) loadTrust() (*models.TrustSettings, error) {
+	trust := &models.TrustSettings{Policy: models.SignaturePolicyWarn, Keys: []models.TrustedKey{}}
+	content, err := os.ReadFile(s.trustPath)
+	if os.IsNotExist(err) {
+		return trust, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取受信任公钥列表: %w", err)
+	}
+	if err := json.Unmarshal(content, trust); err != nil {
+		return nil, fmt.Errorf("受信任公钥列表格式错误: %w", err)
+	}
+	if trust.Keys == nil {
+		trust.Keys = []models.TrustedKey{}
+	}
+	return trust, nil
+}
+
+// Verify 校验配置清单的签名，签名者是本机密钥或受信任列表中的公钥时结果为 valid
+// 密钥ID按签名中的公钥重新计算，不信任签名文件中声明的ID
+func (s *SigningService) Verify(manifest, signatureData []byte) models.SignatureStatus {
+	if manifest == nil || signatureData == nil {
+		return models.SignatureStatus{Status: models.SignatureUnsigned, Message: "导出包没有签名"}
+	}
+
+	var signature models.ManifestSignature
+	if err := json.Unmarshal(signatureData, &signature); err != nil {
+		return models.SignatureStatus{Status: models.SignatureInvalid, Message: "无法解析签名: " + err.Error()}
+	}
+	publicKey, err := base64.StdEncoding.DecodeString(signature.PublicKey)
+	if signature.Algorithm != signingAlgorithm || err != nil || len(publicKey) != ed25519.PublicKeySize {
+		return models.SignatureStatus{Status: models.SignatureInvalid, Message: "不支持的签名算法或公钥"}
+	}
+	keyID := signingKeyID(publicKey)
+	sig, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil || !ed25519.Verify(publicKey, manifest, sig) {
+		return models.SignatureStatus{Status: models.SignatureInvalid, KeyID: keyID, Message: "签名与配置清单不匹配，导出包可能被篡改"}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 校验只读取已有的密钥，本机还没有密钥时签名者不可能是本机
+	if own, err := s.readKey(); err == nil && own != nil && own.KeyID == keyID {
+		return models.SignatureStatus{Status: models.SignatureValid, KeyID: keyID, Signer: "本机"}
+	}
+	if trust, err := s.loadTrust(); err == nil {
+		for _, key := range trust.Keys {
+			if key.KeyID == keyID {
+				return models.SignatureStatus{Status: models.SignatureValid, KeyID: keyID, Signer: key.Name}
+			}
+		}
+	}
+	return models.SignatureStatus{Status: models.SignatureUntrusted, KeyID: keyID, Message: fmt.Sprintf("签名者 %s 不在受信任列表中", keyID)}
+}
+
+// signingKeyID 返回公钥 SHA-256 的前 16 个十六进制字符
+func signingKeyID(publicKey []byte) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:])[:16]
+}
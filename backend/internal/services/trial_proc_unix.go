@@ -0,0 +1,24 @@
+//go:build unix
+
+package services
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setTrialProcessGroup 让 shell 在独立的进程组中运行，超时终止时连同 rc 文件启动的后台进程一起结束
+func setTrialProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return killTrialProcessGroup(cmd)
+	}
+}
+
+// killTrialProcessGroup 向 shell 所在的整个进程组发送 SIGKILL，进程组中已没有进程时返回错误
+func killTrialProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return syscall.ESRCH
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
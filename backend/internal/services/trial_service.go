@@ -0,0 +1,278 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// 试运行的时间与输出限制
+const (
+	defaultTrialTimeout = 10 * time.Second
+	maxTrialTimeout     = 60 * time.Second
+	trialOutputLimit    = 64 << 10
+)
+
+// trialShells 支持试运行的 shell
+var trialShells = map[string]bool{"bash": true, "zsh": true, "sh": true}
+
+// TrialService 在一次性的临时 HOME 中启动 shell 加载配置文件，检查能否正常启动
+type TrialService struct {
+	configService *ConfigService
+	systemService *SystemService
+}
+
+// NewTrialService 创建新的试运行服务实例
+func NewTrialService(configService *ConfigService, systemService *SystemService) *TrialService {
+	return &TrialService{
+		configService: configService,
+		systemService: systemService,
+	}
+}
+
+// Trial 把提议的内容写入临时 HOME，以非交互方式启动 shell 加载它，返回退出码、输出和启动耗时
+// 其他已登记的 shell 配置按磁盘上的当前内容一并复制，使 rc 文件中 source 的文件可用
+func (t *TrialService) Trial(fileID string, req models.TrialRequest) (*models.TrialResult, error) {
+	file, realPath, err := t.configService.resolveFile(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	shellName, err := t.trialShell(*file, req.Shell)
+	if err != nil {
+		return nil, err
+	}
+	shellPath, err := exec.LookPath(shellName)
+	if err != nil {
+		return nil, fmt.Errorf("未找到 %s: %w", shellName, err)
+	}
+
+	timeout := defaultTrialTimeout
+	if req.TimeoutMs < 0 {
+		return nil, invalidf("超时时间不能为负数")
+	}
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+		if timeout > maxTrialTimeout {
+			return nil, invalidf("超时时间不能超过 %s", maxTrialTimeout)
+		}
+	}
+
+	var content []byte
+	if req.Content != nil && file.Template {
+		// 模板文件提交的是模板源文件，试运行本机渲染后的结果
+		rendered, err := t.configService.renderSource(file, *req.Content)
+		if err != nil {
+			return nil, err
+		}
+		content = []byte(rendered)
+	} else if req.Content != nil {
+		content = []byte(*req.Content)
+	} else {
+		content, err = os.ReadFile(realPath)
+		if os.IsNotExist(err) {
+			return nil, notFoundf("文件不存在: %s", realPath)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("无法读取文件 %s: %w", realPath, err)
+		}
+	}
+
+	home, err := os.MkdirTemp("", "trial-home-*")
+	if err != nil {
+		return nil, fmt.Errorf("无法创建临时 HOME: %w", err)
+	}
+	defer os.RemoveAll(home)
+
+	if err := t.populateHome(home, file.ID); err != nil {
+		return nil, err
+	}
+	rcPath := trialPath(home, file.Path)
+	if err := writeTrialFile(rcPath, content); err != nil {
+		return nil, err
+	}
+
+	return runTrial(shellPath, home, rcPath, timeout)
+}
+
+// trialShell 选择试运行使用的 shell：优先使用请求指定的，其次按文件格式，
+// shell 分类中无法推断格式的文件使用用户的登录 shell
+func (t *TrialService) trialShell(file models.ConfigFile, override string) (string, error) {
+	if override != "" {
+		if !trialShells[override] {
+			return "", invalidf("不支持的 shell: %s（可用: bash, zsh, sh）", override)
+		}
+		return override, nil
+	}
+
+	format := detectFormat(file)
+	if trialShells[format] {
+		return format, nil
+	}
+	if format == "" && file.Category == "shell" {
+		shell := filepath.Base(t.systemService.DetectShell())
+		if !trialShells[shell] {
+			return "", invalidf("登录 shell %s 不支持试运行，请指定 shell", shell)
+		}
+		return shell, nil
+	}
+	return "", invalidf("文件 %s 不是 shell 配置，无法试运行", file.ID)
+}
+
+// populateHome 把除 skipID 外的 shell 配置文件的当前内容复制到临时 HOME
+func (t *TrialService) populateHome(home, skipID string) error {
+	files, err := t.configService.registry.List()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.ID == skipID || !strings.HasPrefix(file.Path, "~/") {
+			continue
+		}
+		if file.Category != "shell" && !trialShells[detectFormat(file)] {
+			continue
+		}
+
+		realPath, err := expandHome(file.Path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(realPath)
+		if err != nil {
+			// 不存在或无法读取的文件在真实环境中同样缺失，保持一致即可
+			continue
+		}
+		if err := writeTrialFile(trialPath(home, file.Path), content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trialPath 返回配置文件在临时 HOME 中的位置，主目录之外的文件放在临时 HOME 根目录
+func trialPath(home, path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(home, filepath.FromSlash(rest))
+	}
+	return filepath.Join(home, filepath.Base(path))
+}
+
+// writeTrialFile 在临时 HOME 中写入文件，必要时创建上级目录
+func writeTrialFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("无法创建临时目录: %w", err)
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return fmt.Errorf("无法写入临时文件: %w", err)
+	}
+	return nil
+}
+
+// runTrial 以非交互方式启动 shell 并 source rc 文件，超时或退出后终止整个进程组
+func runTrial(shellPath, home, rcPath string, timeout time.Duration) (*models.TrialResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, shellPath, "-c", `. "$1"`, "trial", rcPath)
+	cmd.Dir = home
+	cmd.Env = trialEnv(home, shellPath)
+	setTrialProcessGroup(cmd)
+	// rc 文件启动的后台进程可能一直持有输出管道，shell 退出或超时后最多再等待 1 秒
+	cmd.WaitDelay = time.Second
+
+	stdout := &limitedBuffer{limit: trialOutputLimit}
+	stderr := &limitedBuffer{limit: trialOutputLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+	// shell 退出后仍在运行的后台进程属于同一进程组，一并终止，避免遗留在系统中
+	leftover := cmd.Process != nil && killTrialProcessGroup(cmd) == nil
+
+	result := &models.TrialResult{
+		Shell:      shellPath,
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		DurationMs: duration.Milliseconds(),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.TimedOut = true
+		result.ExitCode = -1
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case errors.Is(err, exec.ErrWaitDelay):
+		// shell 已正常退出，只是后台进程仍持有输出管道
+		result.ExitCode = cmd.ProcessState.ExitCode()
+		leftover = true
+	default:
+		return nil, fmt.Errorf("无法启动 %s: %w", shellPath, err)
+	}
+	if leftover && !result.TimedOut {
+		result.Warning = "rc 文件启动的后台进程在 shell 退出后仍在运行，已被终止"
+	}
+
+	result.Clean = !result.TimedOut && result.ExitCode == 0 && result.Warning == "" &&
+		strings.TrimSpace(result.Stderr) == ""
+	return result, nil
+}
+
+// trialEnv 构造试运行使用的最小环境，所有 XDG 目录都指向临时 HOME
+func trialEnv(home, shellPath string) []string {
+	env := []string{
+		"HOME=" + home,
+		"ZDOTDIR=" + home,
+		"SHELL=" + shellPath,
+		"PATH=" + os.Getenv("PATH"),
+		"USER=" + currentUser(),
+		"LOGNAME=" + currentUser(),
+		"TERM=dumb",
+		"XDG_CONFIG_HOME=" + filepath.Join(home, ".config"),
+		"XDG_DATA_HOME=" + filepath.Join(home, ".local", "share"),
+		"XDG_STATE_HOME=" + filepath.Join(home, ".local", "state"),
+		"XDG_CACHE_HOME=" + filepath.Join(home, ".cache"),
+	}
+	if lang := os.Getenv("LANG"); lang != "" {
+		env = append(env, "LANG="+lang)
+	}
+	return env
+}
+
+// limitedBuffer 只保留前 limit 个字节的输出，其余部分丢弃
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write 实现 io.Writer，超出上限时静默丢弃，避免子进程因管道写入失败而退出
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// String 返回保留的输出，被截断时追加提示
+func (b *limitedBuffer) String() string {
+	if b.truncated {
+		return b.buf.String() + "\n...（输出过长，已截断）"
+	}
+	return b.buf.String()
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestTrialShellRcFile(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("未安装 bash")
+	}
+
+	home := testutil.SetupHome(t)
+
+	// .bashrc 引用的 .profile 应按当前内容复制到临时 HOME
+	writeTestFile(t, filepath.Join(home, ".profile"), "export FROM_PROFILE=1\n")
+	writeTestFile(t, filepath.Join(home, ".bashrc"), "echo original\n")
+
+	configService := NewConfigService()
+	trial := NewTrialService(configService, NewSystemService())
+
+	content := ". \"$HOME/.profile\"\necho \"profile=$FROM_PROFILE\"\ntouch \"$HOME/marker\"\n"
+	result, err := trial.Trial("bashrc", models.TrialRequest{Content: &content})
+	if err != nil {
+		t.Fatalf("试运行失败: %v", err)
+	}
+	if !result.Clean || result.ExitCode != 0 || strings.TrimSpace(result.Stdout) != "profile=1" {
+		t.Errorf("试运行结果错误: %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(home, "marker")); !os.IsNotExist(err) {
+		t.Errorf("试运行不应修改真实 HOME")
+	}
+	if current, _ := os.ReadFile(filepath.Join(home, ".bashrc")); string(current) != "echo original\n" {
+		t.Errorf("试运行不应写入真实文件, got %q", current)
+	}
+
+	broken := "no_such_command_for_trial\n"
+	result, err = trial.Trial("bashrc", models.TrialRequest{Content: &broken})
+	if err != nil {
+		t.Fatalf("试运行失败: %v", err)
+	}
+	if result.Clean || result.ExitCode == 0 || !strings.Contains(result.Stderr, "no_such_command_for_trial") {
+		t.Errorf("应报告命令不存在: %+v", result)
+	}
+
+	slow := "sleep 5\n"
+	result, err = trial.Trial("bashrc", models.TrialRequest{Content: &slow, TimeoutMs: 200})
+	if err != nil {
+		t.Fatalf("试运行失败: %v", err)
+	}
+	if !result.TimedOut || result.ExitCode != -1 || result.Clean {
+		t.Errorf("应报告超时: %+v", result)
+	}
+
+	// 后台进程持有输出管道时 shell 仍算正常退出，后台进程被终止并给出警告
+	background := "sleep 7777 &\necho ok\n"
+	result, err = trial.Trial("bashrc", models.TrialRequest{Content: &background})
+	if err != nil {
+		t.Fatalf("试运行失败: %v", err)
+	}
+	if result.TimedOut || result.ExitCode != 0 || result.Clean || result.Warning == "" ||
+		strings.TrimSpace(result.Stdout) != "ok" {
+		t.Errorf("应报告后台进程被终止: %+v", result)
+	}
+	if _, err := exec.LookPath("pgrep"); err == nil {
+		// 被终止的进程由 init 回收之前仍可能出现在进程列表中
+		deadline := time.Now().Add(2 * time.Second)
+		for exec.Command("pgrep", "-x", "-f", "sleep 7777").Run() == nil {
+			if time.Now().After(deadline) {
+				t.Errorf("后台进程未被终止")
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+
+	if _, err := trial.Trial("gitconfig", models.TrialRequest{Content: &content}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("非 shell 配置应返回 ErrInvalidInput, got %v", err)
+	}
+}
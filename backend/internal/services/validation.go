@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// 诊断级别
+const (
+	SeverityError   = "error"   // 阻止保存
+	SeverityWarning = "warning" // 仅作提示
+)
+
+// shellCheckTimeout 单次 shell 语法检查的最长时间
+const shellCheckTimeout = 5 * time.Second
+
+// Validator 检查某种格式的配置内容
+type Validator interface {
+	Validate(content string) []models.Diagnostic
+}
+
+// ValidatorFunc 将普通函数适配为 Validator
+type ValidatorFunc func(content string) []models.Diagnostic
+
+// Validate 调用函数本身
+func (f ValidatorFunc) Validate(content string) []models.Diagnostic { return f(content) }
+
+// validators 按文件格式注册的校验器
+var (
+	validatorsMu sync.RWMutex
+	validators   = map[string]Validator{
+		"sh":        shellValidator{shell: "sh"},
+		"bash":      shellValidator{shell: "bash"},
+		"zsh":       shellValidator{shell: "zsh"},
+		"gitconfig": ValidatorFunc(validateGitConfig),
+		"sshconfig": ValidatorFunc(validateSSHConfig),
+	}
+)
+
+// RegisterValidator 注册（或替换）指定格式的校验器
+func RegisterValidator(format string, validator Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators[format] = validator
+}
+
+// ValidatorFormats 返回所有已注册的格式名称
+func ValidatorFormats() []string {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+
+	formats := make([]string, 0, len(validators))
+	for format := range validators {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// lookupValidator 返回指定格式的校验器，未注册时返回 nil
+func lookupValidator(format string) Validator {
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	return validators[format]
+}
+
+// 按文件名推断格式的规则
+var (
+	bashFileNames = map[string]bool{".bashrc": true, ".bash_profile": true, ".bash_login": true, ".bash_logout": true, ".bash_aliases": true}
+	zshFileNames  = map[string]bool{".zshrc": true, ".zshenv": true, ".zprofile": true, ".zlogin": true, ".zlogout": true}
+	shFileNames   = map[string]bool{".profile": true, ".xprofile": true, ".xsessionrc": true}
+)
+
+// detectFormat 返回文件的校验格式：优先使用登记表中指定的格式，否则按路径推断，无法推断时返回空字符串
+func detectFormat(file models.ConfigFile) string {
+	if file.Format != "" {
+		return file.Format
+	}
+
+	p := path.Clean(file.Path)
+	base := path.Base(p)
+	switch {
+	case bashFileNames[base] || strings.HasSuffix(base, ".bash"):
+		return "bash"
+	case zshFileNames[base] || strings.HasSuffix(base, ".zsh"):
+		return "zsh"
+	case shFileNames[base] || strings.HasSuffix(base, ".sh"):
+		return "sh"
+	case base == ".gitconfig" || strings.HasSuffix(p, "/git/config") || strings.HasSuffix(p, "/.git/config"):
+		return "gitconfig"
+	case strings.HasSuffix(p, "/.ssh/config") || strings.HasSuffix(p, "/ssh/ssh_config") || strings.HasPrefix(p, "/etc/ssh/ssh_config.d/"):
+		return "sshconfig"
+	}
+	return ""
+}
+
+// validateContent 使用文件对应格式的校验器检查内容并检查受管块标记，没有校验器时只检查受管块标记
+func validateContent(file models.ConfigFile, content string) *models.ValidationResult {
+	return validateTemplate(file, content, content)
+}
+
+// validateTemplate 检查 source 中的受管块标记，并用格式校验器检查 rendered
+// 模板文件的受管块位于模板源文件中，与 ListBlocks 和受管块的修改一致；其他文件两者相同
+func validateTemplate(file models.ConfigFile, source, rendered string) *models.ValidationResult {
+	result := &models.ValidationResult{
+		Format:      detectFormat(file),
+		Valid:       true,
+		Diagnostics: []models.Diagnostic{},
+	}
+
+	// 受管块的标记被手动编辑破坏时给出警告，不阻止保存
+	_, diagnostics := parseBlocks(source)
+	if validator := lookupValidator(result.Format); validator != nil {
+		diagnostics = append(diagnostics, validator.Validate(rendered)...)
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			result.Valid = false
+		}
+	}
+	result.Diagnostics = diagnostics
+	return result
+}
+
+// shellDiagnosticPattern 匹配去掉文件名前缀后的 shell 错误行：
+// bash 为 ": line 3: ..."，dash 为 ": 3: ..."，zsh 为 ":3: ..."
+var shellDiagnosticPattern = regexp.MustCompile(`^:\s*(?:line\s+)?(\d+):\s*(.*)$`)
+
+// shellValidator 在子进程中用 <shell> -n 检查脚本语法，不会执行脚本
+type shellValidator struct {
+	shell string
+}
+
+// Validate 执行语法检查并把错误输出解析为诊断信息
+func (v shellValidator) Validate(content string) []models.Diagnostic {
+	shellPath, err := exec.LookPath(v.shell)
+	if err != nil {
+		return []models.Diagnostic{{
+			Severity: SeverityWarning,
+			Message:  fmt.Sprintf("未找到 %s，已跳过语法检查", v.shell),
+			Source:   v.shell,
+		}}
+	}
+
+	tmp, err := os.CreateTemp("", "validate-*."+v.shell)
+	if err != nil {
+		return []models.Diagnostic{v.internalError(fmt.Errorf("无法创建临时文件: %w", err))}
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return []models.Diagnostic{v.internalError(fmt.Errorf("无法写入临时文件: %w", err))}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shellCheckTimeout)
+	defer cancel()
+
+	// -f 让 zsh 不读取启动文件；使用最小环境，避免 BASH_ENV/ENV 被加载，并固定英文输出便于解析
+	args := []string{"-n", tmp.Name()}
+	if v.shell == "zsh" {
+		args = []string{"-f", "-n", tmp.Name()}
+	}
+	cmd := exec.CommandContext(ctx, shellPath, args...)
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "LC_ALL=C"}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	// 超时说明不了内容有语法错误，与校验器无法执行一样只作警告
+	if ctx.Err() != nil {
+		return []models.Diagnostic{{Severity: SeverityWarning, Message: fmt.Sprintf("语法检查超过 %s 未完成，已跳过", shellCheckTimeout), Source: v.shell}}
+	}
+	if runErr == nil {
+		return nil
+	}
+	if _, ok := runErr.(*exec.ExitError); !ok {
+		return []models.Diagnostic{v.internalError(runErr)}
+	}
+
+	diagnostics := parseShellDiagnostics(stderr.String(), tmp.Name(), v.shell)
+	if len(diagnostics) == 0 {
+		diagnostics = append(diagnostics, models.Diagnostic{Severity: SeverityError, Message: "语法检查未通过", Source: v.shell})
+	}
+	return diagnostics
+}
+
+// internalError 把校验器自身的故障报告为警告，不阻止保存
+func (v shellValidator) internalError(err error) models.Diagnostic {
+	return models.Diagnostic{
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("%s 语法检查无法执行: %v", v.shell, err),
+		Source:   v.shell,
+	}
+}
+
+// parseShellDiagnostics 解析 shell -n 的错误输出
+func parseShellDiagnostics(output, fileName, source string) []models.Diagnostic {
+	var diagnostics []models.Diagnostic
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		d := models.Diagnostic{Severity: SeverityError, Message: line, Source: source}
+		if rest, ok := strings.CutPrefix(line, fileName); ok {
+			if m := shellDiagnosticPattern.FindStringSubmatch(rest); m != nil {
+				d.Line, _ = strconv.Atoi(m[1])
+				d.Message = m[2]
+			}
+		}
+
+		// bash 会在错误后另起一行回显出错的代码，合并到上一条诊断中
+		if n := len(diagnostics); n > 0 && d.Line == diagnostics[n-1].Line && strings.HasPrefix(d.Message, "`") {
+			diagnostics[n-1].Message += ": " + d.Message
+			continue
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
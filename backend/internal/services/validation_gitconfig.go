@@ -0,0 +1,202 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// gitConfigParser 按 git-config(1) 的语法逐行解析 INI 风格的配置
+type gitConfigParser struct {
+	lines       []string
+	inSection   bool
+	diagnostics []models.Diagnostic
+}
+
+// validateGitConfig 检查 gitconfig 的节头、变量名、转义序列、引号和续行
+func validateGitConfig(content string) []models.Diagnostic {
+	p := &gitConfigParser{lines: strings.Split(content, "\n")}
+	for i := 0; i < len(p.lines); i++ {
+		i = p.parseLine(i)
+	}
+	return p.diagnostics
+}
+
+// errorf 记录一条错误诊断，line 和 column 从 1 开始
+func (p *gitConfigParser) errorf(line, column int, format string, args ...interface{}) {
+	p.diagnostics = append(p.diagnostics, models.Diagnostic{
+		Line:     line,
+		Column:   column,
+		Severity: SeverityError,
+		Message:  fmt.Sprintf(format, args...),
+		Source:   "gitconfig",
+	})
+}
+
+// line 返回去掉行尾 \r 的第 i 行
+func (p *gitConfigParser) line(i int) string {
+	return strings.TrimSuffix(p.lines[i], "\r")
+}
+
+// parseLine 解析第 i 行，返回最后消费的行下标（变量值可能通过续行跨越多行）
+func (p *gitConfigParser) parseLine(i int) int {
+	line := p.line(i)
+	text := strings.TrimLeft(line, " \t")
+	column := len(line) - len(text) + 1
+
+	if text == "" || text[0] == '#' || text[0] == ';' {
+		return i
+	}
+
+	if text[0] == '[' {
+		rest, restColumn, ok := p.parseSectionHeader(i+1, column, text)
+		// 节头有误时仍视为进入了新节，避免后续每个变量都重复报错
+		p.inSection = true
+		if !ok || rest == "" {
+			return i
+		}
+		// git 允许在节头之后的同一行直接定义变量
+		text, column = rest, restColumn
+	}
+
+	if !p.inSection {
+		p.errorf(i+1, column, "变量必须位于某个节（例如 [core]）之内")
+		return i
+	}
+	return p.parseVariable(i, column, text)
+}
+
+// parseSectionHeader 解析 [section]、[section "subsection"] 或 [section.subsection]
+// 返回节头之后剩余的文本（已去掉前导空白）及其列号
+func (p *gitConfigParser) parseSectionHeader(lineNo, column int, text string) (string, int, bool) {
+	k := 1
+	for k < len(text) && (isASCIIAlnum(text[k]) || text[k] == '-' || text[k] == '.') {
+		k++
+	}
+	if k == 1 {
+		p.errorf(lineNo, column+k, "节名不能为空，只能包含字母、数字、- 和 .")
+		return "", 0, false
+	}
+
+	if k < len(text) && (text[k] == ' ' || text[k] == '\t') {
+		for k < len(text) && (text[k] == ' ' || text[k] == '\t') {
+			k++
+		}
+		if k >= len(text) || text[k] != '"' {
+			p.errorf(lineNo, column+k, "子节名必须用双引号括起来")
+			return "", 0, false
+		}
+		start := k
+		k++
+		closed := false
+		for k < len(text) {
+			if text[k] == '\\' {
+				k += 2
+				continue
+			}
+			if text[k] == '"' {
+				closed = true
+				k++
+				break
+			}
+			k++
+		}
+		if !closed {
+			p.errorf(lineNo, column+start, "子节名的引号没有闭合")
+			return "", 0, false
+		}
+	}
+
+	if k >= len(text) || text[k] != ']' {
+		p.errorf(lineNo, column+k, "节头缺少 ]")
+		return "", 0, false
+	}
+	k++
+
+	rest := strings.TrimLeft(text[k:], " \t")
+	restColumn := column + len(text) - len(rest)
+	if rest != "" && (rest[0] == '#' || rest[0] == ';') {
+		rest = ""
+	}
+	return rest, restColumn, true
+}
+
+// parseVariable 解析 "name = value" 或单独的布尔变量名，返回最后消费的行下标
+func (p *gitConfigParser) parseVariable(i, column int, text string) int {
+	k := 0
+	for k < len(text) && (isASCIIAlnum(text[k]) || text[k] == '-') {
+		k++
+	}
+	if k == 0 || !isASCIILetter(text[0]) {
+		p.errorf(i+1, column, "无效的变量名，变量名必须以字母开头，只能包含字母、数字和 -")
+		return i
+	}
+
+	rest := strings.TrimLeft(text[k:], " \t")
+	restColumn := column + len(text) - len(rest)
+	if rest == "" || rest[0] == '#' || rest[0] == ';' {
+		return i
+	}
+	if rest[0] != '=' {
+		p.errorf(i+1, restColumn, "变量名 %s 之后应为 = 或行尾", text[:k])
+		return i
+	}
+	return p.parseValue(i, restColumn+1, rest[1:])
+}
+
+// parseValue 检查变量值中的转义序列和引号，行尾的 \ 表示值在下一行继续
+func (p *gitConfigParser) parseValue(i, column int, value string) int {
+	inQuote := false
+	quoteLine, quoteColumn := 0, 0
+
+	for {
+		continued := false
+	scan:
+		for k := 0; k < len(value); k++ {
+			switch c := value[k]; {
+			case c == '\\':
+				if k == len(value)-1 {
+					continued = true
+					break scan
+				}
+				switch value[k+1] {
+				case 'n', 't', 'b', '"', '\\':
+				default:
+					p.errorf(i+1, column+k, "无效的转义序列 \\%c", value[k+1])
+				}
+				k++
+			case c == '"':
+				inQuote = !inQuote
+				quoteLine, quoteColumn = i+1, column+k
+			case (c == '#' || c == ';') && !inQuote:
+				break scan
+			}
+		}
+
+		if !continued {
+			break
+		}
+		if i+1 >= len(p.lines) {
+			p.errorf(i+1, column+len(value)-1, "续行符 \\ 之后没有内容")
+			break
+		}
+		i++
+		value, column = p.line(i), 1
+	}
+
+	if inQuote {
+		p.errorf(quoteLine, quoteColumn, "引号没有闭合")
+	}
+	return i
+}
+
+// isASCIILetter 判断是否为 ASCII 字母
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isASCIIAlnum 判断是否为 ASCII 字母或数字
+func isASCIIAlnum(c byte) bool {
+	return isASCIILetter(c) || c >= '0' && c <= '9'
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"linux-config-manager-backend/internal/models"
+)
+
+// sshKeywords ssh_config(5) 中支持的关键字（小写）
+var sshKeywords = wordSet(`
+	host match include
+	addkeystoagent addressfamily batchmode bindaddress bindinterface
+	canonicaldomains canonicalizefallbacklocal canonicalizehostname canonicalizemaxdots canonicalizepermittedcnames
+	casignaturealgorithms certificatefile channeltimeout checkhostip ciphers clearallforwardings compression
+	connectionattempts connecttimeout controlmaster controlpath controlpersist dynamicforward
+	enableescapecommandline enablesshkeysign escapechar exitonforwardfailure fingerprinthash
+	forkafterauthentication forwardagent forwardx11 forwardx11timeout forwardx11trusted gatewayports
+	globalknownhostsfile gssapiauthentication gssapidelegatecredentials hashknownhosts
+	hostbasedacceptedalgorithms hostbasedauthentication hostkeyalgorithms hostkeyalias hostname
+	identitiesonly identityagent identityfile ignoreunknown ipqos kbdinteractiveauthentication
+	kbdinteractivedevices kexalgorithms knownhostscommand localcommand localforward loglevel logverbose macs
+	nohostauthenticationforlocalhost numberofpasswordprompts obscurekeystroketiming passwordauthentication
+	permitlocalcommand permitremoteopen pkcs11provider port preferredauthentications proxycommand proxyjump
+	proxyusefdpass pubkeyacceptedalgorithms pubkeyauthentication rekeylimit remotecommand remoteforward
+	requesttty requiredrsasize revokedhostkeys securitykeyprovider sendenv serveralivecountmax
+	serveraliveinterval sessiontype setenv stdinnull streamlocalbindmask streamlocalbindunlink
+	stricthostkeychecking syslogfacility tag tcpkeepalive tunnel tunneldevice updatehostkeys user
+	userknownhostsfile verifyhostkeydns visualhostkey xauthlocation
+`)
+
+// sshDeprecatedKeywords 已废弃或改名的关键字，OpenSSH 仍能解析但会忽略或给出提示
+var sshDeprecatedKeywords = map[string]string{
+	"pubkeyacceptedkeytypes":          "已改名为 PubkeyAcceptedAlgorithms",
+	"hostbasedkeytypes":               "已改名为 HostbasedAcceptedAlgorithms",
+	"challengeresponseauthentication": "已改名为 KbdInteractiveAuthentication",
+	"protocol":                        "SSH 协议 1 已移除，该选项会被忽略",
+	"rsaauthentication":               "SSH 协议 1 已移除，该选项会被忽略",
+	"cipher":                          "SSH 协议 1 已移除，请使用 Ciphers",
+	"compressionlevel":                "已移除，该选项会被忽略",
+	"useroaming":                      "已移除，该选项会被忽略",
+	"usersh":                          "已移除，该选项会被忽略",
+	"fallbacktorsh":                   "已移除，该选项会被忽略",
+	"useprivilegedport":               "已移除，该选项会被忽略",
+}
+
+// sshYesNoKeywords 只接受 yes/no 的关键字
+var sshYesNoKeywords = map[string]bool{
+	"batchmode": true, "checkhostip": true, "clearallforwardings": true, "compression": true,
+	"enablesshkeysign": true, "exitonforwardfailure": true, "forwardx11": true, "forwardx11trusted": true,
+	"gatewayports": true, "gssapiauthentication": true, "gssapidelegatecredentials": true, "hashknownhosts": true,
+	"hostbasedauthentication": true, "identitiesonly": true, "kbdinteractiveauthentication": true,
+	"nohostauthenticationforlocalhost": true, "passwordauthentication": true, "permitlocalcommand": true,
+	"streamlocalbindunlink": true, "tcpkeepalive": true, "visualhostkey": true,
+}
+
+// sshMatchCriteria Match 支持的条件，值表示该条件是否需要参数
+var sshMatchCriteria = map[string]bool{
+	"all": false, "canonical": false, "final": false,
+	"exec": true, "host": true, "originalhost": true, "tagged": true, "user": true,
+	"localuser": true, "localnetwork": true, "version": true, "sessiontype": true, "command": true,
+}
+
+// sshArg ssh_config 的一个参数及其列号
+type sshArg struct {
+	Value  string
+	Column int
+}
+
+// validateSSHConfig 检查 ssh_config 的关键字、参数个数、引号和常见取值
+func validateSSHConfig(content string) []models.Diagnostic {
+	var diagnostics []models.Diagnostic
+	report := func(severity string, line, column int, format string, args ...interface{}) {
+		diagnostics = append(diagnostics, models.Diagnostic{
+			Line:     line,
+			Column:   column,
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+			Source:   "ssh_config",
+		})
+	}
+
+	// IgnoreUnknown 列出的模式只对其后出现的关键字生效
+	var ignoreUnknown []string
+
+	for i, raw := range strings.Split(content, "\n") {
+		lineNo := i + 1
+		line := strings.TrimSuffix(raw, "\r")
+		text := strings.TrimLeft(line, " \t")
+		column := len(line) - len(text) + 1
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		// 关键字与参数之间用空白或一个可选的 = 分隔
+		end := strings.IndexAny(text, " \t=")
+		if end < 0 {
+			end = len(text)
+		}
+		keyword := text[:end]
+		rest := strings.TrimLeft(text[end:], " \t")
+		if strings.HasPrefix(rest, "=") {
+			rest = strings.TrimLeft(rest[1:], " \t")
+		}
+		argsColumn := column + len(text) - len(rest)
+
+		lower := strings.ToLower(keyword)
+		if keyword == "" {
+			report(SeverityError, lineNo, column, "缺少关键字")
+			continue
+		}
+		if reason, ok := sshDeprecatedKeywords[lower]; ok {
+			report(SeverityWarning, lineNo, column, "%s %s", keyword, reason)
+			continue
+		}
+		if !sshKeywords[lower] {
+			if sshIgnored(lower, ignoreUnknown) {
+				report(SeverityWarning, lineNo, column, "未知的关键字 %s（已被 IgnoreUnknown 忽略）", keyword)
+			} else {
+				report(SeverityError, lineNo, column, "未知的关键字 %s", keyword)
+			}
+			continue
+		}
+
+		args, quoteColumn := splitSSHArgs(rest, argsColumn)
+		if quoteColumn > 0 {
+			report(SeverityError, lineNo, quoteColumn, "引号没有闭合")
+			continue
+		}
+		if len(args) == 0 {
+			report(SeverityError, lineNo, column, "%s 缺少参数", keyword)
+			continue
+		}
+
+		switch {
+		case lower == "ignoreunknown":
+			for _, pattern := range strings.Split(args[0].Value, ",") {
+				ignoreUnknown = append(ignoreUnknown, strings.ToLower(pattern))
+			}
+		case lower == "match":
+			for k := 0; k < len(args); k++ {
+				criterion := strings.ToLower(strings.TrimPrefix(args[k].Value, "!"))
+				needsArg, ok := sshMatchCriteria[criterion]
+				if !ok {
+					report(SeverityError, lineNo, args[k].Column, "未知的 Match 条件 %s", args[k].Value)
+					break
+				}
+				if needsArg {
+					if k+1 >= len(args) {
+						report(SeverityError, lineNo, args[k].Column, "Match 条件 %s 缺少参数", args[k].Value)
+						break
+					}
+					k++
+				}
+			}
+		case lower == "port":
+			if port, err := strconv.Atoi(args[0].Value); err != nil || port < 1 || port > 65535 {
+				report(SeverityError, lineNo, args[0].Column, "无效的端口号 %s", args[0].Value)
+			}
+		case sshYesNoKeywords[lower]:
+			if value := strings.ToLower(args[0].Value); value != "yes" && value != "no" {
+				report(SeverityError, lineNo, args[0].Column, "%s 只接受 yes 或 no，实际为 %s", keyword, args[0].Value)
+			}
+		}
+	}
+	return diagnostics
+}
+
+// splitSSHArgs 按空白拆分参数，支持双引号包含空白；引号未闭合时返回引号所在列号
+func splitSSHArgs(text string, column int) ([]sshArg, int) {
+	var args []sshArg
+	k := 0
+	for k < len(text) {
+		if text[k] == ' ' || text[k] == '\t' {
+			k++
+			continue
+		}
+		if text[k] == '#' {
+			break
+		}
+
+		start := k
+		var value strings.Builder
+		for k < len(text) && text[k] != ' ' && text[k] != '\t' {
+			if text[k] == '"' {
+				end := strings.IndexByte(text[k+1:], '"')
+				if end < 0 {
+					return args, column + k
+				}
+				value.WriteString(text[k+1 : k+1+end])
+				k += end + 2
+				continue
+			}
+			value.WriteByte(text[k])
+			k++
+		}
+		args = append(args, sshArg{Value: value.String(), Column: column + start})
+	}
+	return args, 0
+}
+
+// wordSet 把空白分隔的单词列表转换为集合
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// sshIgnored 判断关键字是否匹配 IgnoreUnknown 中的某个模式
+func sshIgnored(keyword string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, keyword); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"linux-config-manager-backend/internal/models"
+	"linux-config-manager-backend/internal/testutil"
+)
+
+func TestValidateGitConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int // 期望的第一条错误所在行，0 表示应当通过
+		column  int
+	}{
+		{"合法配置", "[user]\n\tname = Alice\n\temail = \"a@example.com\" # 注释\n[core]\n\tautocrlf\n[remote \"origin\"]\n\turl = a\\\n  b\n", 0, 0},
+		{"节头后直接定义变量", "[alias] co = checkout\n", 0, 0},
+		{"变量不在节内", "name = Alice\n", 1, 1},
+		{"节头缺少右括号", "[user\n", 1, 6},
+		{"子节名未加引号", "[remote origin]\n", 1, 9},
+		{"无效的变量名", "[user]\n  1name = x\n", 2, 3},
+		{"缺少等号", "[user]\nname Alice\n", 2, 6},
+		{"无效的转义序列", "[user]\nname = a\\qb\n", 2, 9},
+		{"引号未闭合", "[user]\nname = \"Alice\n", 2, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := validateGitConfig(tt.content)
+			if tt.line == 0 {
+				if len(diagnostics) != 0 {
+					t.Fatalf("不应有诊断信息: %+v", diagnostics)
+				}
+				return
+			}
+			if len(diagnostics) == 0 {
+				t.Fatalf("应当报告错误")
+			}
+			if d := diagnostics[0]; d.Line != tt.line || d.Column != tt.column || d.Severity != SeverityError {
+				t.Errorf("诊断位置错误: got %d:%d (%s) want %d:%d", d.Line, d.Column, d.Message, tt.line, tt.column)
+			}
+		})
+	}
+}
+
+func TestValidateSSHConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		line     int
+		severity string // 期望的第一条诊断级别，空字符串表示应当通过
+	}{
+		{"合法配置", "Host github.com\n  HostName github.com\n  User=git\n  IdentityFile \"~/.ssh/my key\"\nMatch host *.internal exec \"test -f /tmp/x\"\n  Port 2222\n", 0, ""},
+		{"未知关键字", "Host a\n  HostNmae a.example.com\n", 2, SeverityError},
+		{"IgnoreUnknown 忽略的关键字", "IgnoreUnknown UseKeychain\nHost a\n  UseKeychain yes\n", 3, SeverityWarning},
+		{"已废弃的关键字", "Protocol 2\n", 1, SeverityWarning},
+		{"缺少参数", "Host\n", 1, SeverityError},
+		{"无效端口", "Port 99999\n", 1, SeverityError},
+		{"yes/no 取值错误", "BatchMode maybe\n", 1, SeverityError},
+		{"未知的 Match 条件", "Match hots foo\n", 1, SeverityError},
+		{"引号未闭合", "IdentityFile \"~/.ssh/id\n", 1, SeverityError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diagnostics := validateSSHConfig(tt.content)
+			if tt.severity == "" {
+				if len(diagnostics) != 0 {
+					t.Fatalf("不应有诊断信息: %+v", diagnostics)
+				}
+				return
+			}
+			if len(diagnostics) == 0 {
+				t.Fatalf("应当报告诊断信息")
+			}
+			if d := diagnostics[0]; d.Line != tt.line || d.Severity != tt.severity {
+				t.Errorf("诊断错误: got line %d %s (%s) want line %d %s", d.Line, d.Severity, d.Message, tt.line, tt.severity)
+			}
+		})
+	}
+}
+
+func TestShellValidator(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("未安装 bash")
+	}
+
+	validator := shellValidator{shell: "bash"}
+	if diagnostics := validator.Validate("if true; then\n  echo ok\nfi\n"); len(diagnostics) != 0 {
+		t.Fatalf("合法脚本不应有诊断信息: %+v", diagnostics)
+	}
+
+	diagnostics := validator.Validate("if true; then\n  echo ok\nfi fi\n")
+	if len(diagnostics) != 1 {
+		t.Fatalf("应当报告一条错误, got %+v", diagnostics)
+	}
+	if diagnostics[0].Line != 3 || diagnostics[0].Severity != SeverityError {
+		t.Errorf("诊断错误: %+v", diagnostics[0])
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := map[string]string{
+		"~/.bashrc":                  "bash",
+		"~/.zshrc":                   "zsh",
+		"~/.profile":                 "sh",
+		"~/bin/deploy.sh":            "sh",
+		"~/.gitconfig":               "gitconfig",
+		"~/.config/git/config":       "gitconfig",
+		"~/.ssh/config":              "sshconfig",
+		"~/.config/kitty/kitty.conf": "",
+	}
+	for path, want := range tests {
+		if got := detectFormat(models.ConfigFile{Path: path}); got != want {
+			t.Errorf("detectFormat(%s) = %q, want %q", path, got, want)
+		}
+	}
+
+	if got := detectFormat(models.ConfigFile{Path: "~/.config/kitty/kitty.conf", Format: "sh"}); got != "sh" {
+		t.Errorf("应优先使用登记的格式, got %q", got)
+	}
+}
+
+func TestUpdateFileRejectsInvalidContent(t *testing.T) {
+	home := testutil.SetupHome(t)
+
+	path := filepath.Join(home, ".gitconfig")
+	writeTestFile(t, path, "[user]\n\tname = Alice\n")
+
+	service := NewConfigService()
+	_, err := service.UpdateFile("gitconfig", "[user\n", UpdateOptions{})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || !errors.Is(err, ErrValidation) {
+		t.Fatalf("非法内容应返回 ValidationError, got %v", err)
+	}
+	if validationErr.Result.Format != "gitconfig" || validationErr.Result.Valid {
+		t.Errorf("校验结果错误: %+v", validationErr.Result)
+	}
+	if content, _ := os.ReadFile(path); string(content) != "[user]\n\tname = Alice\n" {
+		t.Errorf("校验失败时不应写入文件, got %q", content)
+	}
+
+	result, err := service.UpdateFile("gitconfig", "[user\n", UpdateOptions{Force: true})
+	if err != nil {
+		t.Fatalf("强制写入失败: %v", err)
+	}
+	if len(result.Diagnostics) == 0 {
+		t.Errorf("强制写入时应返回被忽略的诊断信息")
+	}
+}
@@ -0,0 +1,17 @@
+// Package testutil 提供服务和处理器测试共用的辅助函数
+package testutil
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// SetupHome 为测试创建临时主目录，并把 XDG 配置和数据目录指向其中
+func SetupHome(t testing.TB) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	return home
+}
@@ -1,4 +1,4 @@
-import { ConfigFile, ConfigCategory, SystemInfo } from '../types';
+import { ConfigFile, ConfigCategory, SystemInfo, DiffResult, ExportSelection, TemplateData, RenderResult, MachineProfile, ProfileResolution, BlockList } from '../types';
 
 const API_BASE_URL = 'http://localhost:8080/api';
 
@@ -9,14 +9,17 @@ export interface APIResponse<T> {
 }
 
 class ApiService {
+  // 每个文件最近一次读取或保存时的 ETag，保存时作为 If-Match 发送
+  private etags: Record<string, string> = {};
+
   private async request<T>(endpoint: string, options?: RequestInit): Promise<APIResponse<T>> {
     try {
       const response = await fetch(`${API_BASE_URL}${endpoint}`, {
+        ...options,
         headers: {
           'Content-Type': 'application/json',
           ...options?.headers,
         },
-        ...options,
       });
 
       if (!response.ok) {
@@ -46,17 +49,44 @@ class ApiService {
 
   // 获取单个配置文件内容
   async getFile(id: string): Promise<APIResponse<ConfigFile>> {
-    return this.request<ConfigFile>(`/files/${id}`);
+    const response = await this.request<ConfigFile>(`/files/${id}`);
+    if (response.success && response.data?.etag) {
+      this.etags[id] = response.data.etag;
+    }
+    return response;
   }
 
-  // 更新配置文件内容
-  async updateFile(id: string, content: string): Promise<APIResponse<{ message: string }>> {
-    return this.request<{ message: string }>(`/files/${id}`, {
+  // 更新配置文件内容（文件在读取后被其他程序修改时后端返回 412）
+  // 必须先通过 getFile 读取文件取得 ETag，否则无法判断保存是否会覆盖其他程序的修改
+  async updateFile(id: string, content: string): Promise<APIResponse<{ etag: string }>> {
+    const etag = this.etags[id];
+    if (!etag) {
+      return { success: false, error: `请先读取文件 ${id} 再保存` };
+    }
+    const response = await this.request<{ etag: string }>(`/files/${id}`, {
       method: 'PUT',
+      headers: { 'If-Match': etag },
+      body: JSON.stringify({ content }),
+    });
+    if (response.success && response.data?.etag) {
+      this.etags[id] = response.data.etag;
+    }
+    return response;
+  }
+
+  // 比较当前内容与即将保存的内容
+  async diffFile(id: string, content: string): Promise<APIResponse<DiffResult>> {
+    return this.request<DiffResult>(`/files/${id}/diff`, {
+      method: 'POST',
       body: JSON.stringify({ content }),
     });
   }
 
+  // 比较备份（backup:<ID>）或历史版本（rev:<版本号>）与当前内容
+  async diffFileAgainst(id: string, against: string): Promise<APIResponse<DiffResult>> {
+    return this.request<DiffResult>(`/files/${id}/diff?against=${encodeURIComponent(against)}`);
+  }
+
   // 备份配置文件
   async backupFile(id: string): Promise<APIResponse<{ message: string; backupPath: string }>> {
     return this.request<{ message: string; backupPath: string }>(`/files/${id}/backup`, {
@@ -64,20 +94,158 @@ class ApiService {
     });
   }
 
+  // 列出文件中的受管块
+  async getBlocks(id: string): Promise<APIResponse<BlockList>> {
+    return this.request<BlockList>(`/files/${id}/blocks`);
+  }
+
+  // 插入或更新受管块，insertAfter / insertBefore 只对新块生效
+  async setBlock(id: string, name: string, content: string, position: { insertAfter?: string; insertBefore?: string } = {}): Promise<APIResponse<{ etag: string }>> {
+    const response = await this.request<{ etag: string }>(`/files/${id}/blocks/${encodeURIComponent(name)}`, {
+      method: 'PUT',
+      body: JSON.stringify({ content, ...position }),
+    });
+    if (response.success && response.data?.etag) {
+      this.etags[id] = response.data.etag;
+    }
+    return response;
+  }
+
+  // 删除受管块及其标记行，force 为 true 时文件未通过格式校验也删除
+  async removeBlock(id: string, name: string, force = false): Promise<APIResponse<{ etag: string }>> {
+    const query = force ? '?force=true' : '';
+    const response = await this.request<{ etag: string }>(`/files/${id}/blocks/${encodeURIComponent(name)}${query}`, {
+      method: 'DELETE',
+    });
+    if (response.success && response.data?.etag) {
+      this.etags[id] = response.data.etag;
+    }
+    return response;
+  }
+
+  // 预览模板文件的渲染结果，profile 中的字段覆盖本机信息
+  async renderFile(id: string, profile: TemplateData = {}): Promise<APIResponse<RenderResult>> {
+    const params = new URLSearchParams();
+    for (const key of ['profile', 'hostname', 'os', 'kernel', 'user', 'homeDir', 'shell'] as const) {
+      if (profile[key]) params.set(key, profile[key] as string);
+    }
+    for (const [name, value] of Object.entries(profile.vars || {})) {
+      params.set(`var.${name}`, value);
+    }
+    const query = params.toString();
+    return this.request<RenderResult>(`/files/${id}/render${query ? `?${query}` : ''}`);
+  }
+
+  // 获取模板变量
+  async getTemplateVars(): Promise<APIResponse<Record<string, string>>> {
+    return this.request<Record<string, string>>('/templates/vars');
+  }
+
+  // 整体替换模板变量
+  async updateTemplateVars(vars: Record<string, string>): Promise<APIResponse<Record<string, string>>> {
+    return this.request<Record<string, string>>('/templates/vars', {
+      method: 'PUT',
+      body: JSON.stringify(vars),
+    });
+  }
+
+  // 获取所有机器配置档案
+  async getProfiles(): Promise<APIResponse<MachineProfile[]>> {
+    return this.request<MachineProfile[]>('/profiles');
+  }
+
+  // 新建机器配置档案
+  async createProfile(profile: MachineProfile): Promise<APIResponse<MachineProfile>> {
+    return this.request<MachineProfile>('/profiles', {
+      method: 'POST',
+      body: JSON.stringify(profile),
+    });
+  }
+
+  // 整体替换机器配置档案
+  async updateProfile(id: string, profile: MachineProfile): Promise<APIResponse<MachineProfile>> {
+    return this.request<MachineProfile>(`/profiles/${id}`, {
+      method: 'PUT',
+      body: JSON.stringify(profile),
+    });
+  }
+
+  // 删除机器配置档案
+  async deleteProfile(id: string): Promise<APIResponse<null>> {
+    return this.request<null>(`/profiles/${id}`, { method: 'DELETE' });
+  }
+
+  // 解析活动档案，可传入主机名、操作系统、内核版本模拟其他机器
+  async getActiveProfile(machine: { hostname?: string; os?: string; kernel?: string } = {}): Promise<APIResponse<ProfileResolution>> {
+    const params = new URLSearchParams();
+    for (const [key, value] of Object.entries(machine)) {
+      if (value) params.set(key, value);
+    }
+    const query = params.toString();
+    return this.request<ProfileResolution>(`/profiles/active${query ? `?${query}` : ''}`);
+  }
+
   // 获取系统信息
   async getSystemInfo(): Promise<APIResponse<SystemInfo>> {
     return this.request<SystemInfo>('/system');
   }
 
-  // 导出配置文件
-  async exportConfigs(): Promise<Blob> {
-    const response = await fetch(`${API_BASE_URL}/export`);
+  // 导出配置文件，selection 为空时导出所有文件
+  // passphrase 非空时导出加密的导出包（口令通过请求头传递）
+  async exportConfigs(format: 'zip' | 'tar.gz' | 'script' | 'nix' | 'ansible' = 'zip', passphrase = '', selection: ExportSelection = {}): Promise<Blob> {
+    const headers: Record<string, string> = { 'Content-Type': 'application/json' };
+    if (passphrase) {
+      headers['X-Bundle-Passphrase'] = passphrase;
+    }
+    const response = await fetch(`${API_BASE_URL}/export`, {
+      method: 'POST',
+      headers,
+      body: JSON.stringify({ ...selection, format }),
+    });
     if (!response.ok) {
       throw new Error(`导出失败: ${response.status}`);
     }
     return response.blob();
   }
 
+  // 生成导入计划（不修改任何文件），createMissing 为 true 时新建本地不存在的文件
+  async planImport(file: File, createMissing = false, passphrase = ''): Promise<APIResponse<any>> {
+    const formData = new FormData();
+    formData.append('configFile', file);
+    formData.append('createMissing', String(createMissing));
+    if (passphrase) {
+      formData.append('passphrase', passphrase);
+    }
+
+    const response = await fetch(`${API_BASE_URL}/import?mode=plan`, {
+      method: 'POST',
+      body: formData,
+    });
+    return response.json();
+  }
+
+  // 读取 chezmoi、stow 或 yadm 的本地目录并生成导入计划
+  async planSourceImport(source: {
+    type: 'chezmoi' | 'stow' | 'yadm';
+    path?: string;
+    target?: string;
+    dotfiles?: boolean;
+    createMissing?: boolean;
+  }): Promise<APIResponse<any>> {
+    return this.request<any>('/import/sources', {
+      method: 'POST',
+      body: JSON.stringify(source),
+    });
+  }
+
+  // 执行导入计划中选定的条目，entries 为空时执行全部；skipValidation 为 true 时写入未通过格式校验的内容
+  async applyImportPlan(planId: string, entries: string[] = [], force = false, skipValidation = false): Promise<APIResponse<any>> {
+    return this.request<any>(`/import/plans/${planId}/apply`, {
+      method: 'POST',
+      body: JSON.stringify({ entries, force, skipValidation }),
+    });
+  }
+
   // 导入配置文件
   async importConfigs(file: File): Promise<APIResponse<any>> {
     const formData = new FormData();
@@ -103,6 +271,51 @@ class ApiService {
       };
     }
   }
+
+  // 分块上传较大的导出包，中断后从服务端记录的 received 处继续，上传完成后开始生成导入计划
+  async uploadImportInChunks(
+    file: File,
+    onProgress?: (received: number, size: number) => void,
+    chunkSize = 4 << 20,
+  ): Promise<APIResponse<any>> {
+    const created = await this.request<any>('/import/jobs', {
+      method: 'POST',
+      body: JSON.stringify({ fileName: file.name, size: file.size }),
+    });
+    if (!created.success) {
+      return created;
+    }
+
+    const jobId = created.data.id;
+    let received = 0;
+    while (received < file.size) {
+      const response = await fetch(`${API_BASE_URL}/import/jobs/${jobId}/chunks?offset=${received}`, {
+        method: 'PUT',
+        body: file.slice(received, received + chunkSize),
+      });
+      if (response.ok) {
+        received = (await response.json()).data.received;
+      } else {
+        // 偏移量不一致或上传中断时按服务端记录的进度继续
+        const job = await this.getImportJob(jobId);
+        if (!job.success || job.data.received === received) {
+          return job.success ? { success: false, error: `上传分块失败: ${response.status}` } : job;
+        }
+        received = job.data.received;
+      }
+      onProgress?.(received, file.size);
+    }
+
+    return this.request<any>(`/import/jobs/${jobId}/start`, {
+      method: 'POST',
+      body: JSON.stringify({ mode: 'plan' }),
+    });
+  }
+
+  // 查询导入任务的上传进度、处理进度和结果
+  async getImportJob(jobId: string): Promise<APIResponse<any>> {
+    return this.request<any>(`/import/jobs/${jobId}`);
+  }
 }
 
 export const apiService = new ApiService();
\ No newline at end of file
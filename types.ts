@@ -10,6 +10,18 @@ export interface ConfigFile {
   isSymlink: boolean;
   backupExists: boolean;
   content?: string;
+  etag?: string;
+  tags?: string[];
+  template?: boolean; // 为 true 时 content 是模板源文件
+}
+
+// 选择性导出的筛选条件，categories、ids、tags 取并集，exclude 总是排除
+export interface ExportSelection {
+  categories?: string[];
+  ids?: string[];
+  tags?: string[];
+  exclude?: string[];
+  profile?: string; // 并入该档案的分类和文件
 }
 
 export interface ConfigCategory {
@@ -29,6 +41,7 @@ export interface ConfigBackup {
 }
 
 export interface SystemInfo {
+  hostname: string;
   os: string;
   kernel: string;
   shell: string;
@@ -44,5 +57,98 @@ export interface FileNode {
   isModified?: boolean;
 }
 
+export interface DiffSegment {
+  text: string;
+  changed: boolean;
+}
+
+export interface DiffLine {
+  number: number;
+  text: string;
+  segments?: DiffSegment[];
+}
+
+export interface DiffRow {
+  kind: 'equal' | 'delete' | 'insert' | 'change';
+  left?: DiffLine;
+  right?: DiffLine;
+}
+
+export interface DiffHunk {
+  fromStart: number;
+  fromCount: number;
+  toStart: number;
+  toCount: number;
+  rows: DiffRow[];
+}
+
+export interface DiffResult {
+  from: string;
+  to: string;
+  identical: boolean;
+  added: number;
+  removed: number;
+  unified: string;
+  hunks: DiffHunk[];
+}
+
+// 受管块：由 BEGIN/END LINUX-CONFIG-MANAGER BLOCK 标记行包围的内容
+export interface ManagedBlock {
+  name: string;
+  startLine: number;
+  endLine: number;
+  content: string;
+}
+
+export interface BlockList {
+  fileId: string;
+  blocks: ManagedBlock[];
+  problems: { line: number; column: number; severity: string; message: string; source: string }[];
+}
+
+// 渲染模板使用的数据，预览时非空字段覆盖本机信息
+export interface TemplateData {
+  hostname?: string;
+  os?: string;
+  kernel?: string;
+  user?: string;
+  homeDir?: string;
+  shell?: string;
+  profile?: string;
+  vars?: Record<string, string>;
+}
+
+// 机器配置档案，match 中的规则为不区分大小写的通配符
+export interface MachineProfile {
+  id: string;
+  name: string;
+  description?: string;
+  match: { hostname?: string; os?: string; kernel?: string };
+  priority?: number;
+  vars?: Record<string, string>;
+  categories?: string[];
+  files?: string[];
+}
+
+export interface ProfileResolution {
+  active: MachineProfile | null;
+  system: SystemInfo;
+  reason: string;
+  candidates: {
+    profileId: string;
+    priority: number;
+    matched: boolean;
+    rules: { field: string; pattern: string; value: string; matched: boolean }[];
+  }[];
+}
+
+export interface RenderResult {
+  fileId: string;
+  path: string;
+  content: string;
+  data: TemplateData;
+  diff?: string;
+}
+
 export type ViewMode = 'list' | 'grid' | 'tree';
 export type SortBy = 'name' | 'modified' | 'size' | 'category';